@@ -19,7 +19,6 @@ cp $CURDIR/../_docker_workspace/out/$RELEASE_PLATFORM/bin/* $WKDIR/
 mkdir -p $WKDIR/python
 cp python/*.py $WKDIR/python/
 cp python/k8s-runtime-requirements.txt $WKDIR/
-cp schemas/bigip-virtual-server_v*.json $WKDIR/
 
 echo "Docker build context:"
 ls -la $WKDIR
@@ -0,0 +1,183 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/pflag"
+)
+
+// Prefix for environment variables that provide flag values
+const envVarPrefix = "BIGIP_CTLR_"
+
+// Map the config file section names to the flag group they configure
+func configFileSections() map[string]*pflag.FlagSet {
+	return map[string]*pflag.FlagSet{
+		"global":           globalFlags,
+		"bigip":            bigIPFlags,
+		"kubernetes":       kubeFlags,
+		"openshift-sdn":    openshiftSDNFlags,
+		"openshift-routes": osRouteFlags,
+	}
+}
+
+// Environment variable name for a flag, e.g. bigip-url -> BIGIP_CTLR_BIGIP_URL
+func envVarName(flagName string) string {
+	return envVarPrefix +
+		strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+func isArrayFlag(f *pflag.Flag) bool {
+	return f.Value.Type() == "stringArray"
+}
+
+// Fill in any flag not given on the command line from the environment and
+// then from the config file. The command line always wins, followed by the
+// environment, the config file and finally the flag defaults.
+func applyConfigSources() error {
+	isSet := make(map[string]bool)
+	flags.Visit(func(f *pflag.Flag) {
+		isSet[f.Name] = true
+	})
+
+	err := applyEnvironment(isSet)
+	if nil != err {
+		return err
+	}
+
+	if 0 != len(*configFile) {
+		err = applyConfigFile(*configFile, isSet)
+		if nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyEnvironment(isSet map[string]bool) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if nil != err || isSet[f.Name] {
+			return
+		}
+		envName := envVarName(f.Name)
+		val, found := os.LookupEnv(envName)
+		if !found {
+			return
+		}
+		values := []string{val}
+		if isArrayFlag(f) {
+			// Array flags take a comma separated list in the environment
+			values = strings.Split(val, ",")
+		}
+		for _, v := range values {
+			if setErr := flags.Set(f.Name, strings.TrimSpace(v)); nil != setErr {
+				err = fmt.Errorf("Invalid value for environment variable %s: %v",
+					envName, setErr)
+				return
+			}
+		}
+		isSet[f.Name] = true
+	})
+	return err
+}
+
+func applyConfigFile(path string, isSet map[string]bool) error {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return fmt.Errorf("Error reading config file: %v", err)
+	}
+
+	// YAML is a superset of JSON, so this handles both formats.
+	var sections map[string]map[string]interface{}
+	err = yaml.Unmarshal(data, &sections)
+	if nil != err {
+		return fmt.Errorf("Error parsing config file %s: %v", path, err)
+	}
+
+	groups := configFileSections()
+	for section, settings := range sections {
+		group, ok := groups[section]
+		if !ok {
+			return fmt.Errorf("Unknown section '%s' in config file %s",
+				section, path)
+		}
+		for name, setting := range settings {
+			f := group.Lookup(name)
+			if nil == f || name == "config-file" {
+				return fmt.Errorf("Unknown setting '%s' in section '%s' of config file %s",
+					name, section, path)
+			}
+			if isSet[name] {
+				continue
+			}
+			values, err := configFileValues(setting)
+			if nil != err {
+				return fmt.Errorf("Invalid value for '%s' in config file %s: %v",
+					name, path, err)
+			}
+			if len(values) > 1 && !isArrayFlag(f) {
+				return fmt.Errorf("Setting '%s' in config file %s does not accept a list",
+					name, path)
+			}
+			for _, v := range values {
+				err = flags.Set(name, v)
+				if nil != err {
+					return fmt.Errorf("Invalid value for '%s' in config file %s: %v",
+						name, path, err)
+				}
+			}
+			isSet[name] = true
+		}
+	}
+	return nil
+}
+
+// Convert a decoded config file value into the string form used by the flags
+func configFileValues(setting interface{}) ([]string, error) {
+	switch val := setting.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{val}, nil
+	case bool:
+		return []string{strconv.FormatBool(val)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(val, 'f', -1, 64)}, nil
+	case []interface{}:
+		var values []string
+		for _, item := range val {
+			v, err := configFileValues(item)
+			if nil != err {
+				return nil, err
+			}
+			if len(v) > 1 {
+				return nil, fmt.Errorf("nested lists are not supported")
+			}
+			values = append(values, v...)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", setting)
+	}
+}
@@ -0,0 +1,204 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfigFile(t *testing.T, name, contents string) string {
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	err = ioutil.WriteFile(path, []byte(contents), 0644)
+	require.NoError(t, err)
+	return path
+}
+
+func TestEnvVarName(t *testing.T) {
+	assert.Equal(t, "BIGIP_CTLR_BIGIP_URL", envVarName("bigip-url"))
+	assert.Equal(t, "BIGIP_CTLR_LOG_LEVEL", envVarName("log-level"))
+	assert.Equal(t, "BIGIP_CTLR_OPENSHIFT_SDN_NAME",
+		envVarName("openshift-sdn-name"))
+}
+
+func TestConfigFileYAML(t *testing.T) {
+	defer _init()
+	path := writeTestConfigFile(t, "config.yaml", `
+global:
+  log-level: debug
+  verify-interval: 45
+bigip:
+  bigip-url: bigip.example.com
+  bigip-username: admin
+  bigip-password: secret
+  bigip-partition:
+  - velcro1
+  - velcro2
+kubernetes:
+  namespace: [default, test]
+  pool-member-type: cluster
+  use-node-internal: false
+openshift-sdn:
+  openshift-sdn-name: /Common/vxlan500
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--config-file=" + path,
+	}
+	flags.Parse(os.Args)
+	err := applyConfigSources()
+	require.NoError(t, err)
+	err = verifyArgs()
+	require.NoError(t, err)
+
+	assert.Equal(t, "DEBUG", *logLevel)
+	assert.Equal(t, 45, *verifyInterval)
+	assert.Equal(t, "https://bigip.example.com", *bigIPURL)
+	assert.Equal(t, "admin", *bigIPUsername)
+	assert.Equal(t, "secret", *bigIPPassword)
+	assert.Equal(t, []string{"velcro1", "velcro2"}, *bigIPPartitions)
+	assert.Equal(t, []string{"default", "test"}, *namespaces)
+	assert.Equal(t, false, *useNodeInternal)
+	assert.Equal(t, false, isNodePort)
+	assert.Equal(t, "maintain", openshiftSDNMode)
+	assert.Equal(t, "/Common/vxlan500", *openshiftSDNName)
+}
+
+func TestConfigFileJSON(t *testing.T) {
+	defer _init()
+	path := writeTestConfigFile(t, "config.json", `{
+  "bigip": {
+    "bigip-url": "bigip.example.com",
+    "bigip-username": "admin",
+    "bigip-password": "secret",
+    "bigip-partition": ["velcro1"]
+  },
+  "openshift-routes": {
+    "route-vserver-addr": "10.10.10.10"
+  }
+}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--config-file=" + path,
+	}
+	flags.Parse(os.Args)
+	err := applyConfigSources()
+	require.NoError(t, err)
+	err = verifyArgs()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"velcro1"}, *bigIPPartitions)
+	assert.Equal(t, "10.10.10.10", *routeVserverAddr)
+	assert.Equal(t, true, watchAllNamespaces)
+}
+
+func TestConfigSourcesPrecedence(t *testing.T) {
+	defer _init()
+	path := writeTestConfigFile(t, "config.yaml", `
+bigip:
+  bigip-url: file.example.com
+  bigip-username: file-user
+  bigip-password: file-password
+  bigip-partition: [file-partition]
+global:
+  log-level: ERROR
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Setenv("BIGIP_CTLR_BIGIP_USERNAME", "env-user")
+	os.Setenv("BIGIP_CTLR_BIGIP_PASSWORD", "env-password")
+	os.Setenv("BIGIP_CTLR_BIGIP_PARTITION", "env1, env2")
+	os.Setenv("BIGIP_CTLR_CONFIG_FILE", path)
+	defer func() {
+		os.Unsetenv("BIGIP_CTLR_BIGIP_USERNAME")
+		os.Unsetenv("BIGIP_CTLR_BIGIP_PASSWORD")
+		os.Unsetenv("BIGIP_CTLR_BIGIP_PARTITION")
+		os.Unsetenv("BIGIP_CTLR_CONFIG_FILE")
+	}()
+
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--bigip-password=cli-password",
+	}
+	flags.Parse(os.Args)
+	err := applyConfigSources()
+	require.NoError(t, err)
+	err = verifyArgs()
+	require.NoError(t, err)
+
+	// Command line beats environment beats config file beats defaults
+	assert.Equal(t, "cli-password", *bigIPPassword)
+	assert.Equal(t, "env-user", *bigIPUsername)
+	assert.Equal(t, []string{"env1", "env2"}, *bigIPPartitions)
+	assert.Equal(t, "https://file.example.com", *bigIPURL)
+	assert.Equal(t, "ERROR", *logLevel)
+	assert.Equal(t, 30, *verifyInterval)
+}
+
+func TestConfigSourcesErrors(t *testing.T) {
+	defer _init()
+	badFiles := []string{
+		// Unknown section
+		"potato:\n  bigip-url: bigip.example.com\n",
+		// Flag in the wrong section
+		"global:\n  bigip-url: bigip.example.com\n",
+		// List for a scalar flag
+		"bigip:\n  bigip-url: [a, b]\n",
+		// Value of the wrong type for the flag
+		"global:\n  verify-interval: often\n",
+		// Not a mapping of sections
+		"- bigip\n",
+	}
+	for _, contents := range badFiles {
+		_init()
+		path := writeTestConfigFile(t, "config.yaml", contents)
+		os.Args = []string{
+			"./bin/k8s-bigip-ctlr",
+			"--config-file=" + path,
+		}
+		flags.Parse(os.Args)
+		err := applyConfigSources()
+		assert.Error(t, err, "config file should be rejected: %s", contents)
+		os.RemoveAll(filepath.Dir(path))
+	}
+
+	_init()
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--config-file=/does/not/exist.yaml",
+	}
+	flags.Parse(os.Args)
+	assert.Error(t, applyConfigSources())
+
+	_init()
+	os.Setenv("BIGIP_CTLR_NODE_POLL_INTERVAL", "sometimes")
+	defer os.Unsetenv("BIGIP_CTLR_NODE_POLL_INTERVAL")
+	os.Args = []string{"./bin/k8s-bigip-ctlr"}
+	flags.Parse(os.Args)
+	assert.Error(t, applyConfigSources())
+}
@@ -0,0 +1,149 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+)
+
+// Names of the files read from the credentials directory
+const (
+	credentialsUsernameFile = "username"
+	credentialsPasswordFile = "password"
+	credentialsURLFile      = "url"
+)
+
+// How often the credentials directory is checked for changes. Mounted Secrets
+// are only refreshed by the kubelet on its sync period, so this does not need
+// to be any faster.
+const credentialsPollInterval = 10 * time.Second
+
+type bigIPCredentials struct {
+	username string
+	password string
+	url      string
+}
+
+// Read the BIG-IP credentials from dir. A file that does not exist leaves the
+// corresponding value from defaults in place.
+func readCredentials(
+	dir string,
+	defaults bigIPCredentials,
+) (bigIPCredentials, error) {
+	creds := defaults
+	files := map[string]*string{
+		credentialsUsernameFile: &creds.username,
+		credentialsPasswordFile: &creds.password,
+		credentialsURLFile:      &creds.url,
+	}
+	for name, val := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if nil != err {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return defaults, fmt.Errorf("Error reading credentials file: %v", err)
+		}
+		// Secrets are frequently created with a trailing newline
+		*val = strings.TrimSpace(string(data))
+	}
+	return creds, nil
+}
+
+// Polls the credentials directory and calls onChange whenever the files hold
+// different values than the last ones applied.
+type credentialsWatcher struct {
+	dir      string
+	interval time.Duration
+	creds    bigIPCredentials
+	onChange func(bigIPCredentials) error
+	stopCh   chan struct{}
+}
+
+func newCredentialsWatcher(
+	dir string,
+	interval time.Duration,
+	current bigIPCredentials,
+	onChange func(bigIPCredentials) error,
+) *credentialsWatcher {
+	return &credentialsWatcher{
+		dir:      dir,
+		interval: interval,
+		creds:    current,
+		onChange: onChange,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (cw *credentialsWatcher) run() {
+	log.Infof("Watching %s for BIG-IP credential changes", cw.dir)
+	for {
+		select {
+		case <-cw.stopCh:
+			return
+		case <-time.After(cw.interval):
+			cw.check()
+		}
+	}
+}
+
+func (cw *credentialsWatcher) stop() {
+	close(cw.stopCh)
+}
+
+func (cw *credentialsWatcher) check() {
+	// Files that disappear (e.g. mid-update of a Secret volume) keep their
+	// last known value rather than dropping back to the flags.
+	creds, err := readCredentials(cw.dir, cw.creds)
+	if nil != err {
+		log.Warningf("%v", err)
+		return
+	}
+	if creds == cw.creds {
+		return
+	}
+
+	creds.url, err = verifyBigIPURL(creds.url)
+	if nil != err {
+		log.Errorf("Ignoring updated BIG-IP credentials: %v", err)
+		return
+	}
+	if 0 == len(creds.username) || 0 == len(creds.password) {
+		log.Errorf("Ignoring updated BIG-IP credentials: username and " +
+			"password must not be empty")
+		return
+	}
+	if creds == cw.creds {
+		return
+	}
+
+	log.Infof("BIG-IP credentials changed, updating driver configuration")
+	err = cw.onChange(creds)
+	if nil != err {
+		// Leave the old values in place so the next poll tries again
+		log.Errorf("Failed to apply updated BIG-IP credentials: %v", err)
+		return
+	}
+	cw.creds = creds
+}
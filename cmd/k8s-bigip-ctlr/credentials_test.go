@@ -0,0 +1,154 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestCredentials(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600)
+		require.NoError(t, err)
+	}
+}
+
+func TestReadCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defaults := bigIPCredentials{
+		username: "flag-user",
+		password: "flag-password",
+		url:      "https://flag.example.com",
+	}
+
+	// Empty directory keeps the defaults
+	creds, err := readCredentials(dir, defaults)
+	require.NoError(t, err)
+	assert.Equal(t, defaults, creds)
+
+	writeTestCredentials(t, dir, map[string]string{
+		"username": "secret-user\n",
+		"password": "secret-password\n",
+	})
+	creds, err = readCredentials(dir, defaults)
+	require.NoError(t, err)
+	assert.Equal(t, bigIPCredentials{
+		username: "secret-user",
+		password: "secret-password",
+		url:      "https://flag.example.com",
+	}, creds)
+
+	_, err = readCredentials(filepath.Join(dir, "username"), defaults)
+	assert.Error(t, err)
+}
+
+func TestVerifyArgsCredentialsDirectory(t *testing.T) {
+	defer _init()
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeTestCredentials(t, dir, map[string]string{
+		"username": "secret-user",
+		"password": "secret-password",
+		"url":      "secret.example.com",
+	})
+
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--bigip-partition=velcro1",
+		"--bigip-username=flag-user",
+		"--credentials-directory=" + dir,
+	}
+	flags.Parse(os.Args)
+	err = verifyArgs()
+	require.NoError(t, err)
+	assert.Equal(t, "secret-user", *bigIPUsername)
+	assert.Equal(t, "secret-password", *bigIPPassword)
+	assert.Equal(t, "https://secret.example.com", *bigIPURL)
+}
+
+func TestCredentialsWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeTestCredentials(t, dir, map[string]string{
+		"username": "admin",
+		"password": "first",
+		"url":      "bigip.example.com",
+	})
+
+	current := bigIPCredentials{
+		username: "admin",
+		password: "first",
+		url:      "https://bigip.example.com",
+	}
+	var applied []bigIPCredentials
+	failApply := false
+	cw := newCredentialsWatcher(dir, time.Millisecond, current,
+		func(creds bigIPCredentials) error {
+			if failApply {
+				return fmt.Errorf("write failed")
+			}
+			applied = append(applied, creds)
+			return nil
+		})
+
+	// Unchanged files, the scheme added to the url does not count as a change
+	cw.check()
+	assert.Empty(t, applied)
+
+	writeTestCredentials(t, dir, map[string]string{"password": "second"})
+	cw.check()
+	require.Len(t, applied, 1)
+	assert.Equal(t, "second", applied[0].password)
+	assert.Equal(t, "https://bigip.example.com", applied[0].url)
+
+	// Invalid values are not applied
+	writeTestCredentials(t, dir, map[string]string{"url": "http://bigip"})
+	cw.check()
+	assert.Len(t, applied, 1)
+	writeTestCredentials(t, dir, map[string]string{
+		"url":      "bigip.example.com",
+		"password": "",
+	})
+	cw.check()
+	assert.Len(t, applied, 1)
+
+	// A failed update is retried on the next check
+	writeTestCredentials(t, dir, map[string]string{"password": "third"})
+	failApply = true
+	cw.check()
+	assert.Len(t, applied, 1)
+	failApply = false
+	cw.check()
+	require.Len(t, applied, 2)
+	assert.Equal(t, "third", applied[1].password)
+
+	go cw.run()
+	cw.stop()
+}
@@ -0,0 +1,127 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// One of several BIG-IPs configured with the same resources
+type bigIPDevice struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	Partitions []string `json:"partitions,omitempty"`
+	// Devices sharing a group are configured through one of them, which
+	// then syncs the config to the rest of the group.
+	ConfigSyncGroup string `json:"config-sync-group,omitempty"`
+}
+
+type bigIPDevicesFile struct {
+	Devices []bigIPDevice `json:"devices"`
+}
+
+// Read the YAML or JSON devices file. Devices without partitions manage all
+// of partitions. When credsDir is set, each device's credentials are read
+// from the subdirectory of credsDir with the device name.
+func readDevicesFile(
+	path string,
+	partitions []string,
+	credsDir string,
+) ([]bigIPDevice, error) {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, fmt.Errorf("Error reading devices file: %v", err)
+	}
+	var file bigIPDevicesFile
+	err = yaml.Unmarshal(data, &file)
+	if nil != err {
+		return nil, fmt.Errorf("Error parsing devices file %s: %v", path, err)
+	}
+	if 0 == len(file.Devices) {
+		return nil, fmt.Errorf("Devices file %s does not list any devices", path)
+	}
+
+	managed := make(map[string]bool)
+	for _, p := range partitions {
+		managed[p] = true
+	}
+	names := make(map[string]bool)
+	for i := range file.Devices {
+		device := &file.Devices[i]
+		if 0 == len(device.Name) || strings.Contains(device.Name, "/") {
+			return nil, fmt.Errorf("Invalid device name '%s' in devices file %s",
+				device.Name, path)
+		}
+		if names[device.Name] {
+			return nil, fmt.Errorf("Duplicate device '%s' in devices file %s",
+				device.Name, path)
+		}
+		names[device.Name] = true
+
+		if 0 != len(credsDir) {
+			creds, err := readCredentials(
+				filepath.Join(credsDir, device.Name),
+				device.credentials())
+			if nil != err {
+				return nil, err
+			}
+			device.setCredentials(creds)
+		}
+		if 0 == len(device.URL) || 0 == len(device.Username) ||
+			0 == len(device.Password) {
+			return nil, fmt.Errorf(
+				"Device '%s' requires a url, username and password", device.Name)
+		}
+		device.URL, err = verifyBigIPURL(device.URL)
+		if nil != err {
+			return nil, fmt.Errorf("Device '%s': %v", device.Name, err)
+		}
+
+		if 0 == len(device.Partitions) {
+			device.Partitions = partitions
+		}
+		for _, p := range device.Partitions {
+			if !managed[p] {
+				return nil, fmt.Errorf(
+					"Device '%s' partition '%s' is not a bigip-partition",
+					device.Name, p)
+			}
+		}
+	}
+	return file.Devices, nil
+}
+
+func (device *bigIPDevice) credentials() bigIPCredentials {
+	return bigIPCredentials{
+		username: device.Username,
+		password: device.Password,
+		url:      device.URL,
+	}
+}
+
+func (device *bigIPDevice) setCredentials(creds bigIPCredentials) {
+	device.Username = creds.username
+	device.Password = creds.password
+	device.URL = creds.url
+}
@@ -0,0 +1,191 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDevicesFile = `
+devices:
+- name: bigip1
+  url: bigip1.example.com
+  username: admin
+  password: test1
+  config-sync-group: sync-group
+- name: bigip2
+  url: https://bigip2.example.com
+  username: admin
+  password: test2
+  config-sync-group: sync-group
+- name: bigip3
+  url: https://bigip3.example.com
+  partitions: [velcro2]
+`
+
+func writeTestDevicesFile(t *testing.T, dir string, contents string) string {
+	path := filepath.Join(dir, "devices.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestReadDevicesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeTestDevicesFile(t, dir, testDevicesFile)
+	partitions := []string{"velcro1", "velcro2"}
+
+	// bigip3 has no credentials without the credentials directory
+	_, err = readDevicesFile(path, partitions, "")
+	assert.Error(t, err)
+
+	credsDir := filepath.Join(dir, "creds")
+	require.NoError(t, os.MkdirAll(filepath.Join(credsDir, "bigip1"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(credsDir, "bigip3"), 0700))
+	writeTestCredentials(t, filepath.Join(credsDir, "bigip1"),
+		map[string]string{"password": "secret1"})
+	writeTestCredentials(t, filepath.Join(credsDir, "bigip3"),
+		map[string]string{"username": "user3", "password": "secret3"})
+
+	devices, err := readDevicesFile(path, partitions, credsDir)
+	require.NoError(t, err)
+	assert.Equal(t, []bigIPDevice{
+		{
+			Name:            "bigip1",
+			URL:             "https://bigip1.example.com",
+			Username:        "admin",
+			Password:        "secret1",
+			Partitions:      partitions,
+			ConfigSyncGroup: "sync-group",
+		},
+		{
+			Name:            "bigip2",
+			URL:             "https://bigip2.example.com",
+			Username:        "admin",
+			Password:        "test2",
+			Partitions:      partitions,
+			ConfigSyncGroup: "sync-group",
+		},
+		{
+			Name:       "bigip3",
+			URL:        "https://bigip3.example.com",
+			Username:   "user3",
+			Password:   "secret3",
+			Partitions: []string{"velcro2"},
+		},
+	}, devices)
+
+	// Partitions must be managed by the controller
+	_, err = readDevicesFile(path, []string{"velcro1"}, credsDir)
+	assert.Error(t, err)
+}
+
+func TestReadDevicesFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	partitions := []string{"velcro1"}
+
+	_, err = readDevicesFile(filepath.Join(dir, "missing"), partitions, "")
+	assert.Error(t, err)
+
+	invalid := []string{
+		"devices: [",
+		"devices: []",
+		"devices:\n- url: https://bigip.example.com\n" +
+			"  username: admin\n  password: test",
+		"devices:\n- name: a/b\n  url: https://bigip.example.com\n" +
+			"  username: admin\n  password: test",
+		"devices:\n" +
+			"- name: bigip1\n  url: https://bigip.example.com\n" +
+			"  username: admin\n  password: test\n" +
+			"- name: bigip1\n  url: https://bigip2.example.com\n" +
+			"  username: admin\n  password: test",
+		"devices:\n- name: bigip1\n  url: https://bigip.example.com/path\n" +
+			"  username: admin\n  password: test",
+	}
+	for _, contents := range invalid {
+		path := writeTestDevicesFile(t, dir, contents)
+		_, err = readDevicesFile(path, partitions, "")
+		assert.Error(t, err, contents)
+	}
+}
+
+func TestVerifyArgsDevicesFile(t *testing.T) {
+	defer _init()
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeTestDevicesFile(t, dir, testDevicesFile)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bigip3"), 0700))
+	writeTestCredentials(t, filepath.Join(dir, "bigip3"),
+		map[string]string{"username": "user3", "password": "secret3"})
+
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--bigip-partition=velcro1",
+		"--bigip-partition=velcro2",
+		"--bigip-devices-file=" + path,
+		"--credentials-directory=" + dir,
+	}
+	flags.Parse(os.Args)
+	require.NoError(t, verifyArgs())
+	require.Len(t, bigIPDevices, 3)
+	assert.Equal(t, "user3", bigIPDevices[2].Username)
+
+	// The single device flags conflict with the devices file
+	_init()
+	os.Args = append(os.Args, "--bigip-url=bigip.example.com")
+	flags.Parse(os.Args)
+	assert.Error(t, verifyArgs())
+}
+
+func TestDriverSupervisorSetDeviceCredentials(t *testing.T) {
+	ds, configWriter := newTestSupervisor("./test/pyTest.py", 3)
+	devices := []bigIPDevice{
+		{Name: "bigip1", URL: "https://bigip1.example.com",
+			Username: "admin", Password: "test1"},
+		{Name: "bigip2", URL: "https://bigip2.example.com",
+			Username: "admin", Password: "test2"},
+	}
+	require.NoError(t, ds.setBigIPSection(bigIPSection{
+		BigIPPartitions: []string{"velcro1"},
+		Devices:         devices,
+	}))
+
+	err := ds.setDeviceCredentials("bigip2", bigIPCredentials{
+		username: "admin",
+		password: "rotated",
+		url:      "https://bigip2.example.com",
+	})
+	require.NoError(t, err)
+	written := configWriter.Sections["bigip"].(bigIPSection)
+	assert.Equal(t, "test1", written.Devices[0].Password)
+	assert.Equal(t, "rotated", written.Devices[1].Password)
+	// The previously written section is not modified
+	assert.Equal(t, "test2", devices[1].Password)
+
+	err = ds.setDeviceCredentials("bigip3", bigIPCredentials{})
+	assert.Error(t, err)
+}
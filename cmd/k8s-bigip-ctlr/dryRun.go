@@ -0,0 +1,52 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/writer"
+)
+
+// Render writer that closes its output file when stopped
+type dryRunWriter struct {
+	writer.Writer
+	file *os.File
+}
+
+func (dw *dryRunWriter) Stop() {
+	dw.Writer.Stop()
+	dw.file.Close()
+}
+
+// Create the writer for a dry run. The config is appended to outputFile, so
+// the history of renders is kept across restarts, or written to stdout if
+// outputFile is empty.
+func newDryRunWriter(outputFile string) (writer.Writer, error) {
+	if 0 == len(outputFile) {
+		return writer.NewRenderWriter(os.Stdout, "-"), nil
+	}
+	f, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if nil != err {
+		return nil, fmt.Errorf("Unable to open dry-run output file: %v", err)
+	}
+	return &dryRunWriter{
+		Writer: writer.NewRenderWriter(f, outputFile),
+		file:   f,
+	}, nil
+}
@@ -0,0 +1,91 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyArgsDryRun(t *testing.T) {
+	defer _init()
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--bigip-partition=velcro1",
+		"--dry-run",
+	}
+	flags.Parse(os.Args)
+	err := verifyArgs()
+	assert.NoError(t, err, "A dry run should not need the Big-IP credentials")
+
+	*leaderElection = true
+	err = verifyArgs()
+	assert.Error(t, err, "A dry run should not take part in leader election")
+	*leaderElection = false
+
+	*bigIPPartitions = []string{}
+	err = verifyArgs()
+	assert.Error(t, err, "A dry run still needs the partition")
+	*bigIPPartitions = []string{"velcro1"}
+
+	*dryRun = false
+	*dryRunOutput = "/tmp/render.out"
+	*bigIPURL = "bigip.example.com"
+	*bigIPUsername = "admin"
+	*bigIPPassword = "admin"
+	err = verifyArgs()
+	assert.Error(t, err, "dry-run-output should require dry-run")
+}
+
+func TestDryRunWriterFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s-bigip-ctlr.test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	outputFile := filepath.Join(dir, "render.out")
+
+	// Renders are appended to the file across restarts
+	for _, port := range []int{80, 443} {
+		w, err := newDryRunWriter(outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, outputFile, w.GetOutputFilename())
+		doneCh, errCh, err := w.SendSection("resources",
+			map[string]int{"port": port})
+		require.NoError(t, err)
+		select {
+		case <-doneCh:
+		case err := <-errCh:
+			t.Fatalf("Unexpected render error: %v", err)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for render response")
+		}
+		w.Stop()
+	}
+
+	output, err := ioutil.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "\"port\": 80")
+	assert.Contains(t, string(output), "\"port\": 443")
+
+	_, err = newDryRunWriter(filepath.Join(dir, "missing", "render.out"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+)
+
+// A named condition that must hold for the controller to be ready. The
+// check returns nil when the condition holds. Leader only checks are skipped
+// on a standby replica, which never writes config or runs the driver.
+type readinessCheck struct {
+	name       string
+	check      func() error
+	leaderOnly bool
+}
+
+func readinessChecks(
+	appMgr *appmanager.Manager,
+	driver *driverSupervisor,
+) []readinessCheck {
+	checks := []readinessCheck{
+		{"informers", func() error {
+			if !appMgr.CachesSynced() {
+				return fmt.Errorf("informer caches have not synced")
+			}
+			return nil
+		}, false},
+		{"initial-state", func() error {
+			if !appMgr.InitialStateReached() {
+				return fmt.Errorf("initial config has not been written")
+			}
+			return nil
+		}, true},
+		{"config-write", func() error {
+			if err := appMgr.LastWriteError(); nil != err {
+				return err
+			}
+			if nil == driver {
+				return nil
+			}
+			return driver.lastWriteError()
+		}, true},
+	}
+	// There is no driver in a dry run
+	if nil != driver {
+		checks = append(checks, readinessCheck{"driver", func() error {
+			if !driver.alive() {
+				return fmt.Errorf("config driver is not running")
+			}
+			return nil
+		}, true})
+	}
+	return checks
+}
+
+// Create the handler serving the liveness and readiness endpoints
+func newHealthMux(
+	checks []readinessCheck,
+	isLeader func() bool,
+) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		var out bytes.Buffer
+		ready := true
+		leader := isLeader()
+		for _, c := range checks {
+			if c.leaderOnly && !leader {
+				fmt.Fprintf(&out, "[+]%s skipped, not the leader\n", c.name)
+			} else if err := c.check(); nil != err {
+				ready = false
+				fmt.Fprintf(&out, "[-]%s failed: %v\n", c.name, err)
+			} else {
+				fmt.Fprintf(&out, "[+]%s ok\n", c.name)
+			}
+		}
+		if ready {
+			out.WriteString("ready\n")
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			out.WriteString("not ready\n")
+		}
+		w.Write(out.Bytes())
+	})
+	return mux
+}
+
+// Serve handler on addr in the background
+func startHTTPServer(addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+	go func() {
+		err := server.ListenAndServe()
+		if nil != err && http.ErrServerClosed != err {
+			log.Fatalf("HTTP server on %s failed: %v", addr, err)
+		}
+	}()
+	log.Infof("Serving health checks on %s", addr)
+	return server
+}
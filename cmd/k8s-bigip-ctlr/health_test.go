@@ -0,0 +1,106 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getHealthEndpoint(handler http.Handler, path string) (int, string) {
+	req := httptest.NewRequest("GET", path, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestHealthz(t *testing.T) {
+	mux := newHealthMux(nil, func() bool { return true })
+	code, body := getHealthEndpoint(mux, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+}
+
+func TestReadyzChecks(t *testing.T) {
+	var failing error
+	leader := true
+	mux := newHealthMux([]readinessCheck{
+		{"always", func() error { return nil }, false},
+		{"sometimes", func() error { return failing }, true},
+	}, func() bool { return leader })
+
+	code, body := getHealthEndpoint(mux, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[+]always ok\n[+]sometimes ok\nready\n", body)
+
+	failing = fmt.Errorf("broken")
+	code, body = getHealthEndpoint(mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t,
+		"[+]always ok\n[-]sometimes failed: broken\nnot ready\n", body)
+
+	// A standby replica skips the leader only checks
+	leader = false
+	code, body = getHealthEndpoint(mux, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t,
+		"[+]always ok\n[+]sometimes skipped, not the leader\nready\n", body)
+}
+
+func TestReadinessChecks(t *testing.T) {
+	ds, configWriter := newTestSupervisor("./test/pyTest.py", 3)
+	appMgr := appmanager.NewManager(&appmanager.Params{
+		ConfigWriter: configWriter,
+	})
+	mux := newHealthMux(readinessChecks(appMgr, ds), func() bool { return true })
+
+	// Nothing has started yet
+	code, body := getHealthEndpoint(mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "[-]informers failed")
+	assert.Contains(t, body, "[-]initial-state failed")
+	assert.Contains(t, body, "[+]config-write ok")
+	assert.Contains(t, body, "[-]driver failed")
+
+	ds.setLastWriteError(fmt.Errorf("write failed"))
+	code, body = getHealthEndpoint(mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "[-]config-write failed: write failed")
+}
+
+func TestReadinessChecksDryRun(t *testing.T) {
+	configWriter := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	appMgr := appmanager.NewManager(&appmanager.Params{
+		ConfigWriter: configWriter,
+	})
+	mux := newHealthMux(readinessChecks(appMgr, nil), func() bool { return true })
+
+	code, body := getHealthEndpoint(mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "[+]config-write ok")
+	assert.NotContains(t, body, "driver")
+}
@@ -0,0 +1,60 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/leaderelection"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// A follower takes over at most leaderLeaseDuration after the leader stops
+// renewing. A leader that shuts down cleanly releases the lock, so followers
+// take over within leaderRetryPeriod.
+const (
+	leaderLeaseDuration = 15 * time.Second
+	leaderRenewDeadline = 10 * time.Second
+	leaderRetryPeriod   = 2 * time.Second
+)
+
+func newLeaderElector(
+	kubeClient kubernetes.Interface,
+	onStartedLeading func(),
+	onStoppedLeading func(),
+) (*leaderelection.LeaderElector, error) {
+	// The pod name is unique among the replicas
+	identity, err := os.Hostname()
+	if nil != err {
+		return nil, fmt.Errorf("Unable to get hostname for leader election: %v",
+			err)
+	}
+	return leaderelection.NewLeaderElector(leaderelection.Config{
+		KubeClient:       kubeClient,
+		Namespace:        *leaderElectionNamespace,
+		Name:             *leaderElectionLockName,
+		Identity:         identity,
+		LeaseDuration:    leaderLeaseDuration,
+		RenewDeadline:    leaderRenewDeadline,
+		RetryPeriod:      leaderRetryPeriod,
+		OnStartedLeading: onStartedLeading,
+		OnStoppedLeading: onStoppedLeading,
+	})
+}
@@ -21,11 +21,14 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/leaderelection"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/openshift"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/pollers"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/writer"
@@ -33,8 +36,10 @@ import (
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 	clog "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger/console"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
 
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -56,6 +61,8 @@ type bigIPSection struct {
 	BigIPPassword   string   `json:"password,omitempty"`
 	BigIPURL        string   `json:"url,omitempty"`
 	BigIPPartitions []string `json:"partitions,omitempty"`
+	// Replaces the url and credentials above when set
+	Devices []bigIPDevice `json:"devices,omitempty"`
 }
 
 var (
@@ -67,12 +74,19 @@ var (
 	openshiftSDNFlags *pflag.FlagSet
 	osRouteFlags      *pflag.FlagSet
 
-	pythonBaseDir    *string
-	logLevel         *string
-	verifyInterval   *int
-	nodePollInterval *int
+	configFile         *string
+	pythonBaseDir      *string
+	logLevel           *string
+	verifyInterval     *int
+	nodePollInterval   *int
+	driverRestartLimit *int
+	httpAddress        *string
+	shutdownTimeout    *int
+	dryRun             *bool
+	dryRunOutput       *string
 
 	namespaces      *[]string
+	nsPartitions    *[]string
 	useNodeInternal *bool
 	poolMemberType  *string
 	inCluster       *bool
@@ -80,10 +94,20 @@ var (
 	namespaceLabel  *string
 	manageRoutes    *bool
 
+	ingressClass      *string
+	ingressController *string
+
+	leaderElection          *bool
+	leaderElectionNamespace *string
+	leaderElectionLockName  *string
+
 	bigIPURL        *string
 	bigIPUsername   *string
 	bigIPPassword   *string
 	bigIPPartitions *[]string
+	credentialsDir  *string
+	devicesFile     *string
+	defaultSnat     *string
 
 	openshiftSDNMode string
 	openshiftSDNName *string
@@ -95,9 +119,17 @@ var (
 	// package variables
 	isNodePort         bool
 	watchAllNamespaces bool
+	bigIPDevices       []bigIPDevice
+	// Parsed from the namespace-partition flag
+	namespacePartitions map[string]string
+	// Parsed from the default-snat flag
+	sourceAddrTranslation *appmanager.SourceAddrTranslation
 )
 
 func _init() {
+	bigIPDevices = nil
+	namespacePartitions = nil
+	sourceAddrTranslation = nil
 	flags = pflag.NewFlagSet("main", pflag.ContinueOnError)
 	globalFlags = pflag.NewFlagSet("Global", pflag.ContinueOnError)
 	bigIPFlags = pflag.NewFlagSet("BigIP", pflag.ContinueOnError)
@@ -106,6 +138,9 @@ func _init() {
 	osRouteFlags = pflag.NewFlagSet("OpenShift Routes", pflag.ContinueOnError)
 
 	// Global flags
+	configFile = globalFlags.String("config-file", "",
+		"Optional, YAML or JSON file providing values for any flag not set on "+
+			"the command line or in a "+envVarPrefix+"* environment variable")
 	pythonBaseDir = globalFlags.String("python-basedir", "/app/python",
 		"Optional, directory location of python utilities")
 	logLevel = globalFlags.String("log-level", "INFO",
@@ -114,6 +149,23 @@ func _init() {
 		"Optional, interval (in seconds) at which to verify the BIG-IP configuration.")
 	nodePollInterval = globalFlags.Int("node-poll-interval", 30,
 		"Optional, interval (in seconds) at which to poll for cluster nodes.")
+	driverRestartLimit = globalFlags.Int("driver-restart-limit", 5,
+		"Optional, number of times in a row the python driver may exit before "+
+			"the controller gives up and exits. The count resets once the driver "+
+			"stays up for a minute.")
+	httpAddress = globalFlags.String("http-listen-address", "0.0.0.0:8080",
+		"Optional, address to serve the /healthz, /readyz and /metrics "+
+			"endpoints on; empty disables them")
+	shutdownTimeout = globalFlags.Int("shutdown-timeout", 25,
+		"Optional, time (in seconds) allowed on shutdown for queued changes "+
+			"to be applied to the Big-IP before the python driver is stopped")
+	dryRun = globalFlags.Bool("dry-run", false,
+		"Optional, render the Big-IP configuration with a diff against the "+
+			"previous one instead of running the python driver; the Big-IP "+
+			"url and credentials are not required")
+	dryRunOutput = globalFlags.String("dry-run-output", "",
+		"Optional, file the dry-run configuration is appended to; "+
+			"empty writes it to stdout")
 
 	globalFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "  Global:\n%s\n", globalFlags.FlagUsages())
@@ -128,6 +180,17 @@ func _init() {
 		"Required, password for the Big-IP user account.")
 	bigIPPartitions = bigIPFlags.StringArray("bigip-partition", []string{},
 		"Required, partition(s) for the Big-IP kubernetes objects.")
+	credentialsDir = bigIPFlags.String("credentials-directory", "",
+		"Optional, directory containing 'username', 'password' and 'url' files "+
+			"for the Big-IP; these replace the matching flags and are reloaded "+
+			"when they change")
+	devicesFile = bigIPFlags.String("bigip-devices-file", "",
+		"Optional, YAML or JSON file listing several Big-IPs to configure, "+
+			"each with its own url, credentials, partitions and config-sync "+
+			"group; replaces bigip-url, bigip-username and bigip-password")
+	defaultSnat = bigIPFlags.String("default-snat", "automap",
+		"Optional, source address translation of the virtual servers that "+
+			"do not set it: automap, none or the full path of a SNAT pool")
 
 	bigIPFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "  BigIP:\n%s\n", bigIPFlags.FlagUsages())
@@ -137,6 +200,9 @@ func _init() {
 	namespaces = kubeFlags.StringArray("namespace", []string{},
 		"Optional, Kubernetes namespace(s) to watch."+
 			"If left blank controller will watch all k8s namespaces")
+	nsPartitions = kubeFlags.StringArray("namespace-partition", []string{},
+		"Optional, <namespace>:<partition> to configure the resources of a "+
+			"namespace in one of the bigip-partition values; may be repeated")
 	useNodeInternal = kubeFlags.Bool("use-node-internal", true,
 		"Optional, provide kubernetes InternalIP addresses to pool")
 	poolMemberType = kubeFlags.String("pool-member-type", "nodeport",
@@ -153,6 +219,21 @@ func _init() {
 	manageRoutes = kubeFlags.Bool("manage-routes", false,
 		"Optional, specify whether or not to manage Route resources")
 	kubeFlags.MarkHidden("manage-routes")
+	ingressClass = kubeFlags.String("ingress-class",
+		appmanager.DefaultIngressClass,
+		"Optional, class name of the Ingresses to manage, as set by their "+
+			"IngressClass or kubernetes.io/ingress.class annotation")
+	ingressController = kubeFlags.String("ingress-controller",
+		appmanager.DefaultIngressController,
+		"Optional, controller value of the IngressClasses to manage")
+	leaderElection = kubeFlags.Bool("leader-election", false,
+		"Optional, run as one of several replicas, with only the elected "+
+			"leader configuring the Big-IP")
+	leaderElectionNamespace = kubeFlags.String("leader-election-namespace",
+		"kube-system", "Optional, namespace of the leader election ConfigMap")
+	leaderElectionLockName = kubeFlags.String("leader-election-lock-name",
+		"k8s-bigip-ctlr", "Optional, name of the leader election ConfigMap; "+
+			"replicas sharing a name elect a single leader")
 
 	kubeFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "  Kubernetes:\n%s\n", kubeFlags.FlagUsages())
@@ -228,42 +309,90 @@ func verifyArgs() error {
 		return logErr
 	}
 
-	if len(*bigIPURL) == 0 || len(*bigIPUsername) == 0 || len(*bigIPPassword) == 0 ||
-		len(*bigIPPartitions) == 0 || len(*poolMemberType) == 0 {
+	if len(*devicesFile) != 0 {
+		if len(*bigIPURL) != 0 || len(*bigIPUsername) != 0 ||
+			len(*bigIPPassword) != 0 {
+			return fmt.Errorf("Can not specify both bigip-devices-file and " +
+				"bigip-url, bigip-username or bigip-password")
+		}
+		if len(*bigIPPartitions) == 0 {
+			return fmt.Errorf("Missing required parameter")
+		}
+		devices, err := readDevicesFile(*devicesFile, *bigIPPartitions,
+			*credentialsDir)
+		if nil != err {
+			return err
+		}
+		bigIPDevices = devices
+	} else if len(*credentialsDir) != 0 {
+		creds, err := readCredentials(*credentialsDir, bigIPCredentials{
+			username: *bigIPUsername,
+			password: *bigIPPassword,
+			url:      *bigIPURL,
+		})
+		if nil != err {
+			return err
+		}
+		*bigIPUsername = creds.username
+		*bigIPPassword = creds.password
+		*bigIPURL = creds.url
+	}
+
+	// A dry run never connects to the BIG-IP
+	if !*dryRun && len(*devicesFile) == 0 && (len(*bigIPURL) == 0 ||
+		len(*bigIPUsername) == 0 || len(*bigIPPassword) == 0) {
+		return fmt.Errorf("Missing required parameter")
+	}
+	if len(*bigIPPartitions) == 0 || len(*poolMemberType) == 0 {
 		return fmt.Errorf("Missing required parameter")
 	}
 
+	if *dryRun && *leaderElection {
+		return fmt.Errorf("Can not specify both dry-run and leader-election")
+	}
+	if len(*dryRunOutput) != 0 && !*dryRun {
+		return fmt.Errorf("dry-run-output requires dry-run")
+	}
+
+	if *driverRestartLimit < 0 {
+		return fmt.Errorf("driver-restart-limit must not be negative")
+	}
+	if *shutdownTimeout < 0 {
+		return fmt.Errorf("shutdown-timeout must not be negative")
+	}
+
 	if len(*namespaces) != 0 && len(*namespaceLabel) != 0 {
 		return fmt.Errorf("Can not specify both namespace and namespace-label")
 	}
-
-	if len(*namespaces) == 0 && len(*namespaceLabel) == 0 {
-		watchAllNamespaces = true
-	} else {
-		watchAllNamespaces = false
+	if len(*ingressClass) == 0 || len(*ingressController) == 0 {
+		return fmt.Errorf("ingress-class and ingress-controller must not be empty")
 	}
 
-	u, err := url.Parse(*bigIPURL)
+	partitions, err := parseNamespacePartitions(
+		*nsPartitions, *bigIPPartitions)
 	if nil != err {
-		return fmt.Errorf("Error parsing url: %s", err)
+		return err
 	}
+	namespacePartitions = partitions
 
-	if len(u.Scheme) == 0 {
-		*bigIPURL = "https://" + *bigIPURL
-		u, err = url.Parse(*bigIPURL)
-		if nil != err {
-			return fmt.Errorf("Error parsing url: %s", err)
-		}
+	sourceAddrTranslation, err = appmanager.ParseSourceAddrTranslation(
+		*defaultSnat)
+	if nil != err {
+		return fmt.Errorf("Invalid default-snat: %v", err)
 	}
 
-	if u.Scheme != "https" {
-		return fmt.Errorf("Invalid BIGIP-URL protocol: '%s' - Must be 'https'",
-			u.Scheme)
+	if len(*namespaces) == 0 && len(*namespaceLabel) == 0 {
+		watchAllNamespaces = true
+	} else {
+		watchAllNamespaces = false
 	}
 
-	if len(u.Path) > 0 && u.Path != "/" {
-		return fmt.Errorf("BIGIP-URL path must be empty or '/'; check URL formatting and/or remove %s from path",
-			u.Path)
+	if len(*bigIPURL) != 0 {
+		bigIPURLVal, err := verifyBigIPURL(*bigIPURL)
+		if nil != err {
+			return err
+		}
+		*bigIPURL = bigIPURLVal
 	}
 
 	if *poolMemberType == "nodeport" {
@@ -284,6 +413,62 @@ func verifyArgs() error {
 	return nil
 }
 
+// Parse <namespace>:<partition> values into a map from namespace to partition
+func parseNamespacePartitions(
+	values []string,
+	partitions []string,
+) (map[string]string, error) {
+	managed := make(map[string]bool)
+	for _, p := range partitions {
+		managed[p] = true
+	}
+	nsPartitions := make(map[string]string)
+	for _, value := range values {
+		parts := strings.Split(value, ":")
+		if len(parts) != 2 || 0 == len(parts[0]) || 0 == len(parts[1]) {
+			return nil, fmt.Errorf("Invalid namespace-partition '%s', must be "+
+				"<namespace>:<partition>", value)
+		}
+		if _, found := nsPartitions[parts[0]]; found {
+			return nil, fmt.Errorf(
+				"Namespace '%s' is in namespace-partition more than once", parts[0])
+		}
+		if !managed[parts[1]] {
+			return nil, fmt.Errorf(
+				"namespace-partition '%s' does not use a bigip-partition", value)
+		}
+		nsPartitions[parts[0]] = parts[1]
+	}
+	return nsPartitions, nil
+}
+
+// Add the https scheme if missing and make sure the BIG-IP URL is usable
+func verifyBigIPURL(bigIPURL string) (string, error) {
+	u, err := url.Parse(bigIPURL)
+	if nil != err {
+		return "", fmt.Errorf("Error parsing url: %s", err)
+	}
+
+	if len(u.Scheme) == 0 {
+		bigIPURL = "https://" + bigIPURL
+		u, err = url.Parse(bigIPURL)
+		if nil != err {
+			return "", fmt.Errorf("Error parsing url: %s", err)
+		}
+	}
+
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("Invalid BIGIP-URL protocol: '%s' - Must be 'https'",
+			u.Scheme)
+	}
+
+	if len(u.Path) > 0 && u.Path != "/" {
+		return "", fmt.Errorf("BIGIP-URL path must be empty or '/'; check URL formatting and/or remove %s from path",
+			u.Path)
+	}
+	return bigIPURL, nil
+}
+
 func setupNodePolling(
 	appMgr *appmanager.Manager,
 	np pollers.Poller,
@@ -318,6 +503,22 @@ func setupNodePolling(
 	return nil
 }
 
+// Whether the cluster serves the networking.k8s.io/v1 Ingress API, which
+// replaces extensions/v1beta1 from Kubernetes 1.19
+func hasNetworkingIngress(client discovery.ServerResourcesInterface) bool {
+	resources, err := client.ServerResourcesForGroupVersion(
+		netv1.SchemeGroupVersion.String())
+	if nil != err {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if "ingresses" == resource.Name {
+			return true
+		}
+	}
+	return false
+}
+
 func createLabel(label string) (labels.Selector, error) {
 	var l labels.Selector
 	var err error
@@ -373,6 +574,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	err = applyConfigSources()
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	err = verifyArgs()
 	if nil != err {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
@@ -387,11 +594,15 @@ func main() {
 		log.Infof("SCALE_PERF: Started controller at: %d", now.Unix())
 	}
 
-	configWriter, err := writer.NewConfigWriter()
+	var configWriter writer.Writer
+	if *dryRun {
+		configWriter, err = newDryRunWriter(*dryRunOutput)
+	} else {
+		configWriter, err = writer.NewConfigWriter()
+	}
 	if nil != err {
 		log.Fatalf("Failed creating ConfigWriter tool: %v", err)
 	}
-	defer configWriter.Stop()
 	var routeConfig = appmanager.RouteConfig{
 		RouteVSAddr:     *routeVserverAddr,
 		RouteServerCert: *routeDefaultServerCert,
@@ -403,6 +614,13 @@ func main() {
 		UseNodeInternal: *useNodeInternal,
 		IsNodePort:      isNodePort,
 		RouteConfig:     routeConfig,
+		// The first partition is the default, DEFAULT_PARTITION
+		ManagedPartitions:   *bigIPPartitions,
+		NamespacePartitions: namespacePartitions,
+		DefaultSnat:         sourceAddrTranslation,
+		IngressClass:        *ingressClass,
+		IngressController:   *ingressController,
+		ReadOnly:            *dryRun,
 	}
 
 	gs := globalSection{
@@ -414,25 +632,50 @@ func main() {
 		BigIPPassword:   *bigIPPassword,
 		BigIPURL:        *bigIPURL,
 		BigIPPartitions: *bigIPPartitions,
-	}
-
-	subPidCh, err := startPythonDriver(configWriter, gs, bs, *pythonBaseDir)
-	if nil != err {
-		log.Fatalf("Could not initialize subprocess configuration: %v", err)
-	}
-	subPid := <-subPidCh
-	defer func(pid int) {
-		if 0 != pid {
-			proc, err := os.FindProcess(pid)
-			if nil != err {
-				log.Warningf("Failed to find sub-process on exit: %v", err)
-			}
-			err = proc.Signal(os.Interrupt)
-			if nil != err {
-				log.Warningf("Could not stop sub-process on exit: %d - %v", pid, err)
-			}
+		Devices:         bigIPDevices,
+	}
+
+	// A dry run renders the config without running the driver
+	var driver *driverSupervisor
+	if !*dryRun {
+		driver = newDriverSupervisor(configWriter, gs, bs, *pythonBaseDir,
+			*driverRestartLimit)
+	}
+
+	var watchers []*credentialsWatcher
+	if nil != driver && len(*credentialsDir) != 0 && len(bigIPDevices) != 0 {
+		// Each device has its own subdirectory of credentials
+		for _, device := range bigIPDevices {
+			name := device.Name
+			watchers = append(watchers, newCredentialsWatcher(
+				filepath.Join(*credentialsDir, name),
+				credentialsPollInterval,
+				device.credentials(),
+				func(creds bigIPCredentials) error {
+					return driver.setDeviceCredentials(name, creds)
+				},
+			))
 		}
-	}(subPid)
+	} else if nil != driver && len(*credentialsDir) != 0 {
+		watchers = append(watchers, newCredentialsWatcher(
+			*credentialsDir,
+			credentialsPollInterval,
+			bigIPCredentials{
+				username: bs.BigIPUsername,
+				password: bs.BigIPPassword,
+				url:      bs.BigIPURL,
+			},
+			func(creds bigIPCredentials) error {
+				bs.BigIPUsername = creds.username
+				bs.BigIPPassword = creds.password
+				bs.BigIPURL = creds.url
+				return driver.setBigIPSection(bs)
+			},
+		))
+	}
+	for _, cw := range watchers {
+		go cw.run()
+	}
 
 	var config *rest.Config
 	if *inCluster {
@@ -448,6 +691,12 @@ func main() {
 	if err != nil {
 		log.Fatalf("error connecting to the client: %v", err)
 	}
+	if hasNetworkingIngress(appMgrParms.KubeClient.Discovery()) {
+		appMgrParms.NetworkingClientV1, err = netv1.NewRESTClient(config)
+		if nil != err {
+			log.Fatalf("unable to create networking client: %v", err)
+		}
+	}
 	if *manageRoutes {
 		rclient, err := routeclient.New(config)
 		appMgrParms.RouteClientV1 = rclient.RESTClient
@@ -457,10 +706,54 @@ func main() {
 	}
 
 	appMgr := appmanager.NewManager(&appMgrParms)
+	if nil != driver {
+		driver.setResendFunc(appMgr.ResendConfig)
+	}
+
+	stopCh := make(chan struct{})
+
+	// Only the leader runs the driver and processes changes
+	startLeading := func() {
+		if nil != driver {
+			err := driver.start()
+			if nil != err {
+				log.Fatalf("Could not initialize subprocess configuration: %v", err)
+			}
+		}
+		appMgr.Run(stopCh)
+	}
+
+	var elector *leaderelection.LeaderElector
+	if *leaderElection {
+		elector, err = newLeaderElector(
+			appMgrParms.KubeClient,
+			startLeading,
+			func() {
+				// Another replica may already be configuring the BIG-IP, so
+				// stop at once and let Kubernetes restart this one.
+				driver.stop()
+				log.Fatalf("Lost leadership, exiting")
+			},
+		)
+		if nil != err {
+			log.Fatalf("Failed to set up leader election: %v", err)
+		}
+	}
+
+	if 0 != len(*httpAddress) {
+		isLeader := func() bool {
+			return nil == elector || elector.IsLeader()
+		}
+		mux := newHealthMux(readinessChecks(appMgr, driver), isLeader)
+		mux.Handle("/metrics", prometheus.Handler())
+		server := startHTTPServer(*httpAddress, mux)
+		defer server.Close()
+	}
 
+	var np pollers.Poller
 	if isNodePort || 0 != len(openshiftSDNMode) {
 		intervalFactor := time.Duration(*nodePollInterval)
-		np := pollers.NewNodePoller(appMgrParms.KubeClient, intervalFactor*time.Second)
+		np = pollers.NewNodePoller(appMgrParms.KubeClient, intervalFactor*time.Second)
 		err := setupNodePolling(appMgr, np)
 		if nil != err {
 			log.Fatalf("Required polling utility for node updates failed setup: %v",
@@ -468,18 +761,38 @@ func main() {
 		}
 
 		np.Run()
-		defer np.Stop()
 	}
 
 	setupWatchers(appMgr, 30*time.Second)
 
-	stopCh := make(chan struct{})
-
-	appMgr.Run(stopCh)
+	electionDone := make(chan struct{})
+	if nil != elector {
+		// Followers keep their caches warm so they can take over quickly
+		go appMgr.StartInformers(stopCh)
+		go func() {
+			elector.Run(stopCh)
+			close(electionDone)
+		}()
+	} else {
+		startLeading()
+		close(electionDone)
+	}
 
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-sigs
+	log.Infof("Received signal %v, shutting down", sig)
+	// Stop everything that writes config before the final write
+	if nil != np {
+		np.Stop()
+	}
+	for _, cw := range watchers {
+		cw.stop()
+	}
+	shutdown(time.Duration(*shutdownTimeout)*time.Second, appMgr, driver,
+		configWriter)
+	// Release the leader election lock only once the driver has stopped
 	close(stopCh)
+	<-electionDone
 	log.Infof("Exiting - signal %v\n", sig)
 }
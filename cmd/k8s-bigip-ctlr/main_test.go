@@ -17,19 +17,29 @@
 package main
 
 import (
+	"crypto/md5"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sort"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/metrics"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/writer"
 
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -256,6 +266,164 @@ func TestDriverSubProcess(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func newTestSupervisor(pyDriver string, maxRestarts int) (
+	*driverSupervisor,
+	*test.MockWriter,
+) {
+	configWriter := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	ds := newDriverSupervisor(
+		configWriter,
+		globalSection{LogLevel: "INFO", VerifyInterval: 30},
+		bigIPSection{
+			BigIPUsername:   "admin",
+			BigIPPassword:   "test",
+			BigIPURL:        "https://bigip.example.com",
+			BigIPPartitions: []string{"velcro1"},
+		},
+		"./test",
+		maxRestarts,
+	)
+	ds.pyCmd = pyDriver
+	ds.minBackoff = 10 * time.Millisecond
+	ds.maxBackoff = 40 * time.Millisecond
+	return ds, configWriter
+}
+
+// Total of the driver restarts counter
+func driverRestartsTotal(t *testing.T) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, metrics.DriverRestarts.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestDriverSupervisorCrashLoop(t *testing.T) {
+	ds, configWriter := newTestSupervisor("./test/pyExit.py", 3)
+	restartsBefore := driverRestartsTotal(t)
+
+	resent := 0
+	ds.setResendFunc(func() {
+		resent++
+	})
+	crashErr := make(chan error, 1)
+	ds.onCrashLoop = func(err error) {
+		crashErr <- err
+	}
+
+	err := ds.start()
+	require.NoError(t, err)
+
+	select {
+	case err = <-crashErr:
+		assert.Contains(t, err.Error(), "failed 4 times in a row")
+	case <-time.After(30 * time.Second):
+		t.Fatal("Timed out waiting for the supervisor to give up")
+	}
+	<-ds.doneCh
+
+	// Every restart writes the global, bigip and resources sections again
+	assert.Equal(t, 3, ds.restartCount())
+	assert.Equal(t, restartsBefore+3, driverRestartsTotal(t))
+	assert.Equal(t, 3, resent)
+	assert.Equal(t, 8, configWriter.WrittenTimes)
+	assert.Equal(t, 0, ds.currentPid())
+}
+
+func TestDriverSupervisorStableResets(t *testing.T) {
+	ds, _ := newTestSupervisor("./test/pyExit.py", 1)
+	// Every exit counts as stable, so the driver keeps being restarted
+	ds.stableTime = 0
+	crashed := false
+	ds.onCrashLoop = func(err error) {
+		crashed = true
+	}
+
+	err := ds.start()
+	require.NoError(t, err)
+	time.Sleep(500 * time.Millisecond)
+	ds.stop()
+
+	assert.False(t, crashed)
+	assert.True(t, ds.restartCount() > 1,
+		"Driver should have been restarted more than once")
+}
+
+func TestDriverSupervisorStop(t *testing.T) {
+	ds, configWriter := newTestSupervisor("./test/pyTest.py", 3)
+
+	err := ds.start()
+	require.NoError(t, err)
+
+	var pid int
+	for i := 0; i < 50 && 0 == pid; i++ {
+		time.Sleep(100 * time.Millisecond)
+		pid = ds.currentPid()
+	}
+	require.NotEqual(t, 0, pid, "Driver should be running")
+
+	err = ds.setBigIPSection(bigIPSection{
+		BigIPUsername:   "admin",
+		BigIPPassword:   "rotated",
+		BigIPURL:        "https://bigip.example.com",
+		BigIPPartitions: []string{"velcro1"},
+	})
+	assert.NoError(t, err)
+	configWriter.Lock()
+	assert.Equal(t, "rotated",
+		configWriter.Sections["bigip"].(bigIPSection).BigIPPassword)
+	configWriter.Unlock()
+
+	ds.stop()
+	assert.Equal(t, 0, ds.restartCount())
+
+	// The process has been reaped, so signalling it must fail
+	err = syscall.Kill(pid, 0)
+	assert.Equal(t, syscall.ESRCH, err, "Driver process should have exited")
+}
+
+func TestDriverSupervisorWaitForApply(t *testing.T) {
+	ds, _ := newTestSupervisor("./test/pyTest.py", 3)
+	// A driver that was never started has nothing to apply
+	assert.NoError(t, ds.waitForApply(time.Millisecond))
+
+	configWriter, err := writer.NewConfigWriter()
+	require.NoError(t, err)
+	defer configWriter.Stop()
+	ds.configWriter = configWriter
+	ds.started = true
+	require.NoError(t, writeDriverSection(configWriter, "resources",
+		map[string]string{"test": "final"}))
+
+	contents, err := ioutil.ReadFile(configWriter.GetOutputFilename())
+	require.NoError(t, err)
+	digest := fmt.Sprintf("%x", md5.Sum(contents))
+	statusFile := filepath.Join(
+		filepath.Dir(configWriter.GetOutputFilename()), driverStatusFile)
+	writeStatus := func(status driverStatus) {
+		out, err := json.Marshal(status)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(statusFile, out, 0644))
+	}
+
+	// No status yet
+	assert.Error(t, ds.waitForApply(200*time.Millisecond))
+
+	// An older config, or the current one with errors, is not applied
+	writeStatus(driverStatus{ConfigMD5: "stale"})
+	assert.Error(t, ds.waitForApply(200*time.Millisecond))
+	writeStatus(driverStatus{ConfigMD5: digest, Incomplete: 1})
+	assert.Error(t, ds.waitForApply(200*time.Millisecond))
+
+	// The driver applies the config while we wait
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		writeStatus(driverStatus{ConfigMD5: digest})
+	}()
+	assert.NoError(t, ds.waitForApply(5*time.Second))
+}
+
 func TestVerifyArgs(t *testing.T) {
 	defer _init()
 	os.Args = []string{
@@ -383,6 +551,71 @@ func TestVerifyArgsLabels(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestVerifyArgsNamespacePartitions(t *testing.T) {
+	defer _init()
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--bigip-partition=velcro1",
+		"--bigip-partition=velcro2",
+		"--bigip-password=admin",
+		"--bigip-url=bigip.example.com",
+		"--bigip-username=admin",
+		"--namespace-partition=tenant-a:velcro2",
+		"--namespace-partition=tenant-b:velcro1",
+	}
+
+	flags.Parse(os.Args)
+	err := verifyArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"tenant-a": "velcro2",
+		"tenant-b": "velcro1",
+	}, namespacePartitions)
+
+	for _, invalid := range [][]string{
+		{"tenant-a"},
+		{"tenant-a:"},
+		{"tenant-a:velcro1:velcro2"},
+		{"tenant-a:velcro3"},
+		{"tenant-a:velcro1", "tenant-a:velcro2"},
+	} {
+		*nsPartitions = invalid
+		err = verifyArgs()
+		assert.Error(t, err, "%v", invalid)
+	}
+}
+
+func TestVerifyArgsDefaultSnat(t *testing.T) {
+	defer _init()
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--bigip-partition=velcro1",
+		"--bigip-password=admin",
+		"--bigip-url=bigip.example.com",
+		"--bigip-username=admin",
+	}
+
+	flags.Parse(os.Args)
+	err := verifyArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, &appmanager.SourceAddrTranslation{Type: "automap"},
+		sourceAddrTranslation)
+
+	*defaultSnat = "/Common/snatpool"
+	err = verifyArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, &appmanager.SourceAddrTranslation{
+		Type: "snat",
+		Pool: "/Common/snatpool",
+	}, sourceAddrTranslation)
+
+	for _, invalid := range []string{"", "snatpool", "/Common", "auto"} {
+		*defaultSnat = invalid
+		err = verifyArgs()
+		assert.Error(t, err, "%v", invalid)
+	}
+}
+
 func TestVerifyArgsSDN(t *testing.T) {
 	defer _init()
 	os.Args = []string{
@@ -673,3 +906,55 @@ func TestSetupWatchersLabels(t *testing.T) {
 	nsInf := vsm.GetNamespaceLabelInformer()
 	require.NotNil(t, nsInf)
 }
+
+func TestVerifyArgsIngressClass(t *testing.T) {
+	defer _init()
+	os.Args = []string{
+		"./bin/k8s-bigip-ctlr",
+		"--bigip-partition=velcro1",
+		"--bigip-password=admin",
+		"--bigip-url=bigip.example.com",
+		"--bigip-username=admin",
+	}
+
+	flags.Parse(os.Args)
+	err := verifyArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, appmanager.DefaultIngressClass, *ingressClass)
+	assert.Equal(t, appmanager.DefaultIngressController, *ingressController)
+
+	os.Args = append(os.Args,
+		"--ingress-class=bigip-dmz",
+		"--ingress-controller=example.com/dmz")
+	flags.Parse(os.Args)
+	err = verifyArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, "bigip-dmz", *ingressClass)
+	assert.Equal(t, "example.com/dmz", *ingressController)
+
+	*ingressClass = ""
+	err = verifyArgs()
+	assert.Error(t, err)
+}
+
+func TestHasNetworkingIngress(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	assert.False(t, hasNetworkingIngress(discovery))
+
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "networkpolicies", Kind: "NetworkPolicy"},
+			},
+		},
+	}
+	assert.False(t, hasNetworkingIngress(discovery),
+		"Ingresses are only served from Kubernetes 1.19")
+
+	discovery.Resources[0].APIResources = append(
+		discovery.Resources[0].APIResources,
+		metav1.APIResource{Name: "ingresses", Kind: "Ingress"})
+	assert.True(t, hasNetworkingIngress(discovery))
+}
@@ -18,12 +18,19 @@ package main
 
 import (
 	"bufio"
+	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/metrics"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/writer"
 
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
@@ -40,23 +47,40 @@ func initializeDriverConfig(
 
 	sectionNames := []string{"global", "bigip"}
 	for i, v := range []interface{}{global, bigIP} {
-		doneCh, errCh, err := configWriter.SendSection(sectionNames[i], v)
+		err := writeDriverSection(configWriter, sectionNames[i], v)
 		if nil != err {
-			return fmt.Errorf("failed writing global config section: %v", err)
-		}
-		select {
-		case <-doneCh:
-		case e := <-errCh:
-			return fmt.Errorf("failed writing section %s - %v: %v",
-				sectionNames[i], e, v)
-		case <-time.After(1000 * time.Millisecond):
-			log.Warning("Did not receive config write response in 1 second")
+			return err
 		}
 	}
 
 	return nil
 }
 
+// Write a section of the driver config and wait for the writer to respond
+func writeDriverSection(
+	configWriter writer.Writer,
+	name string,
+	section interface{},
+) error {
+	doneCh, errCh, err := configWriter.SendSection(name, section)
+	if nil != err {
+		metrics.ConfigWrites.WithLabelValues(name, metrics.WriteFailure).Inc()
+		return fmt.Errorf("failed writing %s config section: %v", name, err)
+	}
+	select {
+	case <-doneCh:
+		metrics.ConfigWrites.WithLabelValues(name, metrics.WriteSuccess).Inc()
+	case e := <-errCh:
+		metrics.ConfigWrites.WithLabelValues(name, metrics.WriteFailure).Inc()
+		return fmt.Errorf("failed writing section %s - %v: %v",
+			name, e, section)
+	case <-time.After(1000 * time.Millisecond):
+		metrics.ConfigWrites.WithLabelValues(name, metrics.WriteTimeout).Inc()
+		log.Warning("Did not receive config write response in 1 second")
+	}
+	return nil
+}
+
 func createDriverCmd(
 	configFilename string,
 	pyCmd string,
@@ -72,7 +96,10 @@ func createDriverCmd(
 	return cmd
 }
 
-func runBigIPDriver(pid chan<- int, cmd *exec.Cmd) {
+// Run the driver sub-process until it exits. The pid channel receives the
+// pid once the process has started and is closed without a value if it fails
+// to start.
+func runBigIPDriver(pid chan<- int, cmd *exec.Cmd) error {
 	defer close(pid)
 
 	// the config driver python logging goes to stderr by default
@@ -100,7 +127,7 @@ func runBigIPDriver(pid chan<- int, cmd *exec.Cmd) {
 
 	err = cmd.Start()
 	if nil != err {
-		log.Fatalf("Internal error: failed to start config driver: %v", err)
+		return fmt.Errorf("failed to start config driver: %v", err)
 	}
 	log.Infof("Started config driver sub-process at pid: %d", cmd.Process.Pid)
 
@@ -111,38 +138,386 @@ func runBigIPDriver(pid chan<- int, cmd *exec.Cmd) {
 	if exitError, ok := err.(*exec.ExitError); ok {
 		waitStatus = exitError.Sys().(syscall.WaitStatus)
 		if waitStatus.Signaled() {
-			log.Fatalf("Config driver signaled to stop: %d - %s",
+			return fmt.Errorf("config driver signaled to stop: %d - %s",
 				waitStatus.Signal(), waitStatus.Signal())
-		} else {
-			log.Fatalf("Config driver exited: %d", waitStatus.ExitStatus())
 		}
+		return fmt.Errorf("config driver exited: %d", waitStatus.ExitStatus())
 	} else if nil != err {
-		log.Fatalf("Config driver exited with error: %v", err)
-	} else {
-		waitStatus = cmd.ProcessState.Sys().(syscall.WaitStatus)
-		log.Warningf("Config driver exited normally: %d", waitStatus.ExitStatus())
+		return fmt.Errorf("config driver exited with error: %v", err)
 	}
+	waitStatus = cmd.ProcessState.Sys().(syscall.WaitStatus)
+	log.Warningf("Config driver exited normally: %d", waitStatus.ExitStatus())
+	return nil
+}
+
+// Restart backoff for the driver sub-process
+const (
+	driverMinBackoff = time.Second
+	driverMaxBackoff = 32 * time.Second
+	// A driver that stays up this long is considered healthy again, which
+	// resets the backoff and the crash-loop count.
+	driverStableTime = time.Minute
+	// How long the driver has to exit after being interrupted
+	driverStopTimeout = 10 * time.Second
+)
+
+// The driver writes the result of each apply to this file, next to its
+// config file
+const (
+	driverStatusFile         = "status.json"
+	driverStatusPollInterval = 100 * time.Millisecond
+	// How often the device status is copied to the metrics
+	driverStatusReportInterval = 10 * time.Second
+)
+
+type driverStatus struct {
+	// md5 of the config file contents that were applied
+	ConfigMD5 string `json:"configMD5"`
+	// Number of partitions that failed to apply
+	Incomplete int `json:"incomplete"`
+	// Result for each BIG-IP device, by device name
+	Devices map[string]deviceStatus `json:"devices"`
 }
 
-// Start called to run the python driver
-func startPythonDriver(
+type deviceStatus struct {
+	Incomplete int    `json:"incomplete"`
+	Error      string `json:"error,omitempty"`
+	// Device the config was synced from, for members of a config-sync group
+	ConfigSyncFrom string `json:"configSyncFrom,omitempty"`
+	// Seconds since the epoch
+	Time float64 `json:"time"`
+}
+
+// Runs the python driver and restarts it when it exits. The config sections
+// are written again before every restart so the new process starts from the
+// current config.
+type driverSupervisor struct {
+	sync.Mutex
+	configWriter writer.Writer
+	pyCmd        string
+	global       globalSection
+	bigIP        bigIPSection
+	// Writes the resources section again, set once the app manager exists
+	resend func()
+
+	// Exits allowed in a row, without the driver becoming stable, before
+	// onCrashLoop is called
+	maxRestarts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	stableTime  time.Duration
+	onCrashLoop func(error)
+
+	started  bool
+	pid      int
+	restarts int
+	// Result of the last write of the global or bigip section
+	lastWriteErr error
+	// Serializes updates of the bigip section
+	bigIPMutex sync.Mutex
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+	doneCh     chan struct{}
+}
+
+func newDriverSupervisor(
 	configWriter writer.Writer,
 	global globalSection,
 	bigIP bigIPSection,
 	pythonBaseDir string,
-) (<-chan int, error) {
-	err := initializeDriverConfig(configWriter, global, bigIP)
+	maxRestarts int,
+) *driverSupervisor {
+	return &driverSupervisor{
+		configWriter: configWriter,
+		pyCmd:        fmt.Sprintf("%s/bigipconfigdriver.py", pythonBaseDir),
+		global:       global,
+		bigIP:        bigIP,
+		maxRestarts:  maxRestarts,
+		minBackoff:   driverMinBackoff,
+		maxBackoff:   driverMaxBackoff,
+		stableTime:   driverStableTime,
+		onCrashLoop: func(err error) {
+			log.Fatalf("%v", err)
+		},
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+// Write the initial driver config and start supervising the driver
+func (ds *driverSupervisor) start() error {
+	ds.Lock()
+	global := ds.global
+	bigIP := ds.bigIP
+	ds.Unlock()
+	err := initializeDriverConfig(ds.configWriter, global, bigIP)
 	if nil != err {
-		return nil, err
+		return err
 	}
+	ds.Lock()
+	ds.started = true
+	ds.Unlock()
+	go ds.run()
+	go ds.reportStatus(driverStatusReportInterval)
+	return nil
+}
 
-	subPidCh := make(chan int)
-	pyCmd := fmt.Sprintf("%s/bigipconfigdriver.py", pythonBaseDir)
-	cmd := createDriverCmd(
-		configWriter.GetOutputFilename(),
-		pyCmd,
-	)
-	go runBigIPDriver(subPidCh, cmd)
+// Stop the driver, if it was started, and wait for it to exit
+func (ds *driverSupervisor) stop() {
+	ds.stopOnce.Do(func() {
+		close(ds.stopCh)
+	})
+	ds.Lock()
+	started := ds.started
+	ds.Unlock()
+	if started {
+		<-ds.doneCh
+	}
+}
+
+// Wait for the driver to report that it applied the current config file
+// without errors. Returns at once if the driver was never started.
+func (ds *driverSupervisor) waitForApply(timeout time.Duration) error {
+	ds.Lock()
+	started := ds.started
+	ds.Unlock()
+	if !started {
+		return nil
+	}
+
+	configFile := ds.configWriter.GetOutputFilename()
+	contents, err := ioutil.ReadFile(configFile)
+	if nil != err {
+		return fmt.Errorf("Unable to read config file: %v", err)
+	}
+	digest := fmt.Sprintf("%x", md5.Sum(contents))
+	statusFile := filepath.Join(filepath.Dir(configFile), driverStatusFile)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := readDriverStatus(statusFile)
+		if nil == err && status.ConfigMD5 == digest && 0 == status.Incomplete {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf(
+				"Config driver did not apply the current config within %v", timeout)
+		}
+		time.Sleep(driverStatusPollInterval)
+	}
+}
 
-	return subPidCh, nil
+func readDriverStatus(statusFile string) (driverStatus, error) {
+	var status driverStatus
+	contents, err := ioutil.ReadFile(statusFile)
+	if nil != err {
+		return status, err
+	}
+	err = json.Unmarshal(contents, &status)
+	return status, err
+}
+
+// Update the bigip section and write it out for the driver
+func (ds *driverSupervisor) setBigIPSection(bigIP bigIPSection) error {
+	ds.bigIPMutex.Lock()
+	defer ds.bigIPMutex.Unlock()
+	return ds.setBigIPSectionLocked(bigIP)
+}
+
+// Update the credentials of one device in the bigip section and write it out
+// for the driver
+func (ds *driverSupervisor) setDeviceCredentials(
+	name string,
+	creds bigIPCredentials,
+) error {
+	ds.bigIPMutex.Lock()
+	defer ds.bigIPMutex.Unlock()
+
+	ds.Lock()
+	bigIP := ds.bigIP
+	ds.Unlock()
+	// Copy the devices so the section already written is left unchanged
+	bigIP.Devices = append([]bigIPDevice{}, bigIP.Devices...)
+	for i := range bigIP.Devices {
+		if bigIP.Devices[i].Name == name {
+			bigIP.Devices[i].setCredentials(creds)
+			return ds.setBigIPSectionLocked(bigIP)
+		}
+	}
+	return fmt.Errorf("Unknown BIG-IP device %s", name)
+}
+
+// This function MUST be called with the bigIPMutex held
+func (ds *driverSupervisor) setBigIPSectionLocked(bigIP bigIPSection) error {
+	ds.Lock()
+	ds.bigIP = bigIP
+	ds.Unlock()
+	err := writeDriverSection(ds.configWriter, "bigip", bigIP)
+	ds.setLastWriteError(err)
+	return err
+}
+
+// Copy the status the driver reports for each device to the metrics until
+// the supervisor is stopped
+func (ds *driverSupervisor) reportStatus(interval time.Duration) {
+	statusFile := filepath.Join(
+		filepath.Dir(ds.configWriter.GetOutputFilename()), driverStatusFile)
+	for {
+		select {
+		case <-ds.stopCh:
+			return
+		case <-time.After(interval):
+		}
+		status, err := readDriverStatus(statusFile)
+		if nil != err {
+			// Nothing has been applied yet
+			continue
+		}
+		for name, device := range status.Devices {
+			metrics.DeviceIncomplete.WithLabelValues(name).Set(
+				float64(device.Incomplete))
+			metrics.DeviceLastApply.WithLabelValues(name).Set(device.Time)
+		}
+	}
+}
+
+func (ds *driverSupervisor) setLastWriteError(err error) {
+	ds.Lock()
+	defer ds.Unlock()
+	ds.lastWriteErr = err
+}
+
+// Error from the last write of a driver section, nil if it succeeded
+func (ds *driverSupervisor) lastWriteError() error {
+	ds.Lock()
+	defer ds.Unlock()
+	return ds.lastWriteErr
+}
+
+// Whether the driver sub-process is currently running
+func (ds *driverSupervisor) alive() bool {
+	return 0 != ds.currentPid()
+}
+
+func (ds *driverSupervisor) setResendFunc(resend func()) {
+	ds.Lock()
+	defer ds.Unlock()
+	ds.resend = resend
+}
+
+// Number of times the driver has been restarted
+func (ds *driverSupervisor) restartCount() int {
+	ds.Lock()
+	defer ds.Unlock()
+	return ds.restarts
+}
+
+func (ds *driverSupervisor) currentPid() int {
+	ds.Lock()
+	defer ds.Unlock()
+	return ds.pid
+}
+
+func (ds *driverSupervisor) run() {
+	defer close(ds.doneCh)
+
+	backoff := ds.minBackoff
+	failures := 0
+	for {
+		pidCh := make(chan int)
+		exitCh := make(chan error, 1)
+		cmd := createDriverCmd(ds.configWriter.GetOutputFilename(), ds.pyCmd)
+		go func() {
+			exitCh <- runBigIPDriver(pidCh, cmd)
+		}()
+		pid := <-pidCh
+		ds.Lock()
+		ds.pid = pid
+		ds.Unlock()
+		startTime := time.Now()
+
+		var err error
+		select {
+		case err = <-exitCh:
+		case <-ds.stopCh:
+			ds.stopDriver(pid)
+			select {
+			case <-exitCh:
+			case <-time.After(driverStopTimeout):
+				log.Warningf("Config driver did not stop, killing pid %d", pid)
+				cmd.Process.Kill()
+				<-exitCh
+			}
+			return
+		}
+		ds.Lock()
+		ds.pid = 0
+		ds.Unlock()
+		if nil == err {
+			err = fmt.Errorf("config driver exited")
+		}
+
+		if time.Since(startTime) >= ds.stableTime {
+			failures = 0
+			backoff = ds.minBackoff
+		}
+		failures++
+		if failures > ds.maxRestarts {
+			ds.onCrashLoop(fmt.Errorf(
+				"Config driver failed %d times in a row, giving up: %v",
+				failures, err))
+			return
+		}
+
+		log.Errorf("Restarting config driver in %v: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ds.stopCh:
+			return
+		}
+		backoff *= 2
+		if backoff > ds.maxBackoff {
+			backoff = ds.maxBackoff
+		}
+
+		ds.Lock()
+		ds.restarts++
+		metrics.DriverRestarts.Inc()
+		log.Infof("Config driver restart count: %d", ds.restarts)
+		ds.Unlock()
+		ds.resendSections()
+	}
+}
+
+// Write every config section again for a restarted driver
+func (ds *driverSupervisor) resendSections() {
+	ds.Lock()
+	global := ds.global
+	bigIP := ds.bigIP
+	resend := ds.resend
+	ds.Unlock()
+
+	err := initializeDriverConfig(ds.configWriter, global, bigIP)
+	if nil != err {
+		log.Warningf("Failed to rewrite config driver sections: %v", err)
+	}
+	ds.setLastWriteError(err)
+	if nil != resend {
+		resend()
+	}
+}
+
+func (ds *driverSupervisor) stopDriver(pid int) {
+	if 0 == pid {
+		return
+	}
+	proc, err := os.FindProcess(pid)
+	if nil != err {
+		log.Warningf("Failed to find sub-process on exit: %v", err)
+		return
+	}
+	err = proc.Signal(os.Interrupt)
+	if nil != err {
+		log.Warningf("Could not stop sub-process on exit: %d - %v", pid, err)
+	}
 }
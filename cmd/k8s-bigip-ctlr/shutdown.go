@@ -0,0 +1,55 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"time"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/writer"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+)
+
+// Stop the controller without leaving a half applied config on the BIG-IP.
+// Changes stop being queued, the queued changes are processed and the final
+// config is written. Once the driver reports that it applied the final config,
+// or timeout passes, the driver and then the config writer are stopped.
+func shutdown(
+	timeout time.Duration,
+	appMgr *appmanager.Manager,
+	driver *driverSupervisor,
+	configWriter writer.Writer,
+) {
+	deadline := time.Now().Add(timeout)
+	err := appMgr.Drain(timeout)
+	if nil != err {
+		log.Warningf("Failed to write the final config: %v", err)
+	} else if nil != driver {
+		err = driver.waitForApply(deadline.Sub(time.Now()))
+		if nil != err {
+			log.Warningf("Stopping before the final config was applied: %v", err)
+		} else {
+			log.Info("Config driver applied the final config")
+		}
+	}
+
+	if nil != driver {
+		driver.stop()
+	}
+	configWriter.Stop()
+}
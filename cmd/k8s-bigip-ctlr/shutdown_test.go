@@ -0,0 +1,55 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/appmanager"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown(t *testing.T) {
+	// The driver is never started, as on a standby replica
+	ds, configWriter := newTestSupervisor("./test/pyTest.py", 3)
+	appMgr := appmanager.NewManager(&appmanager.Params{
+		ConfigWriter: configWriter,
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	appMgr.Run(stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		shutdown(5*time.Second, appMgr, ds, configWriter)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for shutdown")
+	}
+
+	configWriter.Lock()
+	_, found := configWriter.Sections["resources"]
+	configWriter.Unlock()
+	assert.True(t, found, "Shutdown should write the final config")
+	assert.True(t, appMgr.InitialStateReached())
+	assert.False(t, ds.alive())
+}
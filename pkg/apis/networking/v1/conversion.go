@@ -0,0 +1,77 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+// Convert an extensions/v1beta1 Ingress, from clusters without the
+// networking.k8s.io/v1 API. Its paths have no type, so they are
+// ImplementationSpecific, and its class is only set by annotation.
+func ConvertV1beta1Ingress(in *v1beta1.Ingress) *Ingress {
+	out := &Ingress{
+		// Events still refer to the original object
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "extensions/v1beta1",
+			Kind:       "Ingress",
+		},
+		// The annotations and labels maps are shared
+		ObjectMeta: in.ObjectMeta,
+	}
+	if nil != in.Spec.Backend {
+		out.Spec.DefaultBackend = convertV1beta1Backend(in.Spec.Backend)
+	}
+	for _, tls := range in.Spec.TLS {
+		out.Spec.TLS = append(out.Spec.TLS, IngressTLS{
+			Hosts:      tls.Hosts,
+			SecretName: tls.SecretName,
+		})
+	}
+	for _, inRule := range in.Spec.Rules {
+		rule := IngressRule{Host: inRule.Host}
+		if nil != inRule.HTTP {
+			rule.HTTP = &HTTPIngressRuleValue{}
+			for _, inPath := range inRule.HTTP.Paths {
+				pathType := PathTypeImplementationSpecific
+				rule.HTTP.Paths = append(rule.HTTP.Paths, HTTPIngressPath{
+					Path:     inPath.Path,
+					PathType: &pathType,
+					Backend:  *convertV1beta1Backend(&inPath.Backend),
+				})
+			}
+		}
+		out.Spec.Rules = append(out.Spec.Rules, rule)
+	}
+	for _, lb := range in.Status.LoadBalancer.Ingress {
+		out.Status.LoadBalancer.Ingress = append(out.Status.LoadBalancer.Ingress,
+			IngressLoadBalancerIngress{IP: lb.IP, Hostname: lb.Hostname})
+	}
+	return out
+}
+
+func convertV1beta1Backend(in *v1beta1.IngressBackend) *IngressBackend {
+	svc := &IngressServiceBackend{Name: in.ServiceName}
+	if intstr.String == in.ServicePort.Type {
+		svc.Port.Name = in.ServicePort.StrVal
+	} else {
+		svc.Port.Number = in.ServicePort.IntVal
+	}
+	return &IngressBackend{Service: svc}
+}
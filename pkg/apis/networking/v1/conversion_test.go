@@ -0,0 +1,113 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+func TestConvertV1beta1Ingress(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	in := &v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ingress",
+			Namespace:   "default",
+			Annotations: map[string]string{"kubernetes.io/ingress.class": "f5"},
+		},
+		Spec: v1beta1.IngressSpec{
+			Backend: &v1beta1.IngressBackend{
+				ServiceName: "foo",
+				ServicePort: intstr.FromInt(80),
+			},
+			TLS: []v1beta1.IngressTLS{
+				{Hosts: []string{"foo.com"}, SecretName: "foo-cert"},
+			},
+			Rules: []v1beta1.IngressRule{
+				{
+					Host: "foo.com",
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{
+							Paths: []v1beta1.HTTPIngressPath{
+								{
+									Path: "/bar",
+									Backend: v1beta1.IngressBackend{
+										ServiceName: "bar",
+										ServicePort: intstr.FromString("http"),
+									},
+								},
+							},
+						},
+					},
+				},
+				{Host: "empty.com"},
+			},
+		},
+		Status: v1beta1.IngressStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+			},
+		},
+	}
+
+	out := ConvertV1beta1Ingress(in)
+	assert.Equal("extensions/v1beta1", out.TypeMeta.APIVersion)
+	assert.Equal("Ingress", out.TypeMeta.Kind)
+	assert.Equal(in.ObjectMeta, out.ObjectMeta)
+	assert.Nil(out.Spec.IngressClassName,
+		"Class annotation should not become the class name")
+
+	require.NotNil(out.Spec.DefaultBackend)
+	require.NotNil(out.Spec.DefaultBackend.Service)
+	assert.Equal(IngressServiceBackend{
+		Name: "foo",
+		Port: ServiceBackendPort{Number: 80},
+	}, *out.Spec.DefaultBackend.Service)
+	assert.Equal([]IngressTLS{
+		{Hosts: []string{"foo.com"}, SecretName: "foo-cert"},
+	}, out.Spec.TLS)
+
+	require.Equal(2, len(out.Spec.Rules))
+	assert.Equal("foo.com", out.Spec.Rules[0].Host)
+	require.NotNil(out.Spec.Rules[0].HTTP)
+	require.Equal(1, len(out.Spec.Rules[0].HTTP.Paths))
+	path := out.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal("/bar", path.Path)
+	require.NotNil(path.PathType)
+	assert.Equal(PathTypeImplementationSpecific, *path.PathType)
+	require.NotNil(path.Backend.Service)
+	assert.Equal(IngressServiceBackend{
+		Name: "bar",
+		Port: ServiceBackendPort{Name: "http"},
+	}, *path.Backend.Service)
+	assert.Equal("empty.com", out.Spec.Rules[1].Host)
+	assert.Nil(out.Spec.Rules[1].HTTP)
+
+	assert.Equal([]IngressLoadBalancerIngress{{IP: "1.2.3.4"}},
+		out.Status.LoadBalancer.Ingress)
+
+	// Ingresses with only rules have no default backend
+	in.Spec.Backend = nil
+	out = ConvertV1beta1Ingress(in)
+	assert.Nil(out.Spec.DefaultBackend)
+}
@@ -0,0 +1,148 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package v1 has the networking.k8s.io/v1 Ingress and IngressClass types.
+// The vendored client-go predates them, so only the fields the controller
+// uses are defined here.
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type (
+	// Ingress exposes Services through HTTP rules
+	Ingress struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   IngressSpec   `json:"spec,omitempty"`
+		Status IngressStatus `json:"status,omitempty"`
+	}
+
+	IngressList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []Ingress `json:"items"`
+	}
+
+	IngressSpec struct {
+		// Name of the IngressClass of the Ingress
+		IngressClassName *string `json:"ingressClassName,omitempty"`
+		// Backend for the requests no rule matches
+		DefaultBackend *IngressBackend `json:"defaultBackend,omitempty"`
+		TLS            []IngressTLS    `json:"tls,omitempty"`
+		Rules          []IngressRule   `json:"rules,omitempty"`
+	}
+
+	// Only Service backends are supported, not resource backends
+	IngressBackend struct {
+		Service *IngressServiceBackend `json:"service,omitempty"`
+	}
+
+	IngressServiceBackend struct {
+		Name string             `json:"name"`
+		Port ServiceBackendPort `json:"port,omitempty"`
+	}
+
+	// Port of a Service, by name or by number
+	ServiceBackendPort struct {
+		Name   string `json:"name,omitempty"`
+		Number int32  `json:"number,omitempty"`
+	}
+
+	IngressTLS struct {
+		Hosts      []string `json:"hosts,omitempty"`
+		SecretName string   `json:"secretName,omitempty"`
+	}
+
+	IngressStatus struct {
+		LoadBalancer IngressLoadBalancerStatus `json:"loadBalancer,omitempty"`
+	}
+
+	IngressLoadBalancerStatus struct {
+		Ingress []IngressLoadBalancerIngress `json:"ingress,omitempty"`
+	}
+
+	IngressLoadBalancerIngress struct {
+		IP       string `json:"ip,omitempty"`
+		Hostname string `json:"hostname,omitempty"`
+	}
+
+	IngressRule struct {
+		Host             string `json:"host,omitempty"`
+		IngressRuleValue `json:",inline,omitempty"`
+	}
+
+	IngressRuleValue struct {
+		HTTP *HTTPIngressRuleValue `json:"http,omitempty"`
+	}
+
+	HTTPIngressRuleValue struct {
+		Paths []HTTPIngressPath `json:"paths"`
+	}
+
+	HTTPIngressPath struct {
+		Path     string         `json:"path,omitempty"`
+		PathType *PathType      `json:"pathType,omitempty"`
+		Backend  IngressBackend `json:"backend"`
+	}
+
+	// How the path of an HTTPIngressPath matches the request path
+	PathType string
+
+	// IngressClass names the controller for the Ingresses of its class
+	IngressClass struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec IngressClassSpec `json:"spec,omitempty"`
+	}
+
+	IngressClassList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []IngressClass `json:"items"`
+	}
+
+	IngressClassSpec struct {
+		Controller string                           `json:"controller,omitempty"`
+		Parameters *IngressClassParametersReference `json:"parameters,omitempty"`
+	}
+
+	// Reference to a resource with the parameters of an IngressClass
+	IngressClassParametersReference struct {
+		APIGroup  *string `json:"apiGroup,omitempty"`
+		Kind      string  `json:"kind"`
+		Name      string  `json:"name"`
+		Scope     *string `json:"scope,omitempty"`
+		Namespace *string `json:"namespace,omitempty"`
+	}
+)
+
+const (
+	// The path must match the request path exactly
+	PathTypeExact = PathType("Exact")
+	// The path matches request paths starting with its segments
+	PathTypePrefix = PathType("Prefix")
+	// The controller decides how the path matches
+	PathTypeImplementationSpecific = PathType("ImplementationSpecific")
+)
+
+// Annotation of the IngressClass for the Ingresses that do not name one
+const AnnotationIsDefaultIngressClass = "ingressclass.kubernetes.io/is-default-class"
@@ -27,6 +27,8 @@ import (
 	"sync"
 	"time"
 
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/metrics"
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/writer"
 
@@ -37,6 +39,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	watch "k8s.io/apimachinery/pkg/watch"
@@ -52,9 +55,19 @@ import (
 
 const DefaultConfigMapLabel = "f5type in (virtual-server)"
 const vsBindAddrAnnotation = "status.virtual-server.f5.com/ip"
+const vsErrorAnnotation = "status.virtual-server.f5.com/error"
 const ingressSslRedirect = "ingress.kubernetes.io/ssl-redirect"
 const ingressAllowHttp = "ingress.kubernetes.io/allow-http"
 const ingHealthMonitorAnnotation = "virtual-server.f5.com/health"
+const ingSnatAnnotation = "virtual-server.f5.com/snat"
+const ingPersistenceAnnotation = "virtual-server.f5.com/persistence"
+const ingPathMatchAnnotation = "virtual-server.f5.com/path-match"
+const ingConditionsAnnotation = "virtual-server.f5.com/conditions"
+const ingRewriteTargetAnnotation = "virtual-server.f5.com/rewrite-target"
+const ingRewriteHostAnnotation = "virtual-server.f5.com/rewrite-host"
+const ingAppRootAnnotation = "virtual-server.f5.com/app-root"
+const ingRedirectURLAnnotation = "virtual-server.f5.com/redirect-url"
+const ingRedirectCodeAnnotation = "virtual-server.f5.com/redirect-code"
 
 type ResourceMap map[int32][]*ResourceConfig
 
@@ -66,9 +79,22 @@ type Manager struct {
 	kubeClient        kubernetes.Interface
 	restClientv1      rest.Interface
 	restClientv1beta1 rest.Interface
+	netClientV1       rest.Interface
 	routeClientV1     rest.Interface
 	configWriter      writer.Writer
 	initialState      bool
+	// Result of the last resources write, protected by the resources lock
+	lastWriteErr error
+	// Whether the informer caches have completed their initial sync
+	cacheSyncMutex     sync.Mutex
+	cachesSynced       bool
+	startInformersOnce sync.Once
+	// Created by Run and closed once the virtual server worker has drained
+	// the queue after it was shut down
+	runMutex         sync.Mutex
+	vsWorkerDone     chan struct{}
+	vsWorkerDoneOnce sync.Once
+	shutDownOnce     sync.Once
 	// Use internal node IPs
 	useNodeInternal bool
 	// Running in nodeport (or cluster) mode
@@ -95,8 +121,49 @@ type Manager struct {
 	broadcaster   record.EventBroadcaster
 	eventRecorder record.EventRecorder
 	eventSource   v1.EventSource
+	eventSink     sync.Once
 	// Route configurations
 	routeConfig RouteConfig
+	// Partitions managed in addition to DEFAULT_PARTITION
+	partitions []string
+	// Partitions for the resources in each namespace, overriding the
+	// namespace partition annotation
+	namespacePartitions map[string]string
+	// All namespaces, for their partition annotation
+	nsPartitionInformer cache.SharedIndexInformer
+	// SNAT of the virtual servers that do not set it
+	defaultSnat *SourceAddrTranslation
+	// The Ingresses the controller handles: the class name of their
+	// annotation or IngressClass, and the controller of their IngressClass
+	ingressClass      string
+	ingressController string
+	// IngressClasses, only in the networking.k8s.io/v1 API
+	ingClassInformer cache.SharedIndexInformer
+	// Informers for the ConfigMaps referenced by the parameters of
+	// IngressClasses, by namespace/name, started when first used until they
+	// are stopped
+	classParamsMutex    sync.Mutex
+	classParamsInformer map[string]cache.SharedIndexInformer
+	classParamsStopCh   chan struct{}
+	classParamsStopped  bool
+	// The last error of each invalid Ingress annotation, so its Event is
+	// only recorded once
+	ingressErrorsMutex sync.Mutex
+	ingressErrors      map[ingressErrorKey]ingressError
+	// Whether writes to the cluster are only logged, for dry runs
+	readOnly bool
+}
+
+type ingressErrorKey struct {
+	Namespace  string
+	Name       string
+	Annotation string
+}
+
+// The UID tells a recreated Ingress from the one the error was recorded for
+type ingressError struct {
+	uid     types.UID
+	message string
 }
 
 // Struct to allow NewManager to receive all or only specific parameters.
@@ -108,8 +175,23 @@ type Params struct {
 	UseNodeInternal bool
 	IsNodePort      bool
 	RouteConfig     RouteConfig
-	InitialState    bool                 // Unit testing only
-	EventRecorder   record.EventRecorder // Unit testing only
+	// BIG-IP partitions the controller manages
+	ManagedPartitions []string
+	// Map from namespace to the BIG-IP partition for its resources
+	NamespacePartitions map[string]string
+	// Set when the cluster serves the networking.k8s.io/v1 Ingress API,
+	// which then replaces extensions/v1beta1
+	NetworkingClientV1 rest.Interface
+	// Ingress class name and IngressClass controller of the controller
+	IngressClass      string
+	IngressController string
+	// SNAT of the virtual servers that do not set it
+	DefaultSnat *SourceAddrTranslation
+	// Do not write status, annotations or Events to the cluster, for dry
+	// runs
+	ReadOnly      bool
+	InitialState  bool                 // Unit testing only
+	EventRecorder record.EventRecorder // Unit testing only
 }
 
 // Configuration options for Routes in OpenShift
@@ -126,23 +208,31 @@ func NewManager(params *Params) *Manager {
 	nsQueue := workqueue.NewNamedRateLimitingQueue(
 		workqueue.DefaultControllerRateLimiter(), "namespace-controller")
 	manager := Manager{
-		resources:         NewResources(),
-		customProfiles:    NewCustomProfiles(),
-		irulesMap:         make(IRulesMap),
-		intDgMap:          make(InternalDataGroupMap),
-		kubeClient:        params.KubeClient,
-		restClientv1:      params.restClient,
-		restClientv1beta1: params.restClient,
-		routeClientV1:     params.RouteClientV1,
-		configWriter:      params.ConfigWriter,
-		useNodeInternal:   params.UseNodeInternal,
-		isNodePort:        params.IsNodePort,
-		initialState:      params.InitialState,
-		eventRecorder:     params.EventRecorder,
-		routeConfig:       params.RouteConfig,
-		vsQueue:           vsQueue,
-		nsQueue:           nsQueue,
-		appInformers:      make(map[string]*appInformer),
+		resources:           NewResources(),
+		customProfiles:      NewCustomProfiles(),
+		irulesMap:           make(IRulesMap),
+		intDgMap:            make(InternalDataGroupMap),
+		kubeClient:          params.KubeClient,
+		restClientv1:        params.restClient,
+		restClientv1beta1:   params.restClient,
+		netClientV1:         params.NetworkingClientV1,
+		routeClientV1:       params.RouteClientV1,
+		configWriter:        params.ConfigWriter,
+		useNodeInternal:     params.UseNodeInternal,
+		isNodePort:          params.IsNodePort,
+		initialState:        params.InitialState,
+		eventRecorder:       params.EventRecorder,
+		routeConfig:         params.RouteConfig,
+		partitions:          params.ManagedPartitions,
+		namespacePartitions: params.NamespacePartitions,
+		defaultSnat:         params.DefaultSnat,
+		ingressClass:        params.IngressClass,
+		ingressController:   params.IngressController,
+		readOnly:            params.ReadOnly,
+		vsQueue:             vsQueue,
+		nsQueue:             nsQueue,
+		appInformers:        make(map[string]*appInformer),
+		ingressErrors:       make(map[ingressErrorKey]ingressError),
 	}
 	if nil != manager.kubeClient && nil == manager.restClientv1 {
 		// This is the normal production case, but need the checks for unit tests.
@@ -152,6 +242,18 @@ func NewManager(params *Params) *Manager {
 		// This is the normal production case, but need the checks for unit tests.
 		manager.restClientv1beta1 = manager.kubeClient.Extensions().RESTClient()
 	}
+	if "" == manager.ingressClass {
+		manager.ingressClass = DefaultIngressClass
+	}
+	if "" == manager.ingressController {
+		manager.ingressController = DefaultIngressController
+	}
+	if nil != manager.kubeClient {
+		manager.nsPartitionInformer = manager.newNamespacePartitionInformer(0)
+	}
+	if nil != manager.netClientV1 {
+		manager.ingClassInformer = manager.newIngressClassInformer(0)
+	}
 	manager.eventSource = v1.EventSource{Component: "k8s-bigip-ctlr"}
 	manager.broadcaster = record.NewBroadcaster()
 	if nil == manager.eventRecorder {
@@ -430,7 +532,22 @@ func (appMgr *Manager) newAppInformer(
 			resyncPeriod,
 			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 		),
-		ingInformer: cache.NewSharedIndexInformer(
+	}
+	// Newer clusters no longer serve the extensions/v1beta1 API
+	if nil != appMgr.netClientV1 {
+		appInf.ingInformer = cache.NewSharedIndexInformer(
+			newListWatchWithLabelSelector(
+				appMgr.netClientV1,
+				"ingresses",
+				namespace,
+				labels.Everything(),
+			),
+			&netv1.Ingress{},
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+	} else {
+		appInf.ingInformer = cache.NewSharedIndexInformer(
 			newListWatchWithLabelSelector(
 				appMgr.restClientv1beta1,
 				"ingresses",
@@ -440,7 +557,7 @@ func (appMgr *Manager) newAppInformer(
 			&v1beta1.Ingress{},
 			resyncPeriod,
 			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-		),
+		)
 	}
 	if nil != appMgr.routeClientV1 {
 		appInf.routeInformer = cache.NewSharedIndexInformer(
@@ -623,7 +740,12 @@ func (appInf *appInformer) waitForCacheSync() {
 }
 
 func (appInf *appInformer) stopInformers() {
-	close(appInf.stopCh)
+	select {
+	case <-appInf.stopCh:
+		// Already stopped by Drain
+	default:
+		close(appInf.stopCh)
+	}
 }
 
 func (appMgr *Manager) IsNodePort() bool {
@@ -638,30 +760,108 @@ func (appMgr *Manager) ConfigWriter() writer.Writer {
 	return appMgr.configWriter
 }
 
+// Whether the namespace and app informer caches have synced
+func (appMgr *Manager) CachesSynced() bool {
+	appMgr.cacheSyncMutex.Lock()
+	defer appMgr.cacheSyncMutex.Unlock()
+	return appMgr.cachesSynced
+}
+
+// Whether the first complete config has been written for the driver
+func (appMgr *Manager) InitialStateReached() bool {
+	appMgr.resources.Lock()
+	defer appMgr.resources.Unlock()
+	return appMgr.initialState
+}
+
+// Error from the last attempt to write the resources section, nil if it
+// succeeded or nothing has been written yet
+func (appMgr *Manager) LastWriteError() error {
+	appMgr.resources.Lock()
+	defer appMgr.resources.Unlock()
+	return appMgr.lastWriteErr
+}
+
+func (appMgr *Manager) setCachesSynced(synced bool) {
+	appMgr.cacheSyncMutex.Lock()
+	defer appMgr.cacheSyncMutex.Unlock()
+	appMgr.cachesSynced = synced
+}
+
 func (appMgr *Manager) Run(stopCh <-chan struct{}) {
+	appMgr.runMutex.Lock()
+	appMgr.vsWorkerDone = make(chan struct{})
+	appMgr.runMutex.Unlock()
 	go appMgr.runImpl(stopCh)
 }
 
+// Stop processing changes and write the final config. Changes are no longer
+// queued, the changes already queued are processed and the config is then
+// written once more. Returns an error if the queue does not drain within
+// timeout or the final write fails.
+func (appMgr *Manager) Drain(timeout time.Duration) error {
+	appMgr.runMutex.Lock()
+	done := appMgr.vsWorkerDone
+	appMgr.runMutex.Unlock()
+	if nil == done {
+		// Never started, e.g. a standby replica
+		return nil
+	}
+
+	// A shut down queue ignores new items but still hands out queued ones
+	appMgr.stopAppInformers()
+	appMgr.shutDownQueues()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		return fmt.Errorf("Timed out draining %d queued changes",
+			appMgr.vsQueue.Len())
+	}
+
+	appMgr.outputConfig()
+	return appMgr.LastWriteError()
+}
+
+// Start the informers and wait for their caches to sync without processing
+// any changes. This lets a standby controller keep its caches warm; Run
+// starts the informers itself if this has not been called.
+func (appMgr *Manager) StartInformers(stopCh <-chan struct{}) {
+	appMgr.startInformersOnce.Do(func() {
+		if nil != appMgr.nsInformer {
+			appMgr.startAndSyncNamespaceInformer(stopCh)
+		}
+		if nil != appMgr.nsPartitionInformer {
+			appMgr.startAndSyncNamespacePartitionInformer(stopCh)
+		}
+		if nil != appMgr.ingClassInformer {
+			appMgr.startAndSyncIngressClassInformer(stopCh)
+		}
+		appMgr.startAndSyncAppInformers()
+		appMgr.setCachesSynced(true)
+	})
+}
+
 func (appMgr *Manager) runImpl(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
-	defer appMgr.vsQueue.ShutDown()
-	defer appMgr.nsQueue.ShutDown()
+	defer appMgr.shutDownQueues()
 
 	if nil != appMgr.routeClientV1 {
-		appMgr.addIRule(
-			sslPassthroughIRuleName, DEFAULT_PARTITION, sslPassthroughIRule())
-		appMgr.addInternalDataGroup(passthroughHostsDgName, DEFAULT_PARTITION)
-		appMgr.addInternalDataGroup(reencryptHostsDgName, DEFAULT_PARTITION)
+		// Routes from any namespace may use any managed partition
+		for _, partition := range appMgr.managedPartitions() {
+			appMgr.addIRule(
+				sslPassthroughIRuleName, partition, sslPassthroughIRule())
+			appMgr.addInternalDataGroup(passthroughHostsDgName, partition)
+			appMgr.addInternalDataGroup(reencryptHostsDgName, partition)
+		}
 	}
 
+	appMgr.StartInformers(stopCh)
+
 	if nil != appMgr.nsInformer {
 		// Using one worker for namespace label changes.
-		appMgr.startAndSyncNamespaceInformer(stopCh)
 		go wait.Until(appMgr.namespaceWorker, time.Second, stopCh)
 	}
 
-	appMgr.startAndSyncAppInformers()
-
 	// Using only one virtual server worker currently.
 	go wait.Until(appMgr.virtualServerWorker, time.Second, stopCh)
 
@@ -669,6 +869,15 @@ func (appMgr *Manager) runImpl(stopCh <-chan struct{}) {
 	appMgr.stopAppInformers()
 }
 
+// The queues panic if shut down twice, and both Drain and stopping Run
+// shut them down
+func (appMgr *Manager) shutDownQueues() {
+	appMgr.shutDownOnce.Do(func() {
+		appMgr.nsQueue.ShutDown()
+		appMgr.vsQueue.ShutDown()
+	})
+}
+
 func (appMgr *Manager) startAndSyncNamespaceInformer(stopCh <-chan struct{}) {
 	appMgr.informersMutex.Lock()
 	defer appMgr.informersMutex.Unlock()
@@ -707,11 +916,20 @@ func (appMgr *Manager) stopAppInformers() {
 	for _, appInf := range appMgr.appInformers {
 		appInf.stopInformers()
 	}
+	appMgr.stopIngressClassParamsInformers()
 }
 
 func (appMgr *Manager) virtualServerWorker() {
 	for appMgr.processNextVirtualServer() {
 	}
+	// The queue has been shut down and drained
+	appMgr.runMutex.Lock()
+	defer appMgr.runMutex.Unlock()
+	if nil != appMgr.vsWorkerDone {
+		appMgr.vsWorkerDoneOnce.Do(func() {
+			close(appMgr.vsWorkerDone)
+		})
+	}
 }
 
 func (appMgr *Manager) processNextVirtualServer() bool {
@@ -742,10 +960,20 @@ type vsSyncStats struct {
 	dgUpdated int
 }
 
+// Add the stats from one sync to the metrics totals
+func (stats *vsSyncStats) record() {
+	metrics.SyncStats.WithLabelValues("vs_found").Add(float64(stats.vsFound))
+	metrics.SyncStats.WithLabelValues("vs_updated").Add(float64(stats.vsUpdated))
+	metrics.SyncStats.WithLabelValues("vs_deleted").Add(float64(stats.vsDeleted))
+	metrics.SyncStats.WithLabelValues("cp_updated").Add(float64(stats.cpUpdated))
+	metrics.SyncStats.WithLabelValues("dg_updated").Add(float64(stats.dgUpdated))
+}
+
 func (appMgr *Manager) syncVirtualServer(sKey serviceQueueKey) error {
 	startTime := time.Now()
 	defer func() {
 		endTime := time.Now()
+		metrics.SyncDuration.Observe(endTime.Sub(startTime).Seconds())
 		log.Debugf("Finished syncing virtual servers %+v (%v)",
 			sKey, endTime.Sub(startTime))
 	}()
@@ -788,18 +1016,26 @@ func (appMgr *Manager) syncVirtualServer(sKey serviceQueueKey) error {
 	// rsMap stores all resources currently in Resources matching sKey, indexed by port
 	rsMap := appMgr.getResourcesForKey(sKey)
 
+	nsPart := appMgr.namespacePartition(sKey.Namespace)
+	if nil != nsPart.err {
+		log.Warningf("%v", nsPart.err)
+	}
+
 	var stats vsSyncStats
-	err = appMgr.syncConfigMaps(&stats, sKey, rsMap, svcPortMap, svc, appInf)
+	err = appMgr.syncConfigMaps(
+		&stats, sKey, rsMap, svcPortMap, svc, appInf, nsPart)
 	if nil != err {
 		return err
 	}
 
-	err = appMgr.syncIngresses(&stats, sKey, rsMap, svcPortMap, svc, appInf)
+	err = appMgr.syncIngresses(
+		&stats, sKey, rsMap, svcPortMap, svc, appInf, nsPart)
 	if nil != err {
 		return err
 	}
-	if nil != appInf.routeInformer && routePort != 0 {
-		err = appMgr.syncRoutes(&stats, sKey, rsMap, svcPortMap, svc, appInf, routePort)
+	if nil != appInf.routeInformer && routePort != 0 && nil == nsPart.err {
+		err = appMgr.syncRoutes(&stats, sKey, rsMap, svcPortMap, svc, appInf,
+			routePort, nsPart.name)
 		if nil != err {
 			return err
 		}
@@ -813,6 +1049,7 @@ func (appMgr *Manager) syncVirtualServer(sKey serviceQueueKey) error {
 	}
 	log.Debugf("Updated %v of %v virtual server configs, deleted %v",
 		stats.vsUpdated, stats.vsFound, stats.vsDeleted)
+	stats.record()
 
 	// delete any custom profiles that are no longer referenced
 	appMgr.deleteUnusedProfiles(sKey.Namespace)
@@ -838,6 +1075,7 @@ func (appMgr *Manager) syncConfigMaps(
 	svcPortMap map[int32]bool,
 	svc *v1.Service,
 	appInf *appInformer,
+	nsPart nsPartition,
 ) error {
 	cfgMapsByIndex, err := appInf.cfgMapInformer.GetIndexer().ByIndex(
 		"namespace", sKey.Namespace)
@@ -857,8 +1095,24 @@ func (appMgr *Manager) syncConfigMaps(
 		if nil != err {
 			// Ignore this config map for the time being. When the user updates it
 			// so that it is valid it will be requeued.
-			fmt.Errorf("Error parsing ConfigMap %v_%v",
-				cm.ObjectMeta.Namespace, cm.ObjectMeta.Name)
+			appMgr.recordConfigMapError(cm, err)
+			continue
+		}
+		_, err = appMgr.resourcePartition(nsPart, rsCfg.Virtual.Partition)
+		if nil != err {
+			log.Warningf("Could not get config for ConfigMap: %v - %v",
+				cm.ObjectMeta.Name, err)
+			appMgr.recordConfigMapError(cm, err)
+			continue
+		}
+		// Named ports are resolved on every sync, so a change of the
+		// Service's ports updates the pools
+		err = rsCfg.resolveServicePorts(
+			sKey.Namespace, appInf.svcInformer.GetIndexer())
+		if nil != err {
+			log.Warningf("Could not get config for ConfigMap: %v - %v",
+				cm.ObjectMeta.Name, err)
+			appMgr.recordConfigMapError(cm, err)
 			continue
 		}
 
@@ -899,12 +1153,9 @@ func (appMgr *Manager) syncConfigMaps(
 			stats.vsUpdated += updated
 		}
 
-		// Set a status annotation to contain the virtualAddress bindAddr
-		if rsCfg.Virtual.IApp == "" &&
-			rsCfg.Virtual.VirtualAddress != nil &&
-			rsCfg.Virtual.VirtualAddress.BindAddr != "" {
-			appMgr.setBindAddrAnnotation(cm, sKey, rsCfg)
-		}
+		// Set the status annotations to contain the virtualAddress bindAddr,
+		// and no error
+		appMgr.setConfigMapStatus(cm, sKey, rsCfg)
 	}
 	return nil
 }
@@ -916,6 +1167,7 @@ func (appMgr *Manager) syncIngresses(
 	svcPortMap map[int32]bool,
 	svc *v1.Service,
 	appInf *appInformer,
+	nsPart nsPartition,
 ) error {
 	ingByIndex, err := appInf.ingInformer.GetIndexer().ByIndex(
 		"namespace", sKey.Namespace)
@@ -924,22 +1176,95 @@ func (appMgr *Manager) syncIngresses(
 			sKey.Namespace, err)
 		return err
 	}
+	_, canaries := appMgr.namespaceIngresses(sKey.Namespace, appInf)
 	for _, obj := range ingByIndex {
 		// We need to look at all ingresses in the store, parse the data blob,
 		// and see if it belongs to the service that has changed.
-		ing := obj.(*v1beta1.Ingress)
-		if ing.ObjectMeta.Namespace != sKey.Namespace {
+		ing, ours := appMgr.prepareIngress(obj)
+		if !ours || ing.ObjectMeta.Namespace != sKey.Namespace {
+			continue
+		}
+		// Named ports are resolved on every sync, so a change of the
+		// Service's ports updates the pools
+		for _, err := range resolveIngressServicePorts(
+			ing, appInf.svcInformer.GetIndexer()) {
+			msg := fmt.Sprintf("Unable to configure the Ingress backend: %v",
+				err)
+			log.Warningf("%s", msg)
+			appMgr.recordIngressEvent(ing, "InvalidData", msg, "")
+		}
+		// Canaries are added to the virtual servers of the other Ingresses
+		if _, canary, err := ingressCanaryWeight(ing); canary {
+			if nil != err {
+				log.Warningf("%v", err)
+				appMgr.recordIngressEvent(ing, "InvalidData", err.Error(), "")
+			}
 			continue
 		}
 
+		partition, err := appMgr.resourcePartition(
+			nsPart, ing.ObjectMeta.Annotations[partitionAnnotation])
+		if nil != err {
+			msg := fmt.Sprintf("Unable to configure the Ingress: %v", err)
+			log.Warningf("%s", msg)
+			appMgr.recordIngressEvent(ing, "InvalidData", msg, "")
+			continue
+		}
+		// The annotations are checked once for all the virtual servers of
+		// the Ingress, and an Event is only recorded when an error changes
+		annotations := ing.ObjectMeta.Annotations
+		// The SNAT of the virtual, the default if not valid
+		var sat *SourceAddrTranslation
+		err = nil
+		if snat, found := annotations[ingSnatAnnotation]; found {
+			sat, err = ParseSourceAddrTranslation(snat)
+		}
+		appMgr.recordIngressAnnotationError(ing, ingSnatAnnotation, err)
+		// The rules of an invalid path match use the prefix match
+		err = nil
+		if match, found := annotations[ingPathMatchAnnotation]; found {
+			_, err = parsePathMatch(match)
+		}
+		appMgr.recordIngressAnnotationError(ing, ingPathMatchAnnotation, err)
+		// The rules for paths with invalid conditions are skipped
+		err = nil
+		if conds, found := annotations[ingConditionsAnnotation]; found {
+			_, err = parseIngressConditions(conds)
+		}
+		appMgr.recordIngressAnnotationError(ing, ingConditionsAnnotation, err)
+		// Invalid rewrites and redirects are left out of the rules
+		_, err = parseIngressRuleActions(annotations)
+		appMgr.recordIngressAnnotationError(ing, "rule actions", err)
+		// The persistence of the virtual, none if not valid
+		var persistence, fallbackPersistence string
+		err = nil
+		if persist, found := annotations[ingPersistenceAnnotation]; found {
+			persistence, fallbackPersistence, err =
+				parsePersistenceAnnotation(persist)
+			if nil == err {
+				// The virtual servers of Ingresses use the http mode
+				err = validatePersistence(
+					"http", persistence, fallbackPersistence)
+			}
+			if nil != err {
+				persistence, fallbackPersistence = "", ""
+			}
+		}
+		appMgr.recordIngressAnnotationError(ing, ingPersistenceAnnotation, err)
+
 		for _, portStruct := range appMgr.virtualPorts(ing) {
-			rsCfg := createRSConfigFromIngress(ing, sKey.Namespace,
+			rsCfg := createRSConfigFromIngress(ing, partition, sKey.Namespace,
 				appInf.svcInformer.GetIndexer(), portStruct)
 			if rsCfg == nil {
-				// Currently, an error is returned only if the Ingress is one we
-				// do not care about
+				// The Ingress has no Service backend
 				continue
 			}
+			for _, err := range addIngressCanaries(rsCfg, ing, canaries,
+				appInf.svcInformer.GetIndexer()) {
+				log.Warningf("%v", err)
+				appMgr.recordIngressEvent(ing, "InvalidData", err.Error(),
+					rsCfg.Virtual.VirtualServerName)
+			}
 
 			// Handle TLS configuration
 			updated := appMgr.handleIngressTls(rsCfg, ing)
@@ -947,6 +1272,12 @@ func (appMgr *Manager) syncIngresses(
 				stats.cpUpdated += 1
 			}
 
+			if nil != sat {
+				rsCfg.Virtual.SourceAddrTranslation = sat
+			}
+			rsCfg.Virtual.PersistenceProfile = persistence
+			rsCfg.Virtual.FallbackPersistenceProfile = fallbackPersistence
+
 			// Handle Ingress health monitors
 			rsName := rsCfg.Virtual.VirtualServerName
 			hmStr, found := ing.ObjectMeta.Annotations[ingHealthMonitorAnnotation]
@@ -959,7 +1290,7 @@ func (appMgr *Manager) syncIngresses(
 					log.Errorf("%s", msg)
 					appMgr.recordIngressEvent(ing, "InvalidData", msg, rsName)
 				} else {
-					if nil != ing.Spec.Backend {
+					if 0 == len(ing.Spec.Rules) {
 						appMgr.handleSingleServiceHealthMonitors(
 							rsName, rsCfg, ing, monitors)
 					} else {
@@ -1017,6 +1348,7 @@ func (appMgr *Manager) syncRoutes(
 	svc *v1.Service,
 	appInf *appInformer,
 	backendPort int32,
+	partition string,
 ) error {
 	routeByIndex, err := appInf.routeInformer.GetIndexer().ByIndex(
 		"namespace", sKey.Namespace)
@@ -1037,7 +1369,7 @@ func (appMgr *Manager) syncRoutes(
 			// The information stored in the internal data groups can span multiple
 			// namespaces, so we need to keep dgMap updated with all current routes
 			// regardless of anything that happens below.
-			updateDataGroupForPassthroughRoute(route, DEFAULT_PARTITION, dgMap)
+			updateDataGroupForPassthroughRoute(route, partition, dgMap)
 		}
 		if route.ObjectMeta.Namespace != sKey.Namespace {
 			continue
@@ -1045,7 +1377,7 @@ func (appMgr *Manager) syncRoutes(
 		pStructs := []portStruct{{protocol: "http", port: DEFAULT_HTTP_PORT},
 			{protocol: "https", port: DEFAULT_HTTPS_PORT}}
 		for _, ps := range pStructs {
-			rsCfg, err := createRSConfigFromRoute(route,
+			rsCfg, err := createRSConfigFromRoute(route, partition,
 				*appMgr.resources, appMgr.routeConfig, ps, backendPort)
 			if err != nil {
 				// We return err if there was an error creating a rule
@@ -1138,7 +1470,7 @@ type secretKey struct {
 // Return value is whether or not a custom profile was updated
 func (appMgr *Manager) handleIngressTls(
 	rsCfg *ResourceConfig,
-	ing *v1beta1.Ingress,
+	ing *netv1.Ingress,
 ) bool {
 	if 0 == len(ing.Spec.TLS) {
 		// Nothing to do if no TLS section
@@ -1275,7 +1607,7 @@ type portStruct struct {
 }
 
 // Return the required ports for Ingress VS (depending on sslRedirect/allowHttp vals)
-func (appMgr *Manager) virtualPorts(ing *v1beta1.Ingress) []portStruct {
+func (appMgr *Manager) virtualPorts(ing *netv1.Ingress) []portStruct {
 	var httpPort int32
 	var httpsPort int32
 	if port, ok := ing.ObjectMeta.Annotations["virtual-server.f5.com/http-port"]; ok == true {
@@ -1417,6 +1749,15 @@ func (appMgr *Manager) handleConfigForType(
 		return false, vsFound, vsUpdated
 	}
 
+	// Services with ClientIP session affinity persist on the source address,
+	// unless the ConfigMap or Ingress sets the persistence. Route virtuals
+	// are shared by many services, so they are left alone.
+	if "" == currRouteSvc && "" == rsCfg.Virtual.IApp &&
+		v1.ServiceAffinityClientIP == svc.Spec.SessionAffinity &&
+		"" == rsCfg.Virtual.PersistenceProfile {
+		rsCfg.Virtual.PersistenceProfile = persistenceProfiles["source-address"]
+	}
+
 	// Update pool members.
 	vsFound += 1
 	correctBackend := true
@@ -1677,46 +2018,164 @@ func (appMgr *Manager) deleteUnusedProfiles(namespace string) {
 	}
 }
 
-func (appMgr *Manager) setBindAddrAnnotation(
+func (appMgr *Manager) setConfigMapStatus(
 	cm *v1.ConfigMap,
 	sKey serviceQueueKey,
 	rsCfg *ResourceConfig,
 ) {
 	var doUpdate bool
-	if cm.ObjectMeta.Annotations == nil {
-		cm.ObjectMeta.Annotations = make(map[string]string)
-		doUpdate = true
-	} else if cm.ObjectMeta.Annotations[vsBindAddrAnnotation] !=
-		rsCfg.Virtual.VirtualAddress.BindAddr {
+	if _, ok := cm.ObjectMeta.Annotations[vsErrorAnnotation]; ok {
+		delete(cm.ObjectMeta.Annotations, vsErrorAnnotation)
 		doUpdate = true
 	}
-	if doUpdate {
+	if rsCfg.Virtual.IApp == "" &&
+		rsCfg.Virtual.VirtualAddress != nil &&
+		rsCfg.Virtual.VirtualAddress.BindAddr != "" {
+		if cm.ObjectMeta.Annotations == nil {
+			cm.ObjectMeta.Annotations = make(map[string]string)
+			doUpdate = true
+		} else if cm.ObjectMeta.Annotations[vsBindAddrAnnotation] !=
+			rsCfg.Virtual.VirtualAddress.BindAddr {
+			doUpdate = true
+		}
 		cm.ObjectMeta.Annotations[vsBindAddrAnnotation] =
 			rsCfg.Virtual.VirtualAddress.BindAddr
+	}
+	if doUpdate && appMgr.readOnly {
+		log.Infof("Dry run: not updating ConfigMap %s/%s annotation - %v: %v",
+			cm.ObjectMeta.Namespace, cm.ObjectMeta.Name, vsBindAddrAnnotation,
+			cm.ObjectMeta.Annotations[vsBindAddrAnnotation])
+	} else if doUpdate {
 		_, err := appMgr.kubeClient.CoreV1().ConfigMaps(sKey.Namespace).Update(cm)
 		if nil != err {
 			log.Warningf("Error when creating status IP annotation: %s", err)
 		} else {
 			log.Debugf("Updating ConfigMap %+v annotation - %v: %v",
 				sKey, vsBindAddrAnnotation,
-				rsCfg.Virtual.VirtualAddress.BindAddr)
+				cm.ObjectMeta.Annotations[vsBindAddrAnnotation])
 		}
 	}
 }
 
+// Record a Warning Event for an invalid ConfigMap, and keep the error in its
+// status annotation. The Event is only recorded for a new error, since the
+// ConfigMap is checked again after every update, including the update of its
+// annotations.
+func (appMgr *Manager) recordConfigMapError(cm *v1.ConfigMap, err error) {
+	msg := err.Error()
+	if cm.ObjectMeta.Annotations[vsErrorAnnotation] == msg {
+		return
+	}
+	appMgr.recordEvent(cm, v1.EventTypeWarning, "InvalidData", msg)
+
+	if cm.ObjectMeta.Annotations == nil {
+		cm.ObjectMeta.Annotations = make(map[string]string)
+	}
+	cm.ObjectMeta.Annotations[vsErrorAnnotation] = msg
+	// Invalid ConfigMaps have no virtual server
+	delete(cm.ObjectMeta.Annotations, vsBindAddrAnnotation)
+	if appMgr.readOnly {
+		log.Infof("Dry run: not updating ConfigMap %s/%s annotation - %v: %v",
+			cm.ObjectMeta.Namespace, cm.ObjectMeta.Name, vsErrorAnnotation, msg)
+		return
+	}
+	_, err = appMgr.kubeClient.CoreV1().ConfigMaps(cm.ObjectMeta.Namespace).
+		Update(cm)
+	if nil != err {
+		log.Warningf("Error when creating status error annotation: %s", err)
+	}
+}
+
+// Record an Event for an invalid annotation of an Ingress, unless the same
+// error was already recorded; a nil err clears the error
+func (appMgr *Manager) recordIngressAnnotationError(
+	ing *netv1.Ingress,
+	annotation string,
+	err error,
+) {
+	key := ingressErrorKey{
+		Namespace:  ing.ObjectMeta.Namespace,
+		Name:       ing.ObjectMeta.Name,
+		Annotation: annotation,
+	}
+	appMgr.ingressErrorsMutex.Lock()
+	defer appMgr.ingressErrorsMutex.Unlock()
+	if nil == err {
+		delete(appMgr.ingressErrors, key)
+		return
+	}
+	log.Warningf("%v", err)
+	ingErr := ingressError{uid: ing.ObjectMeta.UID, message: err.Error()}
+	if prev, ok := appMgr.ingressErrors[key]; ok && prev == ingErr {
+		return
+	}
+	appMgr.recordIngressEvent(ing, "InvalidData", ingErr.message, "")
+	appMgr.ingressErrors[key] = ingErr
+}
+
 func (appMgr *Manager) setIngressStatus(
-	ing *v1beta1.Ingress,
+	ing *netv1.Ingress,
 	rsCfg *ResourceConfig,
 ) {
+	// The status is updated on the cached object, in the API it came from
+	appInf, ok := appMgr.getNamespaceInformer(ing.ObjectMeta.Namespace)
+	if !ok {
+		return
+	}
+	obj, found, _ := appInf.ingInformer.GetStore().GetByKey(
+		ing.ObjectMeta.Namespace + "/" + ing.ObjectMeta.Name)
+	if !found {
+		return
+	}
 	// Set the ingress status to include the virtual IP
-	lbIngress := v1.LoadBalancerIngress{IP: rsCfg.Virtual.VirtualAddress.BindAddr}
-	if len(ing.Status.LoadBalancer.Ingress) == 0 {
-		ing.Status.LoadBalancer.Ingress = append(ing.Status.LoadBalancer.Ingress, lbIngress)
-	} else if ing.Status.LoadBalancer.Ingress[0].IP != rsCfg.Virtual.VirtualAddress.BindAddr {
-		ing.Status.LoadBalancer.Ingress[0] = lbIngress
-	}
-	_, updateErr := appMgr.kubeClient.ExtensionsV1beta1().
-		Ingresses(ing.ObjectMeta.Namespace).UpdateStatus(ing)
+	bindAddr := rsCfg.Virtual.VirtualAddress.BindAddr
+	if appMgr.readOnly {
+		log.Infof("Dry run: not setting Ingress %s/%s status IP: %v",
+			ing.ObjectMeta.Namespace, ing.ObjectMeta.Name, bindAddr)
+		return
+	}
+	var updateErr error
+	switch cached := obj.(type) {
+	case *netv1.Ingress:
+		ingCopy := *cached
+		lbIngress := netv1.IngressLoadBalancerIngress{IP: bindAddr}
+		lbStatus := append([]netv1.IngressLoadBalancerIngress{},
+			cached.Status.LoadBalancer.Ingress...)
+		if len(lbStatus) == 0 {
+			lbStatus = append(lbStatus, lbIngress)
+		} else if lbStatus[0].IP != bindAddr {
+			lbStatus[0] = lbIngress
+		}
+		ingCopy.Status.LoadBalancer.Ingress = lbStatus
+		// The client has no typed Ingress, so the body is encoded here
+		body, err := json.Marshal(&ingCopy)
+		if nil != err {
+			log.Warningf("Error encoding Ingress %s status: %v",
+				ing.ObjectMeta.Name, err)
+			return
+		}
+		updateErr = appMgr.netClientV1.Put().
+			Namespace(ing.ObjectMeta.Namespace).
+			Resource("ingresses").
+			Name(ing.ObjectMeta.Name).
+			SubResource("status").
+			Body(body).
+			Do().
+			Error()
+	case *v1beta1.Ingress:
+		ingCopy := *cached
+		lbIngress := v1.LoadBalancerIngress{IP: bindAddr}
+		lbStatus := append([]v1.LoadBalancerIngress{},
+			cached.Status.LoadBalancer.Ingress...)
+		if len(lbStatus) == 0 {
+			lbStatus = append(lbStatus, lbIngress)
+		} else if lbStatus[0].IP != bindAddr {
+			lbStatus[0] = lbIngress
+		}
+		ingCopy.Status.LoadBalancer.Ingress = lbStatus
+		_, updateErr = appMgr.kubeClient.ExtensionsV1beta1().
+			Ingresses(ing.ObjectMeta.Namespace).UpdateStatus(&ingCopy)
+	}
 	if nil != updateErr {
 		// Multi-service causes the controller to try to update the status multiple times
 		// at once. Ignore this error.
@@ -1732,7 +2191,7 @@ func (appMgr *Manager) setIngressStatus(
 }
 
 // This function expects either an Ingress resource or the name of a VS for an Ingress
-func (appMgr *Manager) recordIngressEvent(ing *v1beta1.Ingress,
+func (appMgr *Manager) recordIngressEvent(ing *netv1.Ingress,
 	reason,
 	message,
 	rsName string) {
@@ -1744,22 +2203,47 @@ func (appMgr *Manager) recordIngressEvent(ing *v1beta1.Ingress,
 		namespace = strings.Split(rsName, "_")[0]
 		name = rsName[len(namespace)+1 : len(rsName)-len("-ingress")]
 	}
-	appMgr.broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{
-		Interface: appMgr.kubeClient.Core().Events(namespace)})
 
 	// If we aren't given an Ingress resource, we use the name to find it
-	var err error
 	if ing == nil {
-		ing, err = appMgr.kubeClient.Extensions().Ingresses(namespace).
-			Get(name, metav1.GetOptions{})
-		if nil != err {
+		appInf, ok := appMgr.getNamespaceInformer(namespace)
+		if !ok {
+			log.Warningf("Could not find Ingress resource '%v'.", name)
+			return
+		}
+		obj, found, _ := appInf.ingInformer.GetStore().GetByKey(
+			namespace + "/" + name)
+		if !found {
 			log.Warningf("Could not find Ingress resource '%v'.", name)
 			return
 		}
+		ing = toNetworkingIngress(obj)
 	}
 
 	// Create the event
-	appMgr.eventRecorder.Event(ing, v1.EventTypeNormal, reason, message)
+	appMgr.recordEvent(ing, v1.EventTypeNormal, reason, message)
+}
+
+// Record an event for a Kubernetes object
+func (appMgr *Manager) recordEvent(
+	obj runtime.Object,
+	eventType,
+	reason,
+	message string,
+) {
+	if appMgr.readOnly {
+		log.Infof("Dry run: not recording %s Event %s: %s",
+			eventType, reason, message)
+		return
+	}
+	// Events for all namespaces go through a single sink
+	appMgr.eventSink.Do(func() {
+		if nil != appMgr.kubeClient {
+			appMgr.broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{
+				Interface: appMgr.kubeClient.Core().Events("")})
+		}
+	})
+	appMgr.eventRecorder.Event(obj, eventType, reason, message)
 }
 
 func getEndpointsForService(
@@ -1816,25 +2300,17 @@ func handleConfigMapParseFailure(
 ) bool {
 	log.Warningf("Could not get config for ConfigMap: %v - %v",
 		cm.ObjectMeta.Name, err)
-	// If virtual server exists for invalid configmap, delete it
-	var serviceName string
-	var servicePort int32
+	appMgr.recordConfigMapError(cm, err)
+	// If virtual server exists for invalid configmap, delete it, along with
+	// the copies stored for each of its backends
 	if nil != cfg {
-		if len(cfg.Pools) == 0 {
-			serviceName = ""
-			servicePort = 0
-		} else {
-			serviceName = cfg.Pools[0].ServiceName
-			servicePort = cfg.Pools[0].ServicePort
-		}
-		sKey := serviceKey{serviceName, servicePort, cm.ObjectMeta.Namespace}
 		rsName := formatConfigMapVSName(cm)
-		if _, ok := appMgr.resources.Get(sKey, rsName); ok {
-			appMgr.resources.Lock()
-			defer appMgr.resources.Unlock()
-			appMgr.resources.Delete(sKey, rsName)
-			delete(cm.ObjectMeta.Annotations, vsBindAddrAnnotation)
-			appMgr.kubeClient.CoreV1().ConfigMaps(cm.ObjectMeta.Namespace).Update(cm)
+		appMgr.resources.Lock()
+		defer appMgr.resources.Unlock()
+		if _, keys := appMgr.resources.GetAllWithName(rsName); 0 != len(keys) {
+			for _, key := range keys {
+				appMgr.resources.Delete(key, rsName)
+			}
 			log.Warningf("Deleted virtual server associated with ConfigMap: %v",
 				cm.ObjectMeta.Name)
 			return true
@@ -1879,7 +2355,12 @@ func (appMgr *Manager) ProcessNodeUpdate(
 					b.WriteString(port)
 					newAddrPorts = append(newAddrPorts, b.String())
 				}
-				cfg.Pools[0].PoolMemberAddrs = newAddrPorts
+				for i, pool := range cfg.Pools {
+					if pool.ServiceName == key.ServiceName &&
+						pool.ServicePort == key.ServicePort {
+						cfg.Pools[i].PoolMemberAddrs = newAddrPorts
+					}
+				}
 			})
 			// Output the Big-IP config
 			appMgr.outputConfigLocked()
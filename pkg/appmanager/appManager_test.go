@@ -19,13 +19,14 @@ package appmanager
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
 
 	routeapi "github.com/openshift/origin/pkg/route/api"
@@ -41,8 +42,7 @@ import (
 )
 
 func init() {
-	workingDir, _ := os.Getwd()
-	schemaUrl = "file://" + workingDir + "/../../schemas/bigip-virtual-server_v0.1.4.json"
+	schemaUrl = "f5schemadb://bigip-virtual-server_v0.1.12.json"
 	DEFAULT_PARTITION = "velcro"
 }
 
@@ -138,7 +138,7 @@ var configmapFooInvalid string = string(`{
     },
     "frontend": {
       "balance": "super-duper-mojo",
-      "mode": "udp",
+      "mode": "sctp",
       "partition": "",
       "virtualAddress": {
         "bindAddr": "10.128.10.260",
@@ -266,6 +266,43 @@ var configmapIApp2 string = string(`{
   }
 }`)
 
+var configmapBackends string = string(`{
+  "virtualServer": {
+    "backends": [ {
+      "name": "foo",
+      "serviceName": "foo",
+      "servicePort": 80,
+      "healthMonitors": [ {
+        "interval": 30,
+        "timeout": 20,
+        "send": "GET /",
+        "protocol": "http"
+        }
+      ]
+    }, {
+      "name": "bar",
+      "serviceName": "bar",
+      "servicePort": 80,
+      "balance": "least-connections-member"
+    } ],
+    "defaultBackend": "foo",
+    "rules": [
+      { "host": "bar.example.com", "backend": "bar" },
+      { "host": "foo.example.com", "path": "/bar", "backend": "bar" },
+      { "path": "/foo", "backend": "foo" }
+    ],
+    "frontend": {
+      "balance": "round-robin",
+      "mode": "http",
+      "partition": "velcro",
+      "virtualAddress": {
+        "bindAddr": "10.128.10.240",
+        "port": 80
+      }
+    }
+  }
+}`)
+
 var emptyConfig string = string(`{"resources":{}}`)
 
 var twoSvcsFourPortsThreeNodesConfig string = string(`{"resources":{"virtualServers":[{"name":"default_barmap","pool":"/velcro/default_barmap","partition":"velcro","mode":"http","virtualAddress":{"bindAddr":"10.128.10.240","port":6051}},{"name":"default_foomap","pool":"/velcro/default_foomap","partition":"velcro","mode":"http","virtualAddress":{"bindAddr":"10.128.10.240","port":5051},"sslProfile":{"f5ProfileName":"velcro/testcert"}},{"name":"default_foomap8080","pool":"/velcro/default_foomap8080","partition":"velcro","mode":"http","virtualAddress":{"bindAddr":"10.128.10.240","port":5051}},{"name":"default_foomap9090","pool":"/velcro/default_foomap9090","partition":"velcro","mode":"tcp","virtualAddress":{"bindAddr":"10.128.10.200","port":4041}}],"pools":[{"name":"default_barmap","partition":"velcro","loadBalancingMode":"round-robin","serviceName":"bar","servicePort":80,"poolMemberAddrs":["127.0.0.1:37001","127.0.0.2:37001","127.0.0.3:37001"],"monitor":null},{"name":"default_foomap","partition":"velcro","loadBalancingMode":"round-robin","serviceName":"foo","servicePort":80,"poolMemberAddrs":["127.0.0.1:30001","127.0.0.2:30001","127.0.0.3:30001"],"monitor":["/velcro/default_foomap"]},{"name":"default_foomap8080","partition":"velcro","loadBalancingMode":"round-robin","serviceName":"foo","servicePort":8080,"poolMemberAddrs":["127.0.0.1:38001","127.0.0.2:38001","127.0.0.3:38001"],"monitor":null},{"name":"default_foomap9090","partition":"velcro","loadBalancingMode":"round-robin","serviceName":"foo","servicePort":9090,"poolMemberAddrs":["127.0.0.1:39001","127.0.0.2:39001","127.0.0.3:39001"],"monitor":null}],"monitors":[{"name":"default_foomap","partition":"velcro","interval":30,"protocol":"tcp","send":"GET /","timeout":20}]}}`)
@@ -495,10 +532,10 @@ func (m *mockAppManager) deleteEndpoints(ep *v1.Endpoints) bool {
 	return ok
 }
 
-func (m *mockAppManager) addIngress(ing *v1beta1.Ingress) bool {
+func (m *mockAppManager) addIngress(ing metav1.Object) bool {
 	ok, keys := m.appMgr.checkValidIngress(ing)
 	if ok {
-		appInf, _ := m.appMgr.getNamespaceInformer(ing.ObjectMeta.Namespace)
+		appInf, _ := m.appMgr.getNamespaceInformer(ing.GetNamespace())
 		appInf.ingInformer.GetStore().Add(ing)
 		for _, vsKey := range keys {
 			mtx := m.getVsMutex(*vsKey)
@@ -510,10 +547,10 @@ func (m *mockAppManager) addIngress(ing *v1beta1.Ingress) bool {
 	return ok
 }
 
-func (m *mockAppManager) updateIngress(ing *v1beta1.Ingress) bool {
+func (m *mockAppManager) updateIngress(ing metav1.Object) bool {
 	ok, keys := m.appMgr.checkValidIngress(ing)
 	if ok {
-		appInf, _ := m.appMgr.getNamespaceInformer(ing.ObjectMeta.Namespace)
+		appInf, _ := m.appMgr.getNamespaceInformer(ing.GetNamespace())
 		appInf.ingInformer.GetStore().Update(ing)
 		for _, vsKey := range keys {
 			mtx := m.getVsMutex(*vsKey)
@@ -525,10 +562,10 @@ func (m *mockAppManager) updateIngress(ing *v1beta1.Ingress) bool {
 	return ok
 }
 
-func (m *mockAppManager) deleteIngress(ing *v1beta1.Ingress) bool {
+func (m *mockAppManager) deleteIngress(ing metav1.Object) bool {
 	ok, keys := m.appMgr.checkValidIngress(ing)
 	if ok {
-		appInf, _ := m.appMgr.getNamespaceInformer(ing.ObjectMeta.Namespace)
+		appInf, _ := m.appMgr.getNamespaceInformer(ing.GetNamespace())
 		appInf.ingInformer.GetStore().Delete(ing)
 		for _, vsKey := range keys {
 			mtx := m.getVsMutex(*vsKey)
@@ -616,6 +653,10 @@ func newServicePort(name string, svcPort int32) v1.ServicePort {
 	}
 }
 
+func ingressVSName(ing *v1beta1.Ingress, protocol string) string {
+	return formatIngressVSName(netv1.ConvertV1beta1Ingress(ing), protocol)
+}
+
 func TestVirtualServerSendFail(t *testing.T) {
 	mw := &test.MockWriter{
 		FailStyle: test.ImmediateFail,
@@ -628,6 +669,7 @@ func TestVirtualServerSendFail(t *testing.T) {
 		appMgr.outputConfig()
 	})
 	assert.Equal(t, 1, mw.WrittenTimes)
+	assert.Error(t, appMgr.LastWriteError())
 }
 
 func TestVirtualServerSendFailAsync(t *testing.T) {
@@ -642,6 +684,7 @@ func TestVirtualServerSendFailAsync(t *testing.T) {
 		appMgr.outputConfig()
 	})
 	assert.Equal(t, 1, mw.WrittenTimes)
+	assert.Error(t, appMgr.LastWriteError())
 }
 
 func TestVirtualServerSendFailTimeout(t *testing.T) {
@@ -656,6 +699,118 @@ func TestVirtualServerSendFailTimeout(t *testing.T) {
 		appMgr.outputConfig()
 	})
 	assert.Equal(t, 1, mw.WrittenTimes)
+	assert.Error(t, appMgr.LastWriteError())
+}
+
+func TestResendConfig(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+
+	appMgr := NewManager(&Params{ConfigWriter: mw})
+
+	// Nothing is resent before the initial state is written
+	appMgr.ResendConfig()
+	assert.Equal(t, 0, mw.WrittenTimes)
+
+	appMgr.outputConfig()
+	assert.Equal(t, 1, mw.WrittenTimes)
+	appMgr.ResendConfig()
+	assert.Equal(t, 2, mw.WrittenTimes)
+	assert.Contains(t, mw.Sections, "resources")
+	assert.NoError(t, appMgr.LastWriteError())
+}
+
+func TestReadinessState(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+
+	appMgr := NewManager(&Params{ConfigWriter: mw})
+	assert.False(t, appMgr.CachesSynced())
+	assert.False(t, appMgr.InitialStateReached())
+
+	appMgr.setCachesSynced(true)
+	assert.True(t, appMgr.CachesSynced())
+
+	appMgr.outputConfig()
+	assert.True(t, appMgr.InitialStateReached())
+	assert.NoError(t, appMgr.LastWriteError())
+
+	// A failed write is reported until the next successful one
+	mw.FailStyle = test.ImmediateFail
+	appMgr.outputConfig()
+	assert.Error(t, appMgr.LastWriteError())
+	mw.FailStyle = test.Success
+	appMgr.outputConfig()
+	assert.NoError(t, appMgr.LastWriteError())
+}
+
+func TestStartInformers(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	appMgr := NewManager(&Params{ConfigWriter: mw})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	appMgr.StartInformers(stopCh)
+	assert.True(t, appMgr.CachesSynced())
+
+	// Informers are only started once, and starting them writes no config
+	require.NotPanics(t, func() {
+		appMgr.StartInformers(stopCh)
+	})
+	assert.Equal(t, 0, mw.WrittenTimes)
+	assert.False(t, appMgr.InitialStateReached())
+}
+
+func TestDrain(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	appMgr := NewManager(&Params{ConfigWriter: mw})
+
+	// A manager that was never run has nothing to drain
+	require.NoError(t, appMgr.Drain(time.Second))
+	assert.Equal(t, 0, mw.WrittenTimes)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	appMgr.Run(stopCh)
+	for i := 0; i < 10; i++ {
+		appMgr.vsQueue.Add(serviceQueueKey{
+			Namespace:   "default",
+			ServiceName: fmt.Sprintf("svc%d", i),
+		})
+	}
+
+	require.NoError(t, appMgr.Drain(5*time.Second))
+	assert.Equal(t, 0, appMgr.vsQueue.Len())
+	assert.True(t, appMgr.InitialStateReached())
+	mw.Lock()
+	written := mw.WrittenTimes
+	_, found := mw.Sections["resources"]
+	mw.Unlock()
+	assert.True(t, written > 0, "Draining should write the final config")
+	assert.True(t, found)
+
+	// Nothing is queued once draining has started
+	appMgr.vsQueue.Add(serviceQueueKey{
+		Namespace:   "default",
+		ServiceName: "late",
+	})
+	assert.Equal(t, 0, appMgr.vsQueue.Len())
+
+	// A failed final write is reported
+	mw.Lock()
+	mw.FailStyle = test.ImmediateFail
+	mw.Unlock()
+	assert.Error(t, appMgr.Drain(time.Second))
 }
 
 func TestGetAddresses(t *testing.T) {
@@ -2142,7 +2297,7 @@ func TestSchemaValidation(t *testing.T) {
 	assert.Contains(err.Error(),
 		"virtualServer.frontend.partition: String length must be greater than or equal to 1")
 	assert.Contains(err.Error(),
-		"virtualServer.frontend.mode: virtualServer.frontend.mode must be one of the following: \\\"http\\\", \\\"tcp\\\"")
+		"virtualServer.frontend.mode: virtualServer.frontend.mode must be one of the following: \\\"http\\\", \\\"tcp\\\", \\\"udp\\\"")
 	assert.Contains(err.Error(),
 		"virtualServer.frontend.balance: virtualServer.frontend.balance must be one of the following:")
 	assert.Contains(err.Error(),
@@ -2160,15 +2315,163 @@ func TestSchemaValidation(t *testing.T) {
 
 func TestConfigMapWrongPartition(t *testing.T) {
 	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+	// The ConfigMap uses velcro, which is not one of the managed partitions
+	DEFAULT_PARTITION = "k8s"
+	defer func() { DEFAULT_PARTITION = "velcro" }()
+
+	for _, managed := range [][]string{{"k8s"}, {"k8s", "velcro"}} {
+		mw := &test.MockWriter{
+			FailStyle: test.Success,
+			Sections:  make(map[string]interface{}),
+		}
+		fakeClient := fake.NewSimpleClientset()
+		appMgr := newMockAppManager(&Params{
+			KubeClient:        fakeClient,
+			ConfigWriter:      mw,
+			restClient:        test.CreateFakeHTTPClient(),
+			IsNodePort:        true,
+			ManagedPartitions: managed,
+		})
+		require.Nil(appMgr.startNonLabelMode([]string{namespace}))
+
+		cfgFoo := test.NewConfigMap("foomap", "1", namespace, map[string]string{
+			"schema": schemaUrl,
+			"data":   configmapFoo})
+		svcFoo := test.NewService("foo", "1", namespace, "NodePort",
+			[]v1.ServicePort{{Port: 80, NodePort: 30001}})
+		appMgr.addConfigMap(cfgFoo)
+		appMgr.addService(svcFoo)
+		_, ok := appMgr.resources().Get(
+			serviceKey{"foo", 80, namespace}, formatConfigMapVSName(cfgFoo))
+		assert.Equal(len(managed) > 1, ok,
+			"ConfigMap should only be configured in a managed partition")
+		appMgr.shutdown()
+	}
+}
+
+func TestConfigMapErrorEvents(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	fakeClient := fake.NewSimpleClientset()
+	fakeRecorder := record.NewFakeRecorder(100)
+	appMgr := newMockAppManager(&Params{
+		KubeClient:    fakeClient,
+		ConfigWriter:  mw,
+		restClient:    test.CreateFakeHTTPClient(),
+		IsNodePort:    true,
+		EventRecorder: fakeRecorder,
+	})
+	require.Nil(appMgr.startNonLabelMode([]string{namespace}))
+	defer appMgr.shutdown()
+	svcFoo := test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 30001}})
+	appMgr.addService(svcFoo)
+
+	cfgFoo := test.NewConfigMap("foomap", "1", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   configmapFooInvalid})
+	_, err := fakeClient.Core().ConfigMaps(namespace).Create(cfgFoo)
+	require.NoError(err)
+	getAnnotations := func() map[string]string {
+		cm, err := fakeClient.Core().ConfigMaps(namespace).Get(
+			"foomap", metav1.GetOptions{})
+		require.NoError(err)
+		return cm.ObjectMeta.Annotations
+	}
+
+	// The schema violations are recorded in an Event and the status annotation
+	r := appMgr.addConfigMap(cfgFoo)
+	require.False(r, "Invalid config map should not be processed")
+	require.Len(fakeRecorder.Events, 1)
+	event := <-fakeRecorder.Events
+	assert.Contains(event, "Warning InvalidData configMap is not valid")
+	assert.Contains(event,
+		"virtualServer.backend.servicePort: Must be greater than or equal to 1")
+	assert.Contains(getAnnotations()[vsErrorAnnotation],
+		"virtualServer.backend.servicePort: Must be greater than or equal to 1")
+
+	// The same error is only recorded once
+	appMgr.updateConfigMap(cfgFoo)
+	assert.Len(fakeRecorder.Events, 0)
+
+	// Once the ConfigMap is valid, the error annotation is removed
+	cfgFoo = test.NewConfigMap("foomap", "2", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   configmapFoo})
+	cfgFoo.ObjectMeta.Annotations = getAnnotations()
+	r = appMgr.updateConfigMap(cfgFoo)
+	require.True(r, "Config map should be processed")
+	annotations := getAnnotations()
+	assert.NotContains(annotations, vsErrorAnnotation)
+	assert.Equal("10.128.10.240", annotations[vsBindAddrAnnotation])
+	assert.Len(fakeRecorder.Events, 0)
+}
+
+func TestDryRunReadOnly(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
 	namespace := "default"
-	//Config map with wrong partition
-	DEFAULT_PARTITION = "k8s" //partition the controller has been asked to watch
-	wrongPartition := test.NewConfigMap("foomap", "1", namespace, map[string]string{
+
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	cfgFoo := test.NewConfigMap("foomap", "1", namespace, map[string]string{
 		"schema": schemaUrl,
 		"data":   configmapFoo})
-	_, err := parseConfigMap(wrongPartition)
-	require.NotNil(err, "Config map with wrong partition should throw an error")
-	DEFAULT_PARTITION = "velcro"
+	cfgBar := test.NewConfigMap("barmap", "1", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   configmapFooInvalid})
+	ing := test.NewIngress("ingress", "1", namespace, v1beta1.IngressSpec{
+		Backend: &v1beta1.IngressBackend{
+			ServiceName: "foo",
+			ServicePort: intstr.IntOrString{IntVal: 80},
+		},
+	}, map[string]string{
+		"virtual-server.f5.com/ip":        "1.2.3.4",
+		"virtual-server.f5.com/partition": "velcro",
+	})
+	fakeClient := fake.NewSimpleClientset(cfgFoo, cfgBar, ing)
+	fakeRecorder := record.NewFakeRecorder(100)
+	appMgr := newMockAppManager(&Params{
+		KubeClient:    fakeClient,
+		ConfigWriter:  mw,
+		restClient:    test.CreateFakeHTTPClient(),
+		IsNodePort:    true,
+		EventRecorder: fakeRecorder,
+		ReadOnly:      true,
+	})
+	require.Nil(appMgr.startNonLabelMode([]string{namespace}))
+	defer appMgr.shutdown()
+	fakeClient.ClearActions()
+
+	// The resources are configured, without writing their status,
+	// annotations or Events
+	svcFoo := test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 30001}})
+	appMgr.addService(svcFoo)
+	r := appMgr.addConfigMap(cfgFoo)
+	require.True(r, "Config map should be processed")
+	r = appMgr.addConfigMap(cfgBar)
+	require.False(r, "Invalid config map should not be processed")
+	r = appMgr.addIngress(ing)
+	require.True(r, "Ingress resource should be processed")
+	assert.Equal(2, appMgr.resources().Count())
+
+	for _, action := range fakeClient.Actions() {
+		assert.NotContains([]string{"create", "update", "patch", "delete"},
+			action.GetVerb(), "Dry runs should not write %s",
+			action.GetResource().Resource)
+	}
+	assert.Len(fakeRecorder.Events, 0)
 }
 
 func validateServiceIps(t *testing.T, serviceName, namespace string,
@@ -3151,11 +3454,12 @@ func TestVirtualServerForIngress(t *testing.T) {
 	namespace := "default"
 
 	appMgr := newMockAppManager(&Params{
-		KubeClient:    fakeClient,
-		ConfigWriter:  mw,
-		restClient:    test.CreateFakeHTTPClient(),
-		IsNodePort:    true,
-		EventRecorder: fakeRecorder,
+		KubeClient:        fakeClient,
+		ConfigWriter:      mw,
+		restClient:        test.CreateFakeHTTPClient(),
+		IsNodePort:        true,
+		EventRecorder:     fakeRecorder,
+		ManagedPartitions: []string{"velcro", "velcro2"},
 	})
 	err := appMgr.startNonLabelMode([]string{namespace})
 	require.Nil(err)
@@ -3330,6 +3634,456 @@ func TestVirtualServerForIngress(t *testing.T) {
 	require.Equal(2, len(rs.Policies[0].Rules))
 }
 
+func TestVirtualServerForConfigMapBackends(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	fakeClient := fake.NewSimpleClientset()
+	require.NotNil(fakeClient, "Mock client should not be nil")
+	namespace := "default"
+
+	appMgr := newMockAppManager(&Params{
+		KubeClient:   fakeClient,
+		ConfigWriter: mw,
+		restClient:   test.CreateFakeHTTPClient(),
+		IsNodePort:   true,
+	})
+	err := appMgr.startNonLabelMode([]string{namespace})
+	require.Nil(err)
+	defer appMgr.shutdown()
+
+	fooSvc := test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 37001}})
+	r := appMgr.addService(fooSvc)
+	assert.True(r, "Service should be processed")
+	barSvc := test.NewService("bar", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 37002}})
+	r = appMgr.addService(barSvc)
+	assert.True(r, "Service should be processed")
+
+	cfgMap := test.NewConfigMap("backendsmap", "1", namespace,
+		map[string]string{
+			"schema": schemaUrl,
+			"data":   configmapBackends,
+		})
+	r = appMgr.addConfigMap(cfgMap)
+	require.True(r, "Config map should be processed")
+	// One copy of the config is stored for each backend
+	resources := appMgr.resources()
+	require.Equal(2, resources.Count())
+	rs, ok := resources.Get(
+		serviceKey{"bar", 80, namespace}, "default_backendsmap")
+	require.True(ok, "Config map should be accessible")
+	require.Len(rs.Pools, 2)
+	assert.Equal("default_backendsmap_bar", rs.Pools[1].Name)
+	assert.Equal("least-connections-member", rs.Pools[1].Balance)
+	assert.Equal("/velcro/default_backendsmap_foo", rs.Virtual.PoolName)
+	assert.Equal([]string{"/velcro/default_backendsmap_foo"},
+		rs.Pools[0].MonitorNames)
+	assert.Equal([]nameRef{{Name: "default_backendsmap", Partition: "velcro"}},
+		rs.Virtual.Policies)
+	require.Len(rs.Policies, 1)
+	require.Len(rs.Policies[0].Rules, 3)
+	var uris []string
+	for _, rule := range rs.Policies[0].Rules {
+		uris = append(uris, rule.FullURI)
+	}
+	assert.Equal([]string{"foo.example.com/bar", "bar.example.com", "/foo"}, uris)
+	assert.Equal("/velcro/default_backendsmap_bar",
+		rs.Policies[0].Rules[0].Actions[0].Pool)
+
+	// Removing a backend removes the config stored for its service
+	cfgMap = test.NewConfigMap("backendsmap", "2", namespace,
+		map[string]string{
+			"schema": schemaUrl,
+			"data":   configmapFoo,
+		})
+	r = appMgr.updateConfigMap(cfgMap)
+	require.True(r, "Config map should be processed")
+	require.Equal(1, resources.Count())
+	_, ok = resources.Get(
+		serviceKey{"bar", 80, namespace}, "default_backendsmap")
+	assert.False(ok, "Config for the removed backend should be deleted")
+
+	// An invalid ConfigMap removes all its configs
+	invalid := strings.Replace(configmapBackends, `"backend": "bar"`,
+		`"backend": "baz"`, 1)
+	cfgMap = test.NewConfigMap("backendsmap", "3", namespace,
+		map[string]string{
+			"schema": schemaUrl,
+			"data":   invalid,
+		})
+	appMgr.updateConfigMap(cfgMap)
+	require.Equal(0, resources.Count())
+}
+
+func TestSourceAddrTranslation(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	fakeClient := fake.NewSimpleClientset()
+	fakeRecorder := record.NewFakeRecorder(100)
+	namespace := "default"
+
+	appMgr := newMockAppManager(&Params{
+		KubeClient:    fakeClient,
+		ConfigWriter:  mw,
+		restClient:    test.CreateFakeHTTPClient(),
+		IsNodePort:    true,
+		EventRecorder: fakeRecorder,
+		DefaultSnat:   &SourceAddrTranslation{Type: "automap"},
+	})
+	err := appMgr.startNonLabelMode([]string{namespace})
+	require.Nil(err)
+	defer appMgr.shutdown()
+
+	fooSvc := test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 37001}})
+	r := appMgr.addService(fooSvc)
+	assert.True(r, "Service should be processed")
+
+	// The ConfigMap turns SNAT off, the Ingress uses a SNAT pool
+	data := strings.Replace(configmapFoo, `"partition": "velcro",`,
+		`"partition": "velcro", "snat": "none",`, 1)
+	cfgFoo := test.NewConfigMap("foomap", "1", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	r = appMgr.addConfigMap(cfgFoo)
+	assert.True(r, "Config map should be processed")
+	ingress := test.NewIngress("ingress", "1", namespace,
+		v1beta1.IngressSpec{
+			Backend: &v1beta1.IngressBackend{
+				ServiceName: "foo",
+				ServicePort: intstr.IntOrString{IntVal: 80},
+			},
+		},
+		map[string]string{
+			"virtual-server.f5.com/ip":        "1.2.3.4",
+			"virtual-server.f5.com/partition": "velcro",
+			ingSnatAnnotation:                 "/Common/snatpool",
+		})
+	r = appMgr.addIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+
+	snatOf := func() map[string]*SourceAddrTranslation {
+		appMgr.appMgr.outputConfig()
+		mw.Lock()
+		defer mw.Unlock()
+		snat := make(map[string]*SourceAddrTranslation)
+		for _, vs := range mw.Sections["resources"].(BigIPConfig).Virtuals {
+			snat[vs.VirtualServerName] = vs.SourceAddrTranslation
+		}
+		return snat
+	}
+	assert.Equal(map[string]*SourceAddrTranslation{
+		"default_foomap": {Type: "none"},
+		"default_ingress-ingress_http": {
+			Type: "snat",
+			Pool: "/Common/snatpool",
+		},
+	}, snatOf())
+
+	// Without a valid annotation, the Ingress uses the default
+	ingress.ObjectMeta.Annotations[ingSnatAnnotation] = "snatpool"
+	r = appMgr.updateIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+	assert.Equal(&SourceAddrTranslation{Type: "automap"},
+		snatOf()["default_ingress-ingress_http"])
+	snatEvents := func() int {
+		count := 0
+		for 0 != len(fakeRecorder.Events) {
+			if strings.Contains(<-fakeRecorder.Events, "snatpool") {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Equal(1, snatEvents(),
+		"Invalid SNAT should be recorded in an Event")
+
+	// The error is recorded once, whatever the virtual servers and syncs
+	ingress.Spec.TLS = []v1beta1.IngressTLS{{SecretName: "/Common/clientssl"}}
+	ingress.ObjectMeta.Annotations[ingressAllowHttp] = "true"
+	ingress.ObjectMeta.Annotations[ingressSslRedirect] = "false"
+	for i := 0; i < 2; i++ {
+		r = appMgr.updateIngress(ingress)
+		assert.True(r, "Ingress resource should be processed")
+	}
+	assert.Equal(&SourceAddrTranslation{Type: "automap"},
+		snatOf()["default_ingress-ingress_https"])
+	assert.Equal(0, snatEvents(), "The same error should not be recorded again")
+
+	// Once fixed, the error is recorded again when it comes back
+	ingress.ObjectMeta.Annotations[ingSnatAnnotation] = "/Common/snatpool"
+	r = appMgr.updateIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+	ingress.ObjectMeta.Annotations[ingSnatAnnotation] = "snatpool"
+	r = appMgr.updateIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+	assert.Equal(1, snatEvents(),
+		"Invalid SNAT should be recorded in an Event")
+}
+
+func TestPersistence(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	fakeClient := fake.NewSimpleClientset()
+	fakeRecorder := record.NewFakeRecorder(100)
+	namespace := "default"
+
+	appMgr := newMockAppManager(&Params{
+		KubeClient:    fakeClient,
+		ConfigWriter:  mw,
+		restClient:    test.CreateFakeHTTPClient(),
+		IsNodePort:    true,
+		EventRecorder: fakeRecorder,
+	})
+	err := appMgr.startNonLabelMode([]string{namespace})
+	require.Nil(err)
+	defer appMgr.shutdown()
+
+	fooSvc := test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 37001}})
+	r := appMgr.addService(fooSvc)
+	assert.True(r, "Service should be processed")
+
+	// The ConfigMap uses built-in profiles, the Ingress its own profile
+	data := strings.Replace(configmapFoo, `"partition": "velcro",`,
+		`"partition": "velcro", "persistence": {
+		"default": "cookie", "fallback": "source-address" },`, 1)
+	cfgFoo := test.NewConfigMap("foomap", "1", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	r = appMgr.addConfigMap(cfgFoo)
+	assert.True(r, "Config map should be processed")
+	ingress := test.NewIngress("ingress", "1", namespace,
+		v1beta1.IngressSpec{
+			Backend: &v1beta1.IngressBackend{
+				ServiceName: "foo",
+				ServicePort: intstr.IntOrString{IntVal: 80},
+			},
+		},
+		map[string]string{
+			"virtual-server.f5.com/ip":        "1.2.3.4",
+			"virtual-server.f5.com/partition": "velcro",
+			ingPersistenceAnnotation:          "/velcro/persist",
+		})
+	r = appMgr.addIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+
+	persistenceOf := func() map[string][]string {
+		appMgr.appMgr.outputConfig()
+		mw.Lock()
+		defer mw.Unlock()
+		persist := make(map[string][]string)
+		for _, vs := range mw.Sections["resources"].(BigIPConfig).Virtuals {
+			persist[vs.VirtualServerName] = []string{
+				vs.PersistenceProfile, vs.FallbackPersistenceProfile}
+		}
+		return persist
+	}
+	assert.Equal(map[string][]string{
+		"default_foomap":               {"/Common/cookie", "/Common/source_addr"},
+		"default_ingress-ingress_http": {"/velcro/persist", ""},
+	}, persistenceOf())
+
+	// Without the annotation, the Ingress follows the session affinity of
+	// the Service
+	fooSvc.Spec.SessionAffinity = v1.ServiceAffinityClientIP
+	r = appMgr.updateService(fooSvc)
+	assert.True(r, "Service should be processed")
+	delete(ingress.ObjectMeta.Annotations, ingPersistenceAnnotation)
+	r = appMgr.updateIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+	assert.Equal(map[string][]string{
+		"default_foomap":               {"/Common/cookie", "/Common/source_addr"},
+		"default_ingress-ingress_http": {"/Common/source_addr", ""},
+	}, persistenceOf())
+
+	// An invalid annotation is recorded, and the affinity still applies
+	ingress.ObjectMeta.Annotations[ingPersistenceAnnotation] =
+		"destination-address,cookie"
+	r = appMgr.updateIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+	assert.Equal([]string{"/Common/source_addr", ""},
+		persistenceOf()["default_ingress-ingress_http"])
+	persistenceEvents := func() int {
+		count := 0
+		for 0 != len(fakeRecorder.Events) {
+			if strings.Contains(<-fakeRecorder.Events, "can not be a fallback") {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Equal(1, persistenceEvents(),
+		"Invalid persistence should be recorded in an Event")
+
+	// The error is recorded once, whatever the virtual servers and syncs
+	ingress.Spec.TLS = []v1beta1.IngressTLS{{SecretName: "/Common/clientssl"}}
+	ingress.ObjectMeta.Annotations[ingressAllowHttp] = "true"
+	ingress.ObjectMeta.Annotations[ingressSslRedirect] = "false"
+	for i := 0; i < 2; i++ {
+		r = appMgr.updateIngress(ingress)
+		assert.True(r, "Ingress resource should be processed")
+	}
+	assert.Equal([]string{"/Common/source_addr", ""},
+		persistenceOf()["default_ingress-ingress_https"])
+	assert.Equal(0, persistenceEvents(),
+		"The same error should not be recorded again")
+}
+
+func TestNamedServicePorts(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	fakeClient := fake.NewSimpleClientset()
+	fakeRecorder := record.NewFakeRecorder(100)
+	namespace := "default"
+
+	appMgr := newMockAppManager(&Params{
+		KubeClient:    fakeClient,
+		ConfigWriter:  mw,
+		restClient:    test.CreateFakeHTTPClient(),
+		IsNodePort:    true,
+		EventRecorder: fakeRecorder,
+	})
+	err := appMgr.startNonLabelMode([]string{namespace})
+	require.Nil(err)
+	defer appMgr.shutdown()
+
+	fooSvc := test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{
+			{Name: "http", Port: 8080, NodePort: 37001},
+			{Name: "metrics", Port: 9090, NodePort: 37002},
+		})
+	r := appMgr.addService(fooSvc)
+	assert.True(r, "Service should be processed")
+
+	data := strings.Replace(configmapFoo, `"servicePort": 80`,
+		`"servicePort": "http"`, 1)
+	cfgFoo := test.NewConfigMap("foomap", "1", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	r = appMgr.addConfigMap(cfgFoo)
+	assert.True(r, "Config map should be processed")
+	ingress := test.NewIngress("ingress", "1", namespace,
+		v1beta1.IngressSpec{
+			Rules: []v1beta1.IngressRule{
+				{
+					Host: "foo.com",
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{
+							Paths: []v1beta1.HTTPIngressPath{
+								{
+									Path: "/",
+									Backend: v1beta1.IngressBackend{
+										ServiceName: "foo",
+										ServicePort: intstr.FromString("http"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		map[string]string{
+			"virtual-server.f5.com/ip":        "1.2.3.4",
+			"virtual-server.f5.com/partition": "velcro",
+		})
+	r = appMgr.addIngress(ingress)
+	assert.True(r, "Ingress resource should be processed")
+
+	poolPorts := func() map[string]int32 {
+		appMgr.appMgr.outputConfig()
+		mw.Lock()
+		defer mw.Unlock()
+		ports := make(map[string]int32)
+		for _, pool := range mw.Sections["resources"].(BigIPConfig).Pools {
+			ports[pool.Name] = pool.ServicePort
+		}
+		return ports
+	}
+	assert.Equal(map[string]int32{
+		"default_foomap":               8080,
+		"default_ingress-ingress_http": 8080,
+	}, poolPorts())
+	assert.Equal(2, appMgr.resources().CountOf(
+		serviceKey{ServiceName: "foo", ServicePort: 8080, Namespace: namespace}))
+
+	// The names are resolved again when the Service changes
+	fooSvc = test.NewService("foo", "2", namespace, "NodePort",
+		[]v1.ServicePort{
+			{Name: "http", Port: 80, NodePort: 37001},
+			{Name: "metrics", Port: 9090, NodePort: 37002},
+		})
+	r = appMgr.updateService(fooSvc)
+	assert.True(r, "Service should be processed")
+	assert.Equal(map[string]int32{
+		"default_foomap":               80,
+		"default_ingress-ingress_http": 80,
+	}, poolPorts())
+	assert.Equal(0, appMgr.resources().CountOf(
+		serviceKey{ServiceName: "foo", ServicePort: 8080, Namespace: namespace}))
+
+	// Updates of the ConfigMap keep the config stored for its named port
+	cfgFoo = test.NewConfigMap("foomap", "2", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	r, _ = appMgr.appMgr.checkValidConfigMap(cfgFoo)
+	assert.True(r, "Config map should be processed")
+	assert.Equal(2, appMgr.resources().CountOf(
+		serviceKey{ServiceName: "foo", ServicePort: 80, Namespace: namespace}))
+	r = appMgr.updateConfigMap(cfgFoo)
+	assert.True(r, "Config map should be processed")
+
+	// Names the Service does not have are recorded in Events
+	for 0 != len(fakeRecorder.Events) {
+		<-fakeRecorder.Events
+	}
+	fooSvc = test.NewService("foo", "3", namespace, "NodePort",
+		[]v1.ServicePort{{Name: "metrics", Port: 9090, NodePort: 37002}})
+	r = appMgr.updateService(fooSvc)
+	assert.True(r, "Service should be processed")
+	assert.Empty(poolPorts())
+	var events []string
+	for 0 != len(fakeRecorder.Events) {
+		events = append(events, <-fakeRecorder.Events)
+	}
+	cmFound, ingFound := false, false
+	for _, event := range events {
+		if strings.Contains(event, "no port named 'http'") {
+			if strings.Contains(event, "backend") {
+				ingFound = true
+			} else {
+				cmFound = true
+			}
+		}
+	}
+	assert.True(cmFound, "Missing ConfigMap port should be recorded: %v",
+		events)
+	assert.True(ingFound, "Missing Ingress port should be recorded: %v",
+		events)
+}
+
 func TestIngressSslProfile(t *testing.T) {
 	mw := &test.MockWriter{
 		FailStyle: test.Success,
@@ -3401,11 +4155,11 @@ func TestIngressSslProfile(t *testing.T) {
 	resources := appMgr.resources()
 	assert.Equal(2, resources.Count())
 	assert.Equal(2, resources.CountOf(svcKey))
-	httpCfg, found := resources.Get(svcKey, formatIngressVSName(fooIng, "http"))
+	httpCfg, found := resources.Get(svcKey, ingressVSName(fooIng, "http"))
 	assert.True(found)
 	require.NotNil(httpCfg)
 
-	httpsCfg, found := resources.Get(svcKey, formatIngressVSName(fooIng, "https"))
+	httpsCfg, found := resources.Get(svcKey, ingressVSName(fooIng, "https"))
 	assert.True(found)
 	require.NotNil(httpsCfg)
 	secretArray := []string{
@@ -3425,7 +4179,7 @@ func TestIngressSslProfile(t *testing.T) {
 	fooIng.ObjectMeta.Annotations[ingressSslRedirect] = "true"
 	fooIng.ObjectMeta.Annotations[ingressAllowHttp] = "false"
 	r = appMgr.addIngress(fooIng)
-	httpCfg, found = resources.Get(svcKey, formatIngressVSName(fooIng, "http"))
+	httpCfg, found = resources.Get(svcKey, ingressVSName(fooIng, "http"))
 	assert.True(found)
 	require.NotNil(httpCfg)
 	assert.True(r, "Ingress resource should be processed")
@@ -3437,7 +4191,7 @@ func TestIngressSslProfile(t *testing.T) {
 	fooIng.ObjectMeta.Annotations[ingressSslRedirect] = "false"
 	fooIng.ObjectMeta.Annotations[ingressAllowHttp] = "false"
 	r = appMgr.addIngress(fooIng)
-	httpsCfg, found = resources.Get(svcKey, formatIngressVSName(fooIng, "https"))
+	httpsCfg, found = resources.Get(svcKey, ingressVSName(fooIng, "https"))
 	assert.True(found)
 	require.NotNil(httpsCfg)
 	assert.True(r, "Ingress resource should be processed")
@@ -3450,7 +4204,7 @@ func TestIngressSslProfile(t *testing.T) {
 	fooIng.ObjectMeta.Annotations[ingressSslRedirect] = "false"
 	fooIng.ObjectMeta.Annotations[ingressAllowHttp] = "true"
 	r = appMgr.addIngress(fooIng)
-	httpCfg, found = resources.Get(svcKey, formatIngressVSName(fooIng, "http"))
+	httpCfg, found = resources.Get(svcKey, ingressVSName(fooIng, "http"))
 	assert.True(found)
 	require.NotNil(httpCfg)
 	assert.True(r, "Ingress resource should be processed")
@@ -3464,12 +4218,12 @@ func TestIngressSslProfile(t *testing.T) {
 	assert.True(r, "Ingress resource should be processed")
 	assert.Equal(1, resources.Count())
 	assert.Equal(1, resources.CountOf(svcKey))
-	httpCfg, found = resources.Get(svcKey, formatIngressVSName(fooIng, "http"))
+	httpCfg, found = resources.Get(svcKey, ingressVSName(fooIng, "http"))
 	assert.True(found)
 	require.NotNil(httpCfg)
 	require.Equal(0, len(httpCfg.Policies))
 
-	httpsCfg, found = resources.Get(svcKey, formatIngressVSName(fooIng, "https"))
+	httpsCfg, found = resources.Get(svcKey, ingressVSName(fooIng, "https"))
 	assert.False(found)
 	require.Nil(httpsCfg)
 }
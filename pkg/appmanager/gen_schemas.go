@@ -0,0 +1,69 @@
+//go:build ignore
+// +build ignore
+
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Generates schemas_generated.go, which embeds the virtual server schemas in
+// the controller. Run with go generate after changing a schema.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const schemaGlob = "../../schemas/bigip-virtual-server_v*.json"
+
+func main() {
+	paths, err := filepath.Glob(schemaGlob)
+	if nil != err || 0 == len(paths) {
+		fmt.Fprintf(os.Stderr, "No schemas found for %s: %v\n", schemaGlob, err)
+		os.Exit(1)
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen_schemas.go; DO NOT EDIT.\n\n")
+	b.WriteString("package appmanager\n\n")
+	b.WriteString("// Schemas for the f5schemadb:// scheme, by name\n")
+	b.WriteString("var embeddedSchemas = map[string]string{\n")
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "Unable to read schema: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(&b, "\t%q: %q,\n", filepath.Base(path), data)
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "Unable to format generated code: %v\n", err)
+		os.Exit(1)
+	}
+	err = ioutil.WriteFile("schemas_generated.go", src, 0644)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "Unable to write generated code: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,364 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// Annotation making an Ingress a canary of the other Ingresses in its
+// namespace, which sends them the percentage of the requests for its paths
+const ingCanaryWeightAnnotation = "virtual-server.f5.com/canary-weight"
+
+// Variable the rules of an LTM policy set for the canary iRule when they
+// forward a request
+const canaryRuleVariable = "k8s_canary_rule"
+
+// Pool receiving a share of the requests forwarded to another pool
+type weightedPool struct {
+	pool   string
+	weight int
+}
+
+// Return the canary weight of an Ingress, and whether it is a canary
+func ingressCanaryWeight(ing *netv1.Ingress) (int, bool, error) {
+	value, found := ing.ObjectMeta.Annotations[ingCanaryWeightAnnotation]
+	if !found {
+		return 0, false, nil
+	}
+	weight, err := strconv.Atoi(value)
+	if nil != err || weight < 0 || weight > 100 {
+		return 0, true, fmt.Errorf("Invalid canary weight '%s', must be a "+
+			"percentage from 0 to 100", value)
+	}
+	return weight, true, nil
+}
+
+// Return the Ingresses of a namespace the controller handles, split into
+// the canaries with a valid weight and the others. Their named ports are
+// resolved.
+func (appMgr *Manager) namespaceIngresses(
+	namespace string,
+	appInf *appInformer,
+) ([]*netv1.Ingress, []*netv1.Ingress) {
+	var ingresses, canaries []*netv1.Ingress
+	objs, err := appInf.ingInformer.GetIndexer().ByIndex("namespace", namespace)
+	if nil != err {
+		return nil, nil
+	}
+	for _, obj := range objs {
+		ing, ours := appMgr.prepareIngress(obj)
+		if !ours {
+			continue
+		}
+		resolveIngressServicePorts(ing, appInf.svcInformer.GetIndexer())
+		if _, canary, err := ingressCanaryWeight(ing); !canary {
+			ingresses = append(ingresses, ing)
+		} else if nil == err {
+			canaries = append(canaries, ing)
+		}
+	}
+	// The canaries are applied in order of their names
+	sort.Slice(canaries, func(i, j int) bool {
+		return canaries[i].ObjectMeta.Name < canaries[j].ObjectMeta.Name
+	})
+	return ingresses, canaries
+}
+
+// Add the pools for the canaries of the paths of an Ingress, with the same
+// host and path, or the default backend of a canary for the default backend
+// of the Ingress, and an iRule sending them their weight of the requests.
+// Canaries that would make the weights of a pool add up to more than 100
+// are left out, and returned as errors.
+func addIngressCanaries(
+	cfg *ResourceConfig,
+	ing *netv1.Ingress,
+	canaries []*netv1.Ingress,
+	svcIndexer cache.Indexer,
+) []error {
+	var errs []error
+	splits := make(map[string][]weightedPool)
+	totals := make(map[string]int)
+	for _, canary := range canaries {
+		weight, _, _ := ingressCanaryWeight(canary)
+		if 0 == weight {
+			continue
+		}
+		for _, split := range ingressCanaryBackends(ing, canary) {
+			// The canary pool balances like the pool it splits requests of
+			primary, balance := "", ""
+			for _, pl := range cfg.Pools {
+				name := joinBigipPath(pl.Partition, pl.Name)
+				if (nil == split[0] && name == cfg.Virtual.PoolName) ||
+					(nil != split[0] && pl.ServiceName == split[0].Name &&
+						pl.ServicePort == split[0].Port.Number) {
+					primary, balance = name, pl.Balance
+					break
+				}
+			}
+			if "" == primary {
+				continue
+			}
+			if totals[primary]+weight > 100 {
+				errs = append(errs, fmt.Errorf("Canary Ingress '%s' is left "+
+					"out, the canary weights of pool '%s' add up to more "+
+					"than 100", canary.ObjectMeta.Name, primary))
+				continue
+			}
+			pool := canaryPool(cfg, canary.ObjectMeta.Namespace, split[1],
+				balance, svcIndexer)
+			if "" == pool {
+				continue
+			}
+			splits[primary] = append(splits[primary],
+				weightedPool{pool: pool, weight: weight})
+			totals[primary] += weight
+		}
+	}
+	if 0 == len(splits) {
+		return errs
+	}
+	// The pool the iRule selects stays selected for the next requests of
+	// the connection, so the rules that forward requests tell the iRule
+	// they did, and the iRule selects the default pool for the others
+	for _, pol := range cfg.Policies {
+		if pol.Name != cfg.Virtual.VirtualServerName {
+			continue
+		}
+		for _, rl := range pol.Rules {
+			forwards := false
+			for _, a := range rl.Actions {
+				forwards = forwards || a.Forward
+			}
+			if forwards {
+				rl.Actions = append(rl.Actions, &action{
+					Name:        strconv.Itoa(len(rl.Actions)),
+					Expression:  "1",
+					Request:     true,
+					SetVariable: true,
+					Tcl:         true,
+					TmName:      canaryRuleVariable,
+				})
+			}
+		}
+	}
+	iRule := IRule{
+		Name:      cfg.Virtual.VirtualServerName + "_canary",
+		Partition: cfg.Virtual.Partition,
+		Code:      canaryIRule(splits, cfg.Virtual.PoolName),
+	}
+	cfg.IRules = append(cfg.IRules, iRule)
+	cfg.Virtual.AddIRule(joinBigipPath(iRule.Partition, iRule.Name))
+	return errs
+}
+
+// Return the pairs of the backend of an Ingress and the backend of its
+// canary for the same host and path; the backend of the Ingress is nil for
+// its default backend
+func ingressCanaryBackends(
+	ing *netv1.Ingress,
+	canary *netv1.Ingress,
+) [][2]*netv1.IngressServiceBackend {
+	var backends [][2]*netv1.IngressServiceBackend
+	if nil != ing.Spec.DefaultBackend && nil != canary.Spec.DefaultBackend &&
+		nil != ing.Spec.DefaultBackend.Service &&
+		nil != canary.Spec.DefaultBackend.Service {
+		backends = append(backends, [2]*netv1.IngressServiceBackend{
+			nil, canary.Spec.DefaultBackend.Service})
+	}
+	for _, rule := range ing.Spec.Rules {
+		if nil == rule.IngressRuleValue.HTTP {
+			continue
+		}
+		for _, path := range rule.IngressRuleValue.HTTP.Paths {
+			if nil == path.Backend.Service {
+				continue
+			}
+			for _, cRule := range canary.Spec.Rules {
+				if rule.Host != cRule.Host || nil == cRule.IngressRuleValue.HTTP {
+					continue
+				}
+				for _, cPath := range cRule.IngressRuleValue.HTTP.Paths {
+					if path.Path == cPath.Path && nil != cPath.Backend.Service {
+						backends = append(backends,
+							[2]*netv1.IngressServiceBackend{
+								path.Backend.Service, cPath.Backend.Service})
+					}
+				}
+			}
+		}
+	}
+	return backends
+}
+
+// Return the full name of the pool for the backend of a canary, adding it
+// with the balance if the config has no pool for its Service yet. Missing
+// Services have no pool.
+func canaryPool(
+	cfg *ResourceConfig,
+	namespace string,
+	backend *netv1.IngressServiceBackend,
+	balance string,
+	svcIndexer cache.Indexer,
+) string {
+	for _, pl := range cfg.Pools {
+		if pl.ServiceName == backend.Name &&
+			pl.ServicePort == backend.Port.Number {
+			return joinBigipPath(pl.Partition, pl.Name)
+		}
+	}
+	if nil == svcIndexer {
+		return ""
+	}
+	if _, found, _ := svcIndexer.GetByKey(
+		namespace + "/" + backend.Name); !found {
+		return ""
+	}
+	pool := Pool{
+		Name: fmt.Sprintf("%s_canary_%s_%d", cfg.Virtual.VirtualServerName,
+			backend.Name, backend.Port.Number),
+		Partition:   cfg.Virtual.Partition,
+		Balance:     balance,
+		ServiceName: backend.Name,
+		ServicePort: backend.Port.Number,
+	}
+	cfg.Pools = append(cfg.Pools, pool)
+	return joinBigipPath(pool.Partition, pool.Name)
+}
+
+// iRule sending the weight, out of 100, of the requests forwarded to a pool
+// to each of its canary pools. The LTM policy of the virtual has already
+// selected the pool of a rule when the iRule runs; other requests go to the
+// default pool of the virtual, if it has one.
+func canaryIRule(splits map[string][]weightedPool, defaultPool string) string {
+	var primaries []string
+	for primary := range splits {
+		primaries = append(primaries, primary)
+	}
+	sort.Strings(primaries)
+
+	var b bytes.Buffer
+	b.WriteString("when HTTP_REQUEST {\n")
+	fmt.Fprintf(&b, "\tif { [info exists %s] } {\n", canaryRuleVariable)
+	fmt.Fprintf(&b, "\t\tunset %s\n", canaryRuleVariable)
+	b.WriteString("\t\tset primary [LB::server pool]\n")
+	b.WriteString("\t} else {\n")
+	fmt.Fprintf(&b, "\t\tset primary \"%s\"\n", defaultPool)
+	if "" != defaultPool {
+		fmt.Fprintf(&b, "\t\tpool %s\n", defaultPool)
+	}
+	b.WriteString("\t}\n")
+	b.WriteString("\tswitch -- $primary {\n")
+	for _, primary := range primaries {
+		fmt.Fprintf(&b, "\t\t\"%s\" {\n", primary)
+		b.WriteString("\t\t\tset share [expr {int(rand() * 100)}]\n")
+		total := 0
+		for i, canary := range splits[primary] {
+			total += canary.weight
+			if i == 0 {
+				fmt.Fprintf(&b, "\t\t\tif { $share < %d } {\n", total)
+			} else {
+				fmt.Fprintf(&b, "\t\t\t} elseif { $share < %d } {\n", total)
+			}
+			fmt.Fprintf(&b, "\t\t\t\tpool %s\n", canary.pool)
+		}
+		b.WriteString("\t\t\t}\n")
+		b.WriteString("\t\t}\n")
+	}
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// A canary has no virtual servers of its own, but changes those of the other
+// Ingresses in its namespace. Remove any virtual servers it had, and return
+// the keys of the Services of the others and of the canary, so that they are
+// synced. Must be called with the resources lock held.
+func (appMgr *Manager) canaryServiceKeysLocked(
+	canary *netv1.Ingress,
+	ingresses []*netv1.Ingress,
+	canaries []*netv1.Ingress,
+	appInf *appInformer,
+) []*serviceQueueKey {
+	namespace := canary.ObjectMeta.Namespace
+	svcIndexer := appInf.svcInformer.GetIndexer()
+	deleted := false
+	for _, portStruct := range appMgr.virtualPorts(canary) {
+		rsName := formatIngressVSName(canary, portStruct.protocol)
+		_, keys := appMgr.resources.GetAllWithName(rsName)
+		for _, key := range keys {
+			appMgr.resources.Delete(key, rsName)
+			deleted = true
+		}
+	}
+	if deleted {
+		appMgr.outputConfigLocked()
+	}
+
+	var keys []*serviceQueueKey
+	found := make(map[string]bool)
+	addKey := func(svcName string) {
+		if !found[svcName] {
+			found[svcName] = true
+			keys = append(keys, &serviceQueueKey{
+				ServiceName: svcName,
+				Namespace:   namespace,
+			})
+		}
+	}
+	if nil != canary.Spec.DefaultBackend &&
+		nil != canary.Spec.DefaultBackend.Service {
+		addKey(canary.Spec.DefaultBackend.Service.Name)
+	}
+	for _, rule := range canary.Spec.Rules {
+		if nil == rule.IngressRuleValue.HTTP {
+			continue
+		}
+		for _, path := range rule.IngressRuleValue.HTTP.Paths {
+			if nil != path.Backend.Service {
+				addKey(path.Backend.Service.Name)
+			}
+		}
+	}
+	for _, ing := range ingresses {
+		partition, err := appMgr.resourcePartition(
+			appMgr.namespacePartition(namespace),
+			ing.ObjectMeta.Annotations[partitionAnnotation])
+		if nil != err {
+			partition = DEFAULT_PARTITION
+		}
+		for _, portStruct := range appMgr.virtualPorts(ing) {
+			rsCfg := createRSConfigFromIngress(
+				ing, partition, namespace, svcIndexer, portStruct)
+			if nil == rsCfg {
+				continue
+			}
+			addIngressCanaries(rsCfg, ing, canaries, svcIndexer)
+			for _, pool := range rsCfg.Pools {
+				addKey(pool.ServiceName)
+			}
+		}
+	}
+	return keys
+}
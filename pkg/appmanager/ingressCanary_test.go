@@ -0,0 +1,244 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+import (
+	"testing"
+
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestIngressCanaryWeight(t *testing.T) {
+	assert := assert.New(t)
+	ing := test.NewNetworkingIngress("ingress", "1", "default",
+		netv1.IngressSpec{}, map[string]string{})
+	_, canary, err := ingressCanaryWeight(ing)
+	assert.False(canary)
+	assert.Nil(err)
+
+	for value, expected := range map[string]int{"0": 0, "20": 20, "100": 100} {
+		ing.ObjectMeta.Annotations[ingCanaryWeightAnnotation] = value
+		weight, canary, err := ingressCanaryWeight(ing)
+		assert.True(canary)
+		assert.Nil(err)
+		assert.Equal(expected, weight)
+	}
+	for _, value := range []string{"", "-1", "101", "20%"} {
+		ing.ObjectMeta.Annotations[ingCanaryWeightAnnotation] = value
+		_, canary, err := ingressCanaryWeight(ing)
+		assert.True(canary)
+		assert.NotNil(err, "Weight '%s' should not be valid", value)
+	}
+}
+
+func TestCanaryIRule(t *testing.T) {
+	splits := map[string][]weightedPool{
+		"/velcro/web": {
+			{pool: "/velcro/web-v2", weight: 10},
+			{pool: "/velcro/web-v3", weight: 5},
+		},
+		"/velcro/api": {{pool: "/velcro/api-v2", weight: 50}},
+	}
+	assert.Equal(t, `when HTTP_REQUEST {
+	if { [info exists k8s_canary_rule] } {
+		unset k8s_canary_rule
+		set primary [LB::server pool]
+	} else {
+		set primary "/velcro/web"
+		pool /velcro/web
+	}
+	switch -- $primary {
+		"/velcro/api" {
+			set share [expr {int(rand() * 100)}]
+			if { $share < 50 } {
+				pool /velcro/api-v2
+			}
+		}
+		"/velcro/web" {
+			set share [expr {int(rand() * 100)}]
+			if { $share < 10 } {
+				pool /velcro/web-v2
+			} elseif { $share < 15 } {
+				pool /velcro/web-v3
+			}
+		}
+	}
+}
+`, canaryIRule(splits, "/velcro/web"))
+
+	// Without a default pool, requests that match no rule are left alone
+	assert.Contains(t, canaryIRule(splits, ""), `
+	} else {
+		set primary ""
+	}
+`)
+}
+
+func TestIngressCanary(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	fakeClient := fake.NewSimpleClientset()
+	fakeRecorder := record.NewFakeRecorder(100)
+	namespace := "default"
+
+	appMgr := newMockAppManager(&Params{
+		KubeClient:         fakeClient,
+		ConfigWriter:       mw,
+		restClient:         test.CreateFakeHTTPClient(),
+		NetworkingClientV1: test.CreateFakeHTTPClient(),
+		IsNodePort:         true,
+		EventRecorder:      fakeRecorder,
+	})
+	err := appMgr.startNonLabelMode([]string{namespace})
+	require.Nil(err)
+	defer appMgr.shutdown()
+
+	for i, name := range []string{"web", "web-v2"} {
+		svcPorts := []v1.ServicePort{newServicePort(name, 80)}
+		svcPorts[0].NodePort = int32(30001 + i)
+		r := appMgr.addService(test.NewService(name, "1", namespace,
+			v1.ServiceTypeNodePort, svcPorts))
+		assert.True(r, "Service should be processed")
+	}
+
+	spec := func(svcName string) netv1.IngressSpec {
+		return netv1.IngressSpec{
+			Rules: []netv1.IngressRule{
+				{
+					Host: "foo.com",
+					IngressRuleValue: netv1.IngressRuleValue{
+						HTTP: &netv1.HTTPIngressRuleValue{
+							Paths: []netv1.HTTPIngressPath{
+								{
+									Path: "/",
+									Backend: netv1.IngressBackend{
+										Service: &netv1.IngressServiceBackend{
+											Name: svcName,
+											Port: netv1.ServiceBackendPort{
+												Number: 80,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	ing := test.NewNetworkingIngress("ingress", "1", namespace, spec("web"),
+		map[string]string{
+			"virtual-server.f5.com/ip":        "1.2.3.4",
+			"virtual-server.f5.com/partition": "velcro",
+			"virtual-server.f5.com/balance":   "least-connections-member",
+		})
+	r := appMgr.addIngress(ing)
+	assert.True(r, "Ingress resource should be processed")
+
+	output := func() BigIPConfig {
+		appMgr.appMgr.outputConfig()
+		mw.Lock()
+		defer mw.Unlock()
+		return mw.Sections["resources"].(BigIPConfig)
+	}
+	config := output()
+	require.Equal(1, len(config.Virtuals))
+	assert.Equal(0, len(config.Virtuals[0].IRules))
+	assert.Equal(1, len(config.Pools))
+
+	// The canary sends its weight of the requests for its paths to its
+	// Service, without a virtual server of its own
+	canary := test.NewNetworkingIngress("ingress-canary", "1", namespace,
+		spec("web-v2"), map[string]string{
+			"virtual-server.f5.com/ip": "1.2.3.5",
+			ingCanaryWeightAnnotation:  "20",
+		})
+	r = appMgr.addIngress(canary)
+	assert.True(r, "Canary Ingress should be processed")
+	canaryPool := "default_ingress-ingress_http_canary_web-v2_80"
+	iRuleName := "/velcro/default_ingress-ingress_http_canary"
+	config = output()
+	require.Equal(1, len(config.Virtuals))
+	assert.Equal([]string{iRuleName}, config.Virtuals[0].IRules)
+	require.Equal(2, len(config.Pools))
+	assert.Equal(canaryPool, config.Pools[1].Name)
+	assert.Equal("web-v2", config.Pools[1].ServiceName)
+	assert.Equal(int32(80), config.Pools[1].ServicePort)
+	assert.Equal("least-connections-member", config.Pools[1].Balance,
+		"Canary pool should balance like the pool it splits")
+	require.Equal(1, len(config.IRules))
+	assert.Contains(config.IRules[0].Code, `"/velcro/default_ingress-ingress_http"`)
+	assert.Contains(config.IRules[0].Code, "$share < 20")
+	assert.Contains(config.IRules[0].Code, "pool /velcro/"+canaryPool)
+
+	// The rule forwarding to the pool tells the iRule it matched
+	require.Equal(1, len(config.Policies))
+	require.Equal(1, len(config.Policies[0].Rules))
+	actions := config.Policies[0].Rules[0].Actions
+	require.Equal(2, len(actions))
+	assert.True(actions[0].Forward)
+	assert.True(actions[1].SetVariable)
+	assert.Equal(canaryRuleVariable, actions[1].TmName)
+
+	// Changes of the weight update the iRule
+	canary.ObjectMeta.Annotations[ingCanaryWeightAnnotation] = "50"
+	canary.ObjectMeta.ResourceVersion = "2"
+	r = appMgr.updateIngress(canary)
+	assert.True(r, "Canary Ingress should be processed")
+	config = output()
+	require.Equal(1, len(config.IRules))
+	assert.Contains(config.IRules[0].Code, "$share < 50")
+
+	// Canaries whose weights add up to more than 100 are left out
+	for 0 != len(fakeRecorder.Events) {
+		<-fakeRecorder.Events
+	}
+	second := test.NewNetworkingIngress("ingress-canary-2", "1", namespace,
+		spec("web-v2"), map[string]string{ingCanaryWeightAnnotation: "60"})
+	r = appMgr.addIngress(second)
+	assert.True(r, "Canary Ingress should be processed")
+	config = output()
+	require.Equal(1, len(config.IRules))
+	assert.Contains(config.IRules[0].Code, "$share < 50")
+	assert.NotContains(config.IRules[0].Code, "$share < 110")
+	require.NotEqual(0, len(fakeRecorder.Events))
+	assert.Contains(<-fakeRecorder.Events,
+		"Canary Ingress 'ingress-canary-2' is left out")
+	r = appMgr.deleteIngress(second)
+	assert.True(r, "Canary Ingress should be processed")
+
+	// Without the canary, all requests go to the Service of the Ingress
+	r = appMgr.deleteIngress(canary)
+	assert.True(r, "Canary Ingress should be processed")
+	config = output()
+	require.Equal(1, len(config.Virtuals))
+	assert.Equal(0, len(config.Virtuals[0].IRules))
+	assert.Equal(1, len(config.Pools))
+	assert.Equal(0, len(config.IRules))
+}
@@ -0,0 +1,307 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+import (
+	"time"
+
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Deprecated annotation naming the class of an Ingress
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// Defaults of the class name and controller the controller handles
+const (
+	DefaultIngressClass      = "f5"
+	DefaultIngressController = "f5.com/k8s-bigip-ctlr"
+)
+
+// IngressClasses are cluster wide, so there is one informer for all the
+// watched namespaces
+func (appMgr *Manager) newIngressClassInformer(
+	resyncPeriod time.Duration,
+) cache.SharedIndexInformer {
+	informer := cache.NewSharedIndexInformer(
+		newListWatchWithLabelSelector(
+			appMgr.netClientV1,
+			"ingressclasses",
+			"",
+			labels.Everything(),
+		),
+		&netv1.IngressClass{},
+		resyncPeriod,
+		cache.Indexers{},
+	)
+	informer.AddEventHandlerWithResyncPeriod(
+		&cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { appMgr.enqueueIngressClass(obj) },
+			UpdateFunc: func(old, cur interface{}) { appMgr.enqueueIngressClass(cur) },
+			DeleteFunc: func(obj interface{}) { appMgr.enqueueIngressClass(obj) },
+		},
+		resyncPeriod,
+	)
+	return informer
+}
+
+// A class change can add or remove any Ingress, so all of them are queued
+func (appMgr *Manager) enqueueIngressClass(obj interface{}) {
+	var ingresses []interface{}
+	appMgr.informersMutex.Lock()
+	for _, appInf := range appMgr.appInformers {
+		ingresses = append(ingresses, appInf.ingInformer.GetStore().List()...)
+	}
+	appMgr.informersMutex.Unlock()
+	for _, ing := range ingresses {
+		appMgr.enqueueIngress(ing)
+	}
+}
+
+func (appMgr *Manager) startAndSyncIngressClassInformer(
+	stopCh <-chan struct{},
+) {
+	go appMgr.ingClassInformer.Run(stopCh)
+	cache.WaitForCacheSync(stopCh, appMgr.ingClassInformer.HasSynced)
+}
+
+// Return the Ingress in the networking.k8s.io/v1 form, whichever API it
+// came from
+func toNetworkingIngress(obj interface{}) *netv1.Ingress {
+	switch ing := obj.(type) {
+	case *netv1.Ingress:
+		// Copy, so the cached object is not changed
+		ingCopy := *ing
+		return &ingCopy
+	case *v1beta1.Ingress:
+		return netv1.ConvertV1beta1Ingress(ing)
+	}
+	return nil
+}
+
+// Return the Ingress with the defaults of its class, and whether the
+// controller handles it
+func (appMgr *Manager) prepareIngress(obj interface{}) (*netv1.Ingress, bool) {
+	ing := toNetworkingIngress(obj)
+	if nil == ing {
+		return nil, false
+	}
+	class, ours := appMgr.ingressClassOf(ing)
+	if !ours {
+		return ing, false
+	}
+	if defaults := appMgr.ingressClassDefaults(class); 0 != len(defaults) {
+		// The annotations of the Ingress override those of its class
+		annotations := make(map[string]string)
+		for key, value := range defaults {
+			annotations[key] = value
+		}
+		for key, value := range ing.ObjectMeta.Annotations {
+			annotations[key] = value
+		}
+		ing.ObjectMeta.Annotations = annotations
+	}
+	return ing, true
+}
+
+// Return the IngressClass of an Ingress, if known, and whether it is the
+// controller's. Ingresses without a class belong to the default class, or
+// to the controller if there is none.
+func (appMgr *Manager) ingressClassOf(
+	ing *netv1.Ingress,
+) (*netv1.IngressClass, bool) {
+	var className string
+	if nil != ing.Spec.IngressClassName {
+		className = *ing.Spec.IngressClassName
+	} else if class, ok := ing.ObjectMeta.Annotations[ingressClassAnnotation]; ok {
+		return nil, class == appMgr.ingressClass
+	}
+	if "" == className {
+		class := appMgr.defaultIngressClass()
+		if nil == class {
+			return nil, true
+		}
+		return class, class.Spec.Controller == appMgr.ingressController
+	}
+	class := appMgr.getIngressClass(className)
+	if nil == class {
+		// Without the IngressClass, only its name identifies the controller
+		return nil, className == appMgr.ingressClass
+	}
+	return class, class.Spec.Controller == appMgr.ingressController
+}
+
+func (appMgr *Manager) getIngressClass(name string) *netv1.IngressClass {
+	if nil == appMgr.ingClassInformer {
+		return nil
+	}
+	obj, found, _ := appMgr.ingClassInformer.GetStore().GetByKey(name)
+	if !found {
+		return nil
+	}
+	return obj.(*netv1.IngressClass)
+}
+
+func (appMgr *Manager) defaultIngressClass() *netv1.IngressClass {
+	if nil == appMgr.ingClassInformer {
+		return nil
+	}
+	for _, obj := range appMgr.ingClassInformer.GetStore().List() {
+		class := obj.(*netv1.IngressClass)
+		if "true" == class.ObjectMeta.Annotations[netv1.AnnotationIsDefaultIngressClass] {
+			return class
+		}
+	}
+	return nil
+}
+
+// The parameters of an IngressClass may reference a ConfigMap, whose data
+// are the default annotations of the Ingresses of the class
+func (appMgr *Manager) ingressClassDefaults(
+	class *netv1.IngressClass,
+) map[string]string {
+	namespace, name, ok := ingressClassParamsConfigMap(class)
+	if !ok {
+		return nil
+	}
+	informer := appMgr.ingressClassParamsInformer(namespace, name)
+	if nil == informer {
+		return nil
+	}
+	obj, found, _ := informer.GetStore().GetByKey(namespace + "/" + name)
+	if !found {
+		log.Warningf("Could not find the parameters of IngressClass '%s'",
+			class.ObjectMeta.Name)
+		return nil
+	}
+	return obj.(*v1.ConfigMap).Data
+}
+
+// Return the namespace and name of the ConfigMap the parameters of an
+// IngressClass reference, and whether they reference one
+func ingressClassParamsConfigMap(
+	class *netv1.IngressClass,
+) (string, string, bool) {
+	if nil == class || nil == class.Spec.Parameters {
+		return "", "", false
+	}
+	params := class.Spec.Parameters
+	if (nil != params.APIGroup && "" != *params.APIGroup) ||
+		"ConfigMap" != params.Kind || nil == params.Namespace {
+		log.Warningf("The parameters of IngressClass '%s' are not a "+
+			"namespaced ConfigMap", class.ObjectMeta.Name)
+		return "", "", false
+	}
+	return *params.Namespace, params.Name, true
+}
+
+// Return the informer for a ConfigMap referenced by the parameters of an
+// IngressClass, starting it and waiting for its cache to sync if it is not
+// running yet. Changes of the ConfigMap queue the Ingresses of the classes
+// referencing it. Once the informers are stopped, no new one is started and
+// nil is returned.
+func (appMgr *Manager) ingressClassParamsInformer(
+	namespace string,
+	name string,
+) cache.SharedIndexInformer {
+	appMgr.classParamsMutex.Lock()
+	defer appMgr.classParamsMutex.Unlock()
+	key := namespace + "/" + name
+	if informer, ok := appMgr.classParamsInformer[key]; ok {
+		return informer
+	}
+	if appMgr.classParamsStopped {
+		return nil
+	}
+	if nil == appMgr.classParamsStopCh {
+		appMgr.classParamsStopCh = make(chan struct{})
+		appMgr.classParamsInformer = make(map[string]cache.SharedIndexInformer)
+	}
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	configMaps := appMgr.kubeClient.Core().ConfigMaps(namespace)
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = fieldSelector
+				return configMaps.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = fieldSelector
+				return configMaps.Watch(options)
+			},
+		},
+		&v1.ConfigMap{},
+		0,
+		cache.Indexers{},
+	)
+	enqueue := func(obj interface{}) {
+		appMgr.enqueueIngressClassParams(namespace, name)
+	}
+	informer.AddEventHandler(&cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(old, cur interface{}) { enqueue(cur) },
+		DeleteFunc: enqueue,
+	})
+	go informer.Run(appMgr.classParamsStopCh)
+	cache.WaitForCacheSync(appMgr.classParamsStopCh, informer.HasSynced)
+	appMgr.classParamsInformer[key] = informer
+	return informer
+}
+
+// Queue the Ingresses whose class has its parameters in a ConfigMap
+func (appMgr *Manager) enqueueIngressClassParams(namespace, name string) {
+	var ingresses []interface{}
+	appMgr.informersMutex.Lock()
+	for _, appInf := range appMgr.appInformers {
+		ingresses = append(ingresses, appInf.ingInformer.GetStore().List()...)
+	}
+	appMgr.informersMutex.Unlock()
+	for _, obj := range ingresses {
+		ing := toNetworkingIngress(obj)
+		if nil == ing {
+			continue
+		}
+		class, ours := appMgr.ingressClassOf(ing)
+		if !ours {
+			continue
+		}
+		ns, n, ok := ingressClassParamsConfigMap(class)
+		if ok && ns == namespace && n == name {
+			appMgr.enqueueIngress(obj)
+		}
+	}
+}
+
+func (appMgr *Manager) stopIngressClassParamsInformers() {
+	appMgr.classParamsMutex.Lock()
+	defer appMgr.classParamsMutex.Unlock()
+	appMgr.classParamsStopped = true
+	if nil != appMgr.classParamsStopCh {
+		close(appMgr.classParamsStopCh)
+		appMgr.classParamsStopCh = nil
+		appMgr.classParamsInformer = nil
+	}
+}
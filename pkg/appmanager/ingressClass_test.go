@@ -0,0 +1,197 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package appmanager
+
+import (
+	"testing"
+
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestIngressClass(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+	defaultsNamespace := "kube-system"
+	fakeClient := fake.NewSimpleClientset(test.NewConfigMap(
+		"bigip-defaults", "1", defaultsNamespace, map[string]string{
+			"virtual-server.f5.com/balance":   "least-connections-member",
+			"virtual-server.f5.com/http-port": "8080",
+		}))
+	fakeRecorder := record.NewFakeRecorder(100)
+
+	appMgr := newMockAppManager(&Params{
+		KubeClient:         fakeClient,
+		ConfigWriter:       mw,
+		restClient:         test.CreateFakeHTTPClient(),
+		NetworkingClientV1: test.CreateFakeHTTPClient(),
+		IsNodePort:         true,
+		EventRecorder:      fakeRecorder,
+	})
+	err := appMgr.startNonLabelMode([]string{namespace})
+	require.Nil(err)
+	defer appMgr.shutdown()
+	require.NotNil(appMgr.appMgr.ingClassInformer)
+
+	svcPorts := []v1.ServicePort{newServicePort("foo", 80)}
+	svcPorts[0].NodePort = 30001
+	svc := test.NewService("foo", "1", namespace, v1.ServiceTypeNodePort,
+		svcPorts)
+	r := appMgr.addService(svc)
+	assert.True(r, "Service should be processed")
+
+	className := func(name string) *string { return &name }
+	spec := netv1.IngressSpec{
+		DefaultBackend: &netv1.IngressBackend{
+			Service: &netv1.IngressServiceBackend{
+				Name: "foo",
+				Port: netv1.ServiceBackendPort{Number: 80},
+			},
+		},
+	}
+	annotations := map[string]string{
+		"virtual-server.f5.com/ip":        "1.2.3.4",
+		"virtual-server.f5.com/http-port": "81",
+	}
+	svcKey := serviceKey{
+		Namespace:   namespace,
+		ServiceName: "foo",
+		ServicePort: 80,
+	}
+	resources := appMgr.resources()
+
+	// Without any IngressClass, Ingresses without a class are ours
+	ing := test.NewNetworkingIngress("ingress", "1", namespace, spec,
+		annotations)
+	r = appMgr.addIngress(ing)
+	assert.True(r, "Ingress without a class should be processed")
+	assert.Equal(1, resources.CountOf(svcKey))
+
+	// An unknown class is only ours if it has the name of our class
+	spec.IngressClassName = className("other")
+	ing = test.NewNetworkingIngress("ingress", "2", namespace, spec,
+		annotations)
+	r = appMgr.updateIngress(ing)
+	assert.False(r, "Ingress of another class should not be processed")
+	assert.Equal(0, resources.CountOf(svcKey),
+		"Virtual server should be removed when the class changes")
+
+	spec.IngressClassName = className(DefaultIngressClass)
+	ing = test.NewNetworkingIngress("ingress", "3", namespace, spec,
+		annotations)
+	r = appMgr.updateIngress(ing)
+	assert.True(r, "Ingress of our class should be processed")
+	assert.Equal(1, resources.CountOf(svcKey))
+
+	// Known classes are ours if they name our controller, and their
+	// parameters set the default annotations of their Ingresses
+	classStore := appMgr.appMgr.ingClassInformer.GetStore()
+	classStore.Add(test.NewIngressClass("bigip", "1", DefaultIngressController,
+		&netv1.IngressClassParametersReference{
+			Kind:      "ConfigMap",
+			Name:      "bigip-defaults",
+			Namespace: &defaultsNamespace,
+		}, nil))
+	classStore.Add(test.NewIngressClass(DefaultIngressClass, "1",
+		"example.com/other", nil, nil))
+
+	r = appMgr.updateIngress(ing)
+	assert.False(r, "Ingress of another controller should not be processed")
+	assert.Equal(0, resources.CountOf(svcKey))
+
+	spec.IngressClassName = className("bigip")
+	ing = test.NewNetworkingIngress("ingress", "4", namespace, spec,
+		annotations)
+	r = appMgr.updateIngress(ing)
+	assert.True(r, "Ingress of our controller should be processed")
+	require.Equal(1, resources.CountOf(svcKey))
+	rs, ok := resources.Get(svcKey, "default_ingress-ingress_http")
+	require.True(ok)
+	assert.Equal("least-connections-member", rs.Pools[0].Balance,
+		"Annotation should default to the class parameters")
+	assert.Equal(int32(81), rs.Virtual.VirtualAddress.Port,
+		"Ingress annotation should override the class parameters")
+	_, ok = ing.ObjectMeta.Annotations["virtual-server.f5.com/balance"]
+	assert.False(ok, "Ingress should not be changed")
+
+	// The parameters are read from an informer, not from the API on every
+	// sync, and changes of them queue the Ingresses of the class
+	for _, action := range fakeClient.Actions() {
+		if "configmaps" == action.GetResource().Resource {
+			assert.NotEqual("get", action.GetVerb(),
+				"Parameters should not be read from the API")
+		}
+	}
+	paramsInformer := appMgr.appMgr.ingressClassParamsInformer(
+		defaultsNamespace, "bigip-defaults")
+	paramsInformer.GetStore().Update(test.NewConfigMap(
+		"bigip-defaults", "2", defaultsNamespace, map[string]string{
+			"virtual-server.f5.com/balance": "ratio-member",
+		}))
+	appMgr.appMgr.enqueueIngressClassParams(defaultsNamespace,
+		"bigip-defaults")
+	require.NotEqual(0, appMgr.appMgr.vsQueue.Len())
+	key, _ := appMgr.appMgr.vsQueue.Get()
+	assert.Equal(serviceQueueKey{ServiceName: "foo", Namespace: namespace},
+		key)
+	appMgr.appMgr.vsQueue.Done(key)
+	r = appMgr.updateIngress(ing)
+	assert.True(r, "Ingress of our controller should be processed")
+	rs, ok = resources.Get(svcKey, "default_ingress-ingress_http")
+	require.True(ok)
+	assert.Equal("ratio-member", rs.Pools[0].Balance,
+		"Annotation should default to the changed class parameters")
+
+	// The deprecated annotation is still honored
+	spec.IngressClassName = nil
+	ing = test.NewNetworkingIngress("ingress", "5", namespace, spec,
+		map[string]string{
+			"virtual-server.f5.com/ip":    "1.2.3.4",
+			"kubernetes.io/ingress.class": DefaultIngressClass,
+		})
+	r = appMgr.updateIngress(ing)
+	assert.True(r, "Ingress with our class annotation should be processed")
+	assert.Equal(1, resources.CountOf(svcKey))
+
+	// Ingresses without a class belong to the default class
+	delete(ing.ObjectMeta.Annotations, "kubernetes.io/ingress.class")
+	classStore.Update(test.NewIngressClass(DefaultIngressClass, "2",
+		"example.com/other", nil, map[string]string{
+			netv1.AnnotationIsDefaultIngressClass: "true",
+		}))
+	r = appMgr.updateIngress(ing)
+	assert.False(r, "Ingress of another default class should not be processed")
+	assert.Equal(0, resources.CountOf(svcKey))
+
+	// Once stopped, no informer is started for the parameters
+	appMgr.appMgr.stopIngressClassParamsInformers()
+	assert.Nil(appMgr.appMgr.ingressClassDefaults(
+		appMgr.appMgr.getIngressClass("bigip")))
+	assert.Nil(appMgr.appMgr.ingressClassParamsInformer(
+		defaultsNamespace, "bigip-defaults"))
+	assert.Nil(appMgr.appMgr.classParamsInformer)
+}
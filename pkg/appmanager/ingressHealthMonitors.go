@@ -18,16 +18,17 @@ package appmanager
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 
-	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
 )
 
 func (appMgr *Manager) assignHealthMonitorsByPath(
 	rsName string,
-	ing *v1beta1.Ingress,
+	ing *netv1.Ingress,
 	rulesMap ingressHostToPathMap,
 	monitors IngressHealthMonitors,
 ) error {
@@ -39,6 +40,11 @@ func (appMgr *Manager) assignHealthMonitorsByPath(
 			return fmt.Errorf("Health Monitor path '%v' is not valid.", mon.Path)
 		}
 
+		if err := validateIngressHealthMonitor(mon); nil != err {
+			return fmt.Errorf("Health Monitor for path '%v' is not valid: %v",
+				mon.Path, err)
+		}
+
 		host := mon.Path[:slashPos]
 		path := mon.Path[slashPos:]
 		pm, found := rulesMap[host]
@@ -64,6 +70,46 @@ func (appMgr *Manager) assignHealthMonitorsByPath(
 	return nil
 }
 
+// Monitor types that check a response to the send string
+var sendRecvMonitorTypes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"tcp":   true,
+	"udp":   true,
+}
+
+// Monitor types that only check the member is reachable
+var reachabilityMonitorTypes = map[string]bool{
+	"icmp":          true,
+	"gateway-icmp":  true,
+	"tcp-half-open": true,
+}
+
+// The annotation has no schema, so check what the ConfigMap schema checks
+func validateIngressHealthMonitor(mon IngressHealthMonitor) error {
+	if "" != mon.Protocol &&
+		!sendRecvMonitorTypes[mon.Protocol] &&
+		!reachabilityMonitorTypes[mon.Protocol] {
+		return fmt.Errorf("Unknown protocol '%s'", mon.Protocol)
+	}
+	if reachabilityMonitorTypes[mon.Protocol] &&
+		("" != mon.Send || "" != mon.Recv || "" != mon.RecvDisable) {
+		return fmt.Errorf("Protocol '%s' does not use send or receive strings",
+			mon.Protocol)
+	}
+	if "" != mon.AliasAddress && nil == net.ParseIP(mon.AliasAddress) {
+		return fmt.Errorf("Alias address '%s' is not an IP address",
+			mon.AliasAddress)
+	}
+	if mon.AliasPort < 0 || mon.AliasPort > 65535 {
+		return fmt.Errorf("Alias port %d is not valid", mon.AliasPort)
+	}
+	if mon.UpInterval < 0 || mon.TimeUntilUp < 0 {
+		return fmt.Errorf("Up interval and time until up can not be negative")
+	}
+	return nil
+}
+
 func (appMgr *Manager) assignMonitorToPool(
 	cfg *ResourceConfig,
 	fullPoolPath string,
@@ -73,13 +119,23 @@ func (appMgr *Manager) assignMonitorToPool(
 	for poolNdx, pool := range cfg.Pools {
 		if pool.Partition == partition && pool.Name == poolName {
 			ruleData.assigned = true
+			protocol := ruleData.healthMon.Protocol
+			if "" == protocol {
+				protocol = "http"
+			}
 			monitor := Monitor{
-				Name:      poolName,
-				Partition: partition,
-				Protocol:  "http",
-				Interval:  ruleData.healthMon.Interval,
-				Send:      ruleData.healthMon.Send,
-				Timeout:   ruleData.healthMon.Timeout,
+				Name:         poolName,
+				Partition:    partition,
+				Protocol:     protocol,
+				Interval:     ruleData.healthMon.Interval,
+				Send:         ruleData.healthMon.Send,
+				Recv:         ruleData.healthMon.Recv,
+				RecvDisable:  ruleData.healthMon.RecvDisable,
+				Timeout:      ruleData.healthMon.Timeout,
+				UpInterval:   ruleData.healthMon.UpInterval,
+				TimeUntilUp:  ruleData.healthMon.TimeUntilUp,
+				AliasAddress: ruleData.healthMon.AliasAddress,
+				AliasPort:    ruleData.healthMon.AliasPort,
 			}
 			cfg.SetMonitor(&cfg.Pools[poolNdx], monitor)
 		}
@@ -88,7 +144,7 @@ func (appMgr *Manager) assignMonitorToPool(
 
 func (appMgr *Manager) notifyUnusedHealthMonitorRules(
 	rsName string,
-	ing *v1beta1.Ingress,
+	ing *netv1.Ingress,
 	hostToPathMap ingressHostToPathMap,
 ) {
 	for _, paths := range hostToPathMap {
@@ -106,14 +162,14 @@ func (appMgr *Manager) notifyUnusedHealthMonitorRules(
 func (appMgr *Manager) handleSingleServiceHealthMonitors(
 	rsName string,
 	cfg *ResourceConfig,
-	ing *v1beta1.Ingress,
+	ing *netv1.Ingress,
 	monitors IngressHealthMonitors,
 ) {
 	// Setup the rule-to-pool map from the ingress
 	ruleItem := make(ingressPathToRuleMap)
 	ruleItem["/"] = &ingressRuleData{
-		svcName: ing.Spec.Backend.ServiceName,
-		svcPort: ing.Spec.Backend.ServicePort.IntVal,
+		svcName: ing.Spec.DefaultBackend.Service.Name,
+		svcPort: ing.Spec.DefaultBackend.Service.Port.Number,
 	}
 	hostToPathMap := make(ingressHostToPathMap)
 	hostToPathMap["*"] = ruleItem
@@ -140,7 +196,7 @@ func (appMgr *Manager) handleSingleServiceHealthMonitors(
 func (appMgr *Manager) handleMultiServiceHealthMonitors(
 	rsName string,
 	cfg *ResourceConfig,
-	ing *v1beta1.Ingress,
+	ing *netv1.Ingress,
 	monitors IngressHealthMonitors,
 ) {
 	// Setup the rule-to-pool map from the ingress
@@ -159,6 +215,9 @@ func (appMgr *Manager) handleMultiServiceHealthMonitors(
 			hostToPathMap[host] = ruleItem
 		}
 		for _, path := range rule.IngressRuleValue.HTTP.Paths {
+			if nil == path.Backend.Service {
+				continue
+			}
 			pathKey := path.Path
 			if "" == pathKey {
 				pathKey = "/"
@@ -172,8 +231,8 @@ func (appMgr *Manager) handleMultiServiceHealthMonitors(
 				appMgr.recordIngressEvent(ing, "DuplicatePath", msg, rsName)
 			} else {
 				pathItem = &ingressRuleData{
-					svcName: path.Backend.ServiceName,
-					svcPort: path.Backend.ServicePort.IntVal,
+					svcName: path.Backend.Service.Name,
+					svcPort: path.Backend.Service.Port.Number,
 				}
 				ruleItem[pathKey] = pathItem
 			}
@@ -191,6 +250,25 @@ func (appMgr *Manager) handleMultiServiceHealthMonitors(
 			appMgr.recordIngressEvent(ing, "DuplicatePath", msg, rsName)
 		}
 	}
+	// The default backend gets the requests no rule matches, so its monitor
+	// has the path '*/', unless a rule already has that path. It is added
+	// after the check above, since it does not conflict with host rules.
+	var defaultRule *ingressRuleData
+	if nil != ing.Spec.DefaultBackend &&
+		nil != ing.Spec.DefaultBackend.Service && "" != cfg.Virtual.PoolName {
+		ruleItem, found := hostToPathMap["*"]
+		if !found {
+			ruleItem = make(ingressPathToRuleMap)
+			hostToPathMap["*"] = ruleItem
+		}
+		if _, found := ruleItem["/"]; !found {
+			defaultRule = &ingressRuleData{
+				svcName: ing.Spec.DefaultBackend.Service.Name,
+				svcPort: ing.Spec.DefaultBackend.Service.Port.Number,
+			}
+			ruleItem["/"] = defaultRule
+		}
+	}
 
 	err := appMgr.assignHealthMonitorsByPath(
 		rsName, ing, hostToPathMap, monitors)
@@ -209,6 +287,10 @@ func (appMgr *Manager) handleMultiServiceHealthMonitors(
 				// associated health monitor.
 				continue
 			}
+			if ruleData == defaultRule {
+				appMgr.assignMonitorToPool(cfg, cfg.Virtual.PoolName, ruleData)
+				continue
+			}
 			for _, pol := range cfg.Policies {
 				if pol.Name != cfg.Virtual.VirtualServerName {
 					continue
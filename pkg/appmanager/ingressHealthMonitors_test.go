@@ -146,7 +146,7 @@ func TestSingleServiceIngressHealthCheck(t *testing.T) {
 
 	// The first test uses an explicit server name
 	assert.Equal(1, resources.CountOf(svcKey))
-	vsCfgFoo, found := resources.Get(svcKey, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found := resources.Get(svcKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 	checkSingleServiceHealthMonitor(t, vsCfgFoo, svcName, svcPort, true)
@@ -162,7 +162,7 @@ func TestSingleServiceIngressHealthCheck(t *testing.T) {
 	r = appMgr.updateIngress(ing)
 	assert.True(r, "Ingress resource should be processed")
 	assert.Equal(1, resources.CountOf(svcKey))
-	vsCfgFoo, found = resources.Get(svcKey, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found = resources.Get(svcKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 	checkSingleServiceHealthMonitor(t, vsCfgFoo, svcName, svcPort, true)
@@ -178,7 +178,7 @@ func TestSingleServiceIngressHealthCheck(t *testing.T) {
 	r = appMgr.updateIngress(ing)
 	assert.True(r, "Ingress resource should be processed")
 	assert.Equal(1, resources.CountOf(svcKey))
-	vsCfgFoo, found = resources.Get(svcKey, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found = resources.Get(svcKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 	checkSingleServiceHealthMonitor(t, vsCfgFoo, svcName, svcPort, true)
@@ -193,7 +193,60 @@ func TestSingleServiceIngressHealthCheck(t *testing.T) {
 	r = appMgr.updateIngress(ing)
 	assert.True(r, "Ingress resource should be processed")
 	assert.Equal(1, resources.CountOf(svcKey))
-	vsCfgFoo, found = resources.Get(svcKey, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found = resources.Get(svcKey, ingressVSName(ing, "http"))
+	assert.True(found)
+	require.NotNil(vsCfgFoo)
+	checkSingleServiceHealthMonitor(t, vsCfgFoo, svcName, svcPort, false)
+
+	// The fifth test sets the protocol and the monitor options
+	ing.ObjectMeta.Annotations[ingHealthMonitorAnnotation] = `[
+		{
+			"path":         "svc1/",
+			"protocol":     "https",
+			"send":         "GET /health HTTP/1.0\\r\\n\\r\\n",
+			"recv":         "200 OK",
+			"recvDisable":  "503",
+			"interval":     5,
+			"timeout":      16,
+			"upInterval":   30,
+			"timeUntilUp":  60,
+			"aliasAddress": "10.2.96.10",
+			"aliasPort":    8443
+		}]`
+	r = appMgr.updateIngress(ing)
+	assert.True(r, "Ingress resource should be processed")
+	vsCfgFoo, found = resources.Get(svcKey, ingressVSName(ing, "http"))
+	assert.True(found)
+	require.NotNil(vsCfgFoo)
+	checkSingleServiceHealthMonitor(t, vsCfgFoo, svcName, svcPort, true)
+	require.Equal(1, len(vsCfgFoo.Monitors))
+	assert.Equal(Monitor{
+		Name:         vsCfgFoo.Pools[0].Name,
+		Partition:    "velcro",
+		Protocol:     "https",
+		Interval:     5,
+		Send:         "GET /health HTTP/1.0\\r\\n\\r\\n",
+		Recv:         "200 OK",
+		RecvDisable:  "503",
+		Timeout:      16,
+		UpInterval:   30,
+		TimeUntilUp:  60,
+		AliasAddress: "10.2.96.10",
+		AliasPort:    8443,
+	}, vsCfgFoo.Monitors[0])
+
+	// The sixth test uses send strings with an icmp monitor (error case)
+	ing.ObjectMeta.Annotations[ingHealthMonitorAnnotation] = `[
+		{
+			"path":     "svc1/",
+			"protocol": "icmp",
+			"send":     "HTTP GET /test1",
+			"interval": 5,
+			"timeout":  10
+		}]`
+	r = appMgr.updateIngress(ing)
+	assert.True(r, "Ingress resource should be processed")
+	vsCfgFoo, found = resources.Get(svcKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 	checkSingleServiceHealthMonitor(t, vsCfgFoo, svcName, svcPort, false)
@@ -394,7 +447,7 @@ func TestMultiServiceIngressHealthCheck(t *testing.T) {
 		ServicePort: int32(svc1Port),
 	}
 	assert.Equal(1, resources.CountOf(svc1Key))
-	vsCfgFoo, found := resources.Get(svc1Key, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found := resources.Get(svc1Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 
@@ -417,7 +470,7 @@ func TestMultiServiceIngressHealthCheck(t *testing.T) {
 		ServicePort: int32(svc2Port),
 	}
 	assert.Equal(1, resources.CountOf(svc2Key))
-	vsCfgBar, found := resources.Get(svc2Key, formatIngressVSName(ing, "http"))
+	vsCfgBar, found := resources.Get(svc2Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBar)
 
@@ -440,7 +493,7 @@ func TestMultiServiceIngressHealthCheck(t *testing.T) {
 		ServicePort: int32(svc3Port),
 	}
 	assert.Equal(1, resources.CountOf(svc3Key))
-	vsCfgBaz, found := resources.Get(svc3Key, formatIngressVSName(ing, "http"))
+	vsCfgBaz, found := resources.Get(svc3Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBaz)
 
@@ -596,7 +649,7 @@ func TestMultiServiceIngressNoPathHealthCheck(t *testing.T) {
 		ServicePort: int32(svc1aPort),
 	}
 	assert.Equal(1, resources.CountOf(svc1aKey))
-	vsCfgFoo, found := resources.Get(svc1aKey, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found := resources.Get(svc1aKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 
@@ -606,7 +659,7 @@ func TestMultiServiceIngressNoPathHealthCheck(t *testing.T) {
 		ServicePort: int32(svc1bPort),
 	}
 	assert.Equal(1, resources.CountOf(svc1bKey))
-	vsCfgBar, found := resources.Get(svc1bKey, formatIngressVSName(ing, "http"))
+	vsCfgBar, found := resources.Get(svc1bKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBar)
 
@@ -616,7 +669,7 @@ func TestMultiServiceIngressNoPathHealthCheck(t *testing.T) {
 		ServicePort: int32(svc2Port),
 	}
 	assert.Equal(1, resources.CountOf(svc2Key))
-	vsCfgBaz, found := resources.Get(svc2Key, formatIngressVSName(ing, "http"))
+	vsCfgBaz, found := resources.Get(svc2Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBaz)
 
@@ -739,7 +792,7 @@ func TestMultiServiceIngressOneHealthCheck(t *testing.T) {
 		ServicePort: int32(svc1aPort),
 	}
 	assert.Equal(1, resources.CountOf(svc1aKey))
-	vsCfgFoo, found := resources.Get(svc1aKey, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found := resources.Get(svc1aKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 
@@ -762,7 +815,7 @@ func TestMultiServiceIngressOneHealthCheck(t *testing.T) {
 		ServicePort: int32(svc1bPort),
 	}
 	assert.Equal(1, resources.CountOf(svc1bKey))
-	vsCfgBar, found := resources.Get(svc1bKey, formatIngressVSName(ing, "http"))
+	vsCfgBar, found := resources.Get(svc1bKey, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBar)
 
@@ -785,7 +838,7 @@ func TestMultiServiceIngressOneHealthCheck(t *testing.T) {
 		ServicePort: int32(svc2Port),
 	}
 	assert.Equal(1, resources.CountOf(svc2Key))
-	vsCfgBaz, found := resources.Get(svc2Key, formatIngressVSName(ing, "http"))
+	vsCfgBaz, found := resources.Get(svc2Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBaz)
 
@@ -908,7 +961,7 @@ func TestMultiServiceIngressHealthCheckNoHost(t *testing.T) {
 		ServicePort: int32(svc1Port),
 	}
 	assert.Equal(1, resources.CountOf(svc1Key))
-	vsCfgFoo, found := resources.Get(svc1Key, formatIngressVSName(ing, "http"))
+	vsCfgFoo, found := resources.Get(svc1Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgFoo)
 
@@ -931,7 +984,7 @@ func TestMultiServiceIngressHealthCheckNoHost(t *testing.T) {
 		ServicePort: int32(svc2Port),
 	}
 	assert.Equal(1, resources.CountOf(svc2Key))
-	vsCfgBar, found := resources.Get(svc2Key, formatIngressVSName(ing, "http"))
+	vsCfgBar, found := resources.Get(svc2Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBar)
 
@@ -954,7 +1007,7 @@ func TestMultiServiceIngressHealthCheckNoHost(t *testing.T) {
 		ServicePort: int32(svc3Port),
 	}
 	assert.Equal(1, resources.CountOf(svc3Key))
-	vsCfgBaz, found := resources.Get(svc3Key, formatIngressVSName(ing, "http"))
+	vsCfgBaz, found := resources.Get(svc3Key, ingressVSName(ing, "http"))
 	assert.True(found)
 	require.NotNil(vsCfgBaz)
 
@@ -962,3 +1015,143 @@ func TestMultiServiceIngressHealthCheckNoHost(t *testing.T) {
 	checkMultiServiceHealthMonitor(t, vsCfgBar, svc2Name, svc2Port, true)
 	checkMultiServiceHealthMonitor(t, vsCfgBaz, svc3Name, svc3Port, true)
 }
+
+func TestMultiServiceIngressDefaultBackendHealthCheck(t *testing.T) {
+	mw := &test.MockWriter{
+		FailStyle: test.Success,
+		Sections:  make(map[string]interface{}),
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	fakeClient := fake.NewSimpleClientset()
+	fakeRecorder := record.NewFakeRecorder(100)
+	require.NotNil(fakeClient, "Mock client should not be nil")
+	require.NotNil(fakeRecorder, "Mock recorder should not be nil")
+	namespace := "default"
+
+	appMgr := newMockAppManager(&Params{
+		KubeClient:    fakeClient,
+		ConfigWriter:  mw,
+		restClient:    test.CreateFakeHTTPClient(),
+		IsNodePort:    false,
+		EventRecorder: fakeRecorder,
+	})
+	err := appMgr.startNonLabelMode([]string{namespace})
+	require.Nil(err)
+	defer appMgr.shutdown()
+
+	host := "foo.com"
+	svc1Name := "svc1"
+	svc1Port := 8080
+	svc1Path := "/foo"
+	svc2Name := "svc2"
+	svc2Port := 9090
+	spec := v1beta1.IngressSpec{
+		Backend: &v1beta1.IngressBackend{
+			ServiceName: svc2Name,
+			ServicePort: intstr.FromInt(svc2Port),
+		},
+		Rules: []v1beta1.IngressRule{
+			{
+				Host: host,
+				IngressRuleValue: v1beta1.IngressRuleValue{
+					HTTP: &v1beta1.HTTPIngressRuleValue{
+						Paths: []v1beta1.HTTPIngressPath{
+							{
+								Path: svc1Path,
+								Backend: v1beta1.IngressBackend{
+									ServiceName: svc1Name,
+									ServicePort: intstr.FromInt(svc1Port),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ing := test.NewIngress("ingress", "1", namespace, spec,
+		map[string]string{
+			"virtual-server.f5.com/ip":        "1.2.3.4",
+			"virtual-server.f5.com/partition": "velcro",
+			"virtual-server.f5.com/health": `[
+				{
+					"path":     "foo.com/foo",
+					"send":     "HTTP GET /health/foo",
+					"interval": 5,
+					"timeout":  10
+				}, {
+					"path":     "*/",
+					"send":     "HTTP GET /health/default",
+					"interval": 5,
+					"timeout":  7
+				}
+			]`,
+		})
+	emptyIps := []string{}
+
+	svc1Ports := []v1.ServicePort{newServicePort(svc1Name, int32(svc1Port))}
+	fooSvc := test.NewService(svc1Name, "1", namespace, v1.ServiceTypeClusterIP,
+		svc1Ports)
+	endpts1 := test.NewEndpoints(svc1Name, "1", namespace,
+		[]string{"10.2.96.0", "10.2.96.1"}, emptyIps,
+		convertSvcPortsToEndpointPorts(svc1Ports))
+	svc2Ports := []v1.ServicePort{newServicePort(svc2Name, int32(svc2Port))}
+	defaultSvc := test.NewService(svc2Name, "1", namespace,
+		v1.ServiceTypeClusterIP, svc2Ports)
+	endpts2 := test.NewEndpoints(svc2Name, "1", namespace,
+		[]string{"10.2.96.2", "10.2.96.3"}, emptyIps,
+		convertSvcPortsToEndpointPorts(svc2Ports))
+
+	r := appMgr.addService(fooSvc)
+	assert.True(r, "Service should be processed")
+	r = appMgr.addEndpoints(endpts1)
+	assert.True(r, "Endpoints should be processed")
+	r = appMgr.addService(defaultSvc)
+	assert.True(r, "Service should be processed")
+	r = appMgr.addEndpoints(endpts2)
+	assert.True(r, "Endpoints should be processed")
+	r = appMgr.addIngress(ing)
+	assert.True(r, "Ingress resource should be processed")
+
+	resources := appMgr.resources()
+	svc1Key := serviceKey{
+		Namespace:   namespace,
+		ServiceName: svc1Name,
+		ServicePort: int32(svc1Port),
+	}
+	svc2Key := serviceKey{
+		Namespace:   namespace,
+		ServiceName: svc2Name,
+		ServicePort: int32(svc2Port),
+	}
+	assert.Equal(1, resources.CountOf(svc1Key))
+	assert.Equal(1, resources.CountOf(svc2Key))
+	cfg, found := resources.Get(svc1Key, ingressVSName(ing, "http"))
+	require.True(found)
+	require.NotNil(cfg)
+
+	// The rule is forwarded by the policy, everything else goes to the
+	// default pool of the virtual server
+	checkMultiServiceHealthMonitor(t, cfg, svc1Name, svc1Port, true)
+	require.Equal(2, len(cfg.Pools))
+	defaultPool := cfg.Pools[1]
+	assert.Equal(svc2Name, defaultPool.ServiceName)
+	assert.Equal(int32(svc2Port), defaultPool.ServicePort)
+	assert.Equal(joinBigipPath("velcro", defaultPool.Name),
+		cfg.Virtual.PoolName)
+	require.Equal(1, len(defaultPool.MonitorNames))
+	assert.Equal(2, len(cfg.Monitors))
+	for _, rule := range cfg.Policies[0].Rules {
+		for _, action := range rule.Actions {
+			assert.NotEqual(cfg.Virtual.PoolName, action.Pool)
+		}
+	}
+
+	// Neither the rules nor the default backend conflict
+	select {
+	case event := <-fakeRecorder.Events:
+		assert.NotContains(event, "conflict")
+	default:
+	}
+}
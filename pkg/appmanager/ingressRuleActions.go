@@ -0,0 +1,232 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Variable the rules of an LTM policy set for the redirect iRule, as the
+// status code and location of the redirect. Policies only redirect with 302.
+const redirectVariable = "k8s_redirect"
+
+const redirectIRuleCode = `when HTTP_REQUEST {
+	if { [info exists ` + redirectVariable + `] } {
+		set redirect $` + redirectVariable + `
+		unset ` + redirectVariable + `
+		HTTP::respond [lindex $redirect 0] Location [lindex $redirect 1]
+	}
+}
+`
+
+// Return the rewrites and redirects set by the annotations of an Ingress.
+// Invalid annotations are left out, and reported in the error.
+func parseIngressRuleActions(
+	annotations map[string]string,
+) (ingressRuleActions, error) {
+	actions := ingressRuleActions{RedirectCode: 302}
+	var errs []string
+	if value, ok := annotations[ingRewriteTargetAnnotation]; ok {
+		if !strings.HasPrefix(value, "/") || !isTclWord(value) {
+			errs = append(errs, fmt.Sprintf(
+				"Invalid rewrite target '%s', must be a path", value))
+		} else {
+			actions.RewriteTarget = value
+		}
+	}
+	if value, ok := annotations[ingRewriteHostAnnotation]; ok {
+		if "" == value || strings.Contains(value, "/") || !isTclWord(value) {
+			errs = append(errs, fmt.Sprintf(
+				"Invalid rewrite host '%s', must be a host name", value))
+		} else {
+			actions.RewriteHost = value
+		}
+	}
+	if value, ok := annotations[ingAppRootAnnotation]; ok {
+		if !strings.HasPrefix(value, "/") || "/" == value || !isTclWord(value) {
+			errs = append(errs, fmt.Sprintf(
+				"Invalid app root '%s', must be a path other than '/'", value))
+		} else {
+			actions.AppRoot = value
+		}
+	}
+	if value, ok := annotations[ingRedirectURLAnnotation]; ok {
+		u, err := url.Parse(value)
+		if nil != err || ("http" != u.Scheme && "https" != u.Scheme) ||
+			"" == u.Host || !isTclWord(value) {
+			errs = append(errs, fmt.Sprintf(
+				"Invalid redirect URL '%s', must be an http or https URL", value))
+		} else {
+			actions.RedirectURL = value
+		}
+	}
+	if value, ok := annotations[ingRedirectCodeAnnotation]; ok {
+		switch value {
+		case "301", "302", "303", "307", "308":
+			actions.RedirectCode, _ = strconv.Atoi(value)
+		default:
+			errs = append(errs, fmt.Sprintf(
+				"Invalid redirect code '%s', must be 301, 302, 303, 307 or 308",
+				value))
+		}
+	}
+	if 0 != len(errs) {
+		return actions, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return actions, nil
+}
+
+// Whether a value can be used in Tcl between braces, and as part of a header
+func isTclWord(value string) bool {
+	return !strings.ContainsAny(value, " \t\r\n\"{}")
+}
+
+// Whether the rules for paths have actions other than forwarding
+func (a ingressRuleActions) empty() bool {
+	return "" == a.RewriteTarget && "" == a.RewriteHost &&
+		"" == a.AppRoot && "" == a.RedirectURL
+}
+
+// Add the actions of an Ingress to the rule for one of its paths. A redirect
+// replaces forwarding the request; rewrites happen before it is forwarded.
+func (a ingressRuleActions) applyTo(rl *Rule) error {
+	if "" != a.RedirectURL {
+		rl.Actions = []*action{redirectAction(a.RedirectURL, a.RedirectCode)}
+		return nil
+	}
+	if "" != a.RewriteTarget {
+		path, err := rewritePath(rl, a.RewriteTarget)
+		if nil != err {
+			return err
+		}
+		rl.Actions = append(rl.Actions, &action{
+			Name:    strconv.Itoa(len(rl.Actions)),
+			HTTPURI: true,
+			Path:    path,
+			Replace: true,
+			Request: true,
+		})
+	}
+	if "" != a.RewriteHost {
+		rl.Actions = append(rl.Actions, &action{
+			Name:     strconv.Itoa(len(rl.Actions)),
+			HTTPHost: true,
+			Replace:  true,
+			Request:  true,
+			Value:    a.RewriteHost,
+		})
+	}
+	return nil
+}
+
+// Rule redirecting requests for the root of a host to the app root
+func appRootRule(host, appRoot, partition string) (*Rule, error) {
+	rl, err := createRule(host+"/", pathMatchExact, "", partition, "")
+	if nil != err {
+		return nil, err
+	}
+	rl.Actions = []*action{redirectAction(appRoot, 302)}
+	return rl, nil
+}
+
+// Action redirecting a request to location. The policy redirects with 302
+// itself, and leaves other codes to the redirect iRule.
+func redirectAction(location string, code int) *action {
+	if 302 == code {
+		return &action{
+			Name:      "0",
+			HttpReply: true,
+			Location:  location,
+			Redirect:  true,
+			Request:   true,
+		}
+	}
+	return &action{
+		Name:        "0",
+		Expression:  fmt.Sprintf("{%d %s}", code, location),
+		Request:     true,
+		SetVariable: true,
+		Tcl:         true,
+		TmName:      redirectVariable,
+	}
+}
+
+// Value of the path a rewrite of the path of a rule replaces the path of the
+// request with. The part of the path the rule matches is replaced with the
+// target, keeping the rest; regular expressions may use their groups in it.
+func rewritePath(rl *Rule, target string) (string, error) {
+	path := ""
+	if i := strings.Index(rl.FullURI, "/"); -1 != i {
+		path = rl.FullURI[i:]
+	}
+	switch rl.PathMatch {
+	case pathMatchExact:
+		return target, nil
+	case pathMatchRegex:
+		if !balancedBraces(path) {
+			return "", fmt.Errorf(
+				"Cannot rewrite regular expression '%s' with unbalanced braces",
+				path)
+		}
+		return fmt.Sprintf("tcl:[regsub -- {%s} [HTTP::path] {%s}]",
+			path, target), nil
+	}
+	prefix := regexp.QuoteMeta(strings.TrimSuffix(path, "/"))
+	replacement := strings.NewReplacer(`\`, `\\`, `&`, `\&`).Replace(
+		strings.TrimSuffix(target, "/") + "/")
+	return fmt.Sprintf("tcl:[regsub -- {^%s/?} [HTTP::path] {%s}]",
+		prefix, replacement), nil
+}
+
+// Whether the braces of a value not escaped by a backslash are balanced, so
+// that it can be used in Tcl between braces
+func balancedBraces(value string) bool {
+	depth := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return 0 == depth
+}
+
+// Add the redirect iRule to a virtual whose policy has redirects that are
+// not 302
+func addRedirectIRule(cfg *ResourceConfig, actions ingressRuleActions) {
+	if "" == actions.RedirectURL || 302 == actions.RedirectCode {
+		return
+	}
+	iRule := IRule{
+		Name:      cfg.Virtual.VirtualServerName + "_redirect",
+		Partition: cfg.Virtual.Partition,
+		Code:      redirectIRuleCode,
+	}
+	cfg.IRules = append(cfg.IRules, iRule)
+	cfg.Virtual.AddIRule(joinBigipPath(iRule.Partition, iRule.Name))
+}
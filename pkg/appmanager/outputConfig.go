@@ -18,9 +18,11 @@ package appmanager
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/metrics"
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 )
 
@@ -31,6 +33,17 @@ func (appMgr *Manager) outputConfig() {
 	appMgr.resources.Unlock()
 }
 
+// Write the Virtual Server configs again, e.g. after the driver restarts.
+// Nothing is written before the initial state has been reached, so a partial
+// config is never sent.
+func (appMgr *Manager) ResendConfig() {
+	appMgr.resources.Lock()
+	defer appMgr.resources.Unlock()
+	if appMgr.initialState {
+		appMgr.outputConfigLocked()
+	}
+}
+
 // Dump out the Virtual Server configs to a file
 // This function MUST be called with the virtualServers
 // lock held.
@@ -44,7 +57,11 @@ func (appMgr *Manager) outputConfigLocked() {
 	// Filter the configs to only those that have active services
 	appMgr.resources.ForEach(func(key serviceKey, cfg *ResourceConfig) {
 		if cfg.MetaData.Active == true {
-			resources.Virtuals = appendVirtual(resources.Virtuals, cfg.Virtual)
+			virtual := cfg.Virtual
+			if nil == virtual.SourceAddrTranslation {
+				virtual.SourceAddrTranslation = appMgr.defaultSnat
+			}
+			resources.Virtuals = appendVirtual(resources.Virtuals, virtual)
 			for _, p := range cfg.Pools {
 				resources.Pools = appendPool(resources.Pools, p)
 			}
@@ -54,6 +71,9 @@ func (appMgr *Manager) outputConfigLocked() {
 			for _, p := range cfg.Policies {
 				resources.Policies = appendPolicy(resources.Policies, p)
 			}
+			for _, r := range cfg.IRules {
+				resources.IRules = appendIRule(resources.IRules, r)
+			}
 		}
 	})
 
@@ -86,12 +106,27 @@ func (appMgr *Manager) outputConfigLocked() {
 
 	if appMgr.vsQueue.Len() == 0 && appMgr.nsQueue.Len() == 0 ||
 		appMgr.initialState == true {
+		metrics.ConfigObjects.WithLabelValues("virtuals").Set(
+			float64(len(resources.Virtuals)))
+		metrics.ConfigObjects.WithLabelValues("pools").Set(
+			float64(len(resources.Pools)))
+		metrics.ConfigObjects.WithLabelValues("monitors").Set(
+			float64(len(resources.Monitors)))
+		metrics.ConfigObjects.WithLabelValues("custom_profiles").Set(
+			float64(len(resources.CustomProfiles)))
+
 		doneCh, errCh, err := appMgr.ConfigWriter().SendSection("resources", resources)
 		if nil != err {
 			log.Warningf("Failed to write Big-IP config data: %v", err)
+			appMgr.lastWriteErr = err
+			metrics.ConfigWrites.WithLabelValues(
+				"resources", metrics.WriteFailure).Inc()
 		} else {
 			select {
 			case <-doneCh:
+				appMgr.lastWriteErr = nil
+				metrics.ConfigWrites.WithLabelValues(
+					"resources", metrics.WriteSuccess).Inc()
 				log.Infof("Wrote %v Virtual Server configs", len(resources.Virtuals))
 				if log.LL_DEBUG == log.GetLogLevel() {
 					// Remove customProfiles from output
@@ -105,8 +140,15 @@ func (appMgr *Manager) outputConfigLocked() {
 				}
 			case e := <-errCh:
 				log.Warningf("Failed to write Big-IP config data: %v", e)
+				appMgr.lastWriteErr = e
+				metrics.ConfigWrites.WithLabelValues(
+					"resources", metrics.WriteFailure).Inc()
 			case <-time.After(time.Second):
 				log.Warning("Did not receive config write response in 1s")
+				appMgr.lastWriteErr = fmt.Errorf(
+					"Did not receive config write response in 1s")
+				metrics.ConfigWrites.WithLabelValues(
+					"resources", metrics.WriteTimeout).Inc()
 			}
 		}
 		appMgr.initialState = true
@@ -115,9 +157,14 @@ func (appMgr *Manager) outputConfigLocked() {
 
 // Only append to the list if it isn't already in the list
 func appendVirtual(rsVirtuals []Virtual, v Virtual) []Virtual {
-	for _, rv := range rsVirtuals {
+	for i, rv := range rsVirtuals {
 		if rv.VirtualServerName == v.VirtualServerName &&
 			rv.Partition == v.Partition {
+			// The virtual of each service of an Ingress only has the
+			// persistence of its own service's session affinity
+			if "" == rv.PersistenceProfile {
+				rsVirtuals[i].PersistenceProfile = v.PersistenceProfile
+			}
 			return rsVirtuals
 		}
 	}
@@ -159,3 +206,14 @@ func appendPolicy(rsPolicies []Policy, p Policy) []Policy {
 	}
 	return append(rsPolicies, p)
 }
+
+// Only append to the list if it isn't already in the list
+func appendIRule(rsIRules []IRule, r IRule) []IRule {
+	for _, rr := range rsIRules {
+		if rr.Name == r.Name &&
+			rr.Partition == r.Partition {
+			return rsIRules
+		}
+	}
+	return append(rsIRules, r)
+}
@@ -0,0 +1,201 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Annotation on a Namespace, or an Ingress, naming the BIG-IP partition for
+// its resources
+const partitionAnnotation = "virtual-server.f5.com/partition"
+
+// BIG-IP partition for the resources in a namespace
+type nsPartition struct {
+	name string
+	// Set when the namespace is mapped to the partition, rather than using
+	// the default partition. Resources in a mapped namespace can not use any
+	// other partition.
+	mapped bool
+	// Set when the namespace is mapped to a partition that is not managed
+	err error
+}
+
+// All partitions the controller manages; the default partition is always
+// managed
+func (appMgr *Manager) managedPartitions() []string {
+	partitions := []string{DEFAULT_PARTITION}
+	for _, p := range appMgr.partitions {
+		if p != DEFAULT_PARTITION {
+			partitions = append(partitions, p)
+		}
+	}
+	return partitions
+}
+
+func (appMgr *Manager) isManagedPartition(partition string) bool {
+	for _, p := range appMgr.managedPartitions() {
+		if p == partition {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the partition for the resources in namespace. The namespace
+// partitions passed to the Manager take precedence over the partition
+// annotation of the namespace; namespaces without either use the default
+// partition.
+func (appMgr *Manager) namespacePartition(namespace string) nsPartition {
+	partition, mapped := appMgr.namespacePartitions[namespace]
+	if !mapped {
+		partition, mapped = appMgr.namespacePartitionAnnotation(namespace)
+	}
+	if !mapped {
+		return nsPartition{name: DEFAULT_PARTITION}
+	}
+	if !appMgr.isManagedPartition(partition) {
+		return nsPartition{
+			name:   partition,
+			mapped: true,
+			err: fmt.Errorf("Namespace '%s' is mapped to partition '%s', "+
+				"which the controller does not manage", namespace, partition),
+		}
+	}
+	return nsPartition{name: partition, mapped: true}
+}
+
+func (appMgr *Manager) namespacePartitionAnnotation(
+	namespace string,
+) (string, bool) {
+	if nil == appMgr.nsPartitionInformer {
+		return "", false
+	}
+	obj, exists, err :=
+		appMgr.nsPartitionInformer.GetIndexer().GetByKey(namespace)
+	if nil != err || !exists {
+		return "", false
+	}
+	return namespacePartitionOf(obj.(*v1.Namespace))
+}
+
+func namespacePartitionOf(ns *v1.Namespace) (string, bool) {
+	partition, ok := ns.ObjectMeta.Annotations[partitionAnnotation]
+	return partition, ok && 0 != len(partition)
+}
+
+// The partition annotation is read for any namespace with resources, so
+// all namespaces are watched, whichever of them the controller watches for
+// resources
+func (appMgr *Manager) newNamespacePartitionInformer(
+	resyncPeriod time.Duration,
+) cache.SharedIndexInformer {
+	namespaces := appMgr.kubeClient.Core().Namespaces()
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return namespaces.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return namespaces.Watch(options)
+			},
+		},
+		&v1.Namespace{},
+		resyncPeriod,
+		cache.Indexers{},
+	)
+	informer.AddEventHandlerWithResyncPeriod(
+		&cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(old, cur interface{}) {
+				appMgr.enqueueNamespacePartition(
+					old.(*v1.Namespace), cur.(*v1.Namespace))
+			},
+		},
+		resyncPeriod,
+	)
+	return informer
+}
+
+func (appMgr *Manager) startAndSyncNamespacePartitionInformer(
+	stopCh <-chan struct{},
+) {
+	go appMgr.nsPartitionInformer.Run(stopCh)
+	cache.WaitForCacheSync(stopCh, appMgr.nsPartitionInformer.HasSynced)
+}
+
+// Queue the services of a namespace when its partition annotation changes,
+// so their resources move to the new partition
+func (appMgr *Manager) enqueueNamespacePartition(old, cur *v1.Namespace) {
+	oldPartition, _ := namespacePartitionOf(old)
+	curPartition, _ := namespacePartitionOf(cur)
+	if oldPartition == curPartition {
+		return
+	}
+	namespace := cur.ObjectMeta.Name
+	appInf, found := appMgr.getNamespaceInformer(namespace)
+	if !found {
+		return
+	}
+	services, err := appInf.svcInformer.GetIndexer().ByIndex(
+		cache.NamespaceIndex, namespace)
+	if nil != err {
+		log.Warningf("Unable to list services in namespace '%s': %v",
+			namespace, err)
+		return
+	}
+	log.Debugf("Partition of namespace '%s' changed from '%s' to '%s'",
+		namespace, oldPartition, curPartition)
+	for _, obj := range services {
+		svc := obj.(*v1.Service)
+		appMgr.vsQueue.Add(serviceQueueKey{
+			Namespace:   namespace,
+			ServiceName: svc.ObjectMeta.Name,
+		})
+	}
+}
+
+// Return the partition for a resource in a namespace that requested a
+// partition, or the namespace partition if requested is empty.
+func (appMgr *Manager) resourcePartition(
+	nsPart nsPartition,
+	requested string,
+) (string, error) {
+	if nil != nsPart.err {
+		return "", nsPart.err
+	}
+	if 0 == len(requested) {
+		return nsPart.name, nil
+	}
+	if nsPart.mapped && requested != nsPart.name {
+		return "", fmt.Errorf("The partition '%s' does not match '%s' that "+
+			"the namespace is mapped to", requested, nsPart.name)
+	}
+	if !appMgr.isManagedPartition(requested) {
+		return "", fmt.Errorf("The partition '%s' is not one of %v that the "+
+			"controller watches for", requested, appMgr.managedPartitions())
+	}
+	return requested, nil
+}
@@ -0,0 +1,143 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+import (
+	"testing"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestNamespacePartition(t *testing.T) {
+	fakeClient := fake.NewSimpleClientset()
+	appMgr := NewManager(&Params{
+		KubeClient:        fakeClient,
+		ManagedPartitions: []string{"velcro", "tenant_a", "tenant_b"},
+		NamespacePartitions: map[string]string{
+			"tenant-a": "tenant_a",
+			// Takes precedence over the namespace annotation
+			"tenant-d": "tenant_a",
+		},
+	})
+	require.NotNil(t, appMgr.nsPartitionInformer)
+	for name, partition := range map[string]string{
+		"tenant-b": "tenant_b",
+		"tenant-c": "unmanaged",
+		"tenant-d": "tenant_b",
+	} {
+		ns := test.NewNamespace(name, "1", map[string]string{})
+		ns.ObjectMeta.Annotations = map[string]string{
+			partitionAnnotation: partition,
+		}
+		require.NoError(t, appMgr.nsPartitionInformer.GetStore().Add(ns))
+	}
+
+	assert.Equal(t, []string{"velcro", "tenant_a", "tenant_b"},
+		appMgr.managedPartitions())
+	assert.Equal(t, nsPartition{name: "velcro"},
+		appMgr.namespacePartition("default"))
+	assert.Equal(t, nsPartition{name: "tenant_a", mapped: true},
+		appMgr.namespacePartition("tenant-a"))
+	assert.Equal(t, nsPartition{name: "tenant_b", mapped: true},
+		appMgr.namespacePartition("tenant-b"))
+	assert.Error(t, appMgr.namespacePartition("tenant-c").err)
+	assert.Equal(t, nsPartition{name: "tenant_a", mapped: true},
+		appMgr.namespacePartition("tenant-d"))
+	// The annotations are only read from the informer cache
+	assert.Empty(t, fakeClient.Actions())
+}
+
+func TestNamespacePartitionChange(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	appMgr := newMockAppManager(&Params{
+		KubeClient:        fake.NewSimpleClientset(),
+		ConfigWriter:      &test.MockWriter{FailStyle: test.Success},
+		restClient:        test.CreateFakeHTTPClient(),
+		ManagedPartitions: []string{"velcro", "tenant_a"},
+	})
+	require.NoError(appMgr.startNonLabelMode([]string{"default", "other"}))
+	defer appMgr.shutdown()
+	for _, svc := range []*v1.Service{
+		test.NewService("foo", "1", "default", "NodePort",
+			[]v1.ServicePort{{Port: 80, NodePort: 30001}}),
+		test.NewService("bar", "1", "default", "NodePort",
+			[]v1.ServicePort{{Port: 80, NodePort: 30002}}),
+		test.NewService("baz", "1", "other", "NodePort",
+			[]v1.ServicePort{{Port: 80, NodePort: 30003}}),
+	} {
+		appInf, _ := appMgr.appMgr.getNamespaceInformer(svc.ObjectMeta.Namespace)
+		require.NoError(appInf.svcInformer.GetStore().Add(svc))
+	}
+
+	old := test.NewNamespace("default", "1", map[string]string{})
+	cur := test.NewNamespace("default", "2", map[string]string{})
+	// Changes that keep the partition do not queue anything
+	appMgr.appMgr.enqueueNamespacePartition(old, cur)
+	assert.Equal(0, appMgr.appMgr.vsQueue.Len())
+
+	cur.ObjectMeta.Annotations = map[string]string{
+		partitionAnnotation: "tenant_a",
+	}
+	appMgr.appMgr.enqueueNamespacePartition(old, cur)
+	require.Equal(2, appMgr.appMgr.vsQueue.Len())
+	var keys []serviceQueueKey
+	for i := 0; i < 2; i++ {
+		key, _ := appMgr.appMgr.vsQueue.Get()
+		keys = append(keys, key.(serviceQueueKey))
+		appMgr.appMgr.vsQueue.Done(key)
+	}
+	assert.Contains(keys, serviceQueueKey{Namespace: "default", ServiceName: "foo"})
+	assert.Contains(keys, serviceQueueKey{Namespace: "default", ServiceName: "bar"})
+}
+
+func TestResourcePartition(t *testing.T) {
+	appMgr := NewManager(&Params{
+		ManagedPartitions: []string{"velcro", "tenant_a"},
+	})
+
+	unmapped := nsPartition{name: "velcro"}
+	partition, err := appMgr.resourcePartition(unmapped, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "velcro", partition)
+	partition, err = appMgr.resourcePartition(unmapped, "tenant_a")
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant_a", partition)
+	_, err = appMgr.resourcePartition(unmapped, "unmanaged")
+	assert.Error(t, err)
+
+	// Resources in a mapped namespace can only use its partition
+	mapped := nsPartition{name: "tenant_a", mapped: true}
+	partition, err = appMgr.resourcePartition(mapped, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant_a", partition)
+	partition, err = appMgr.resourcePartition(mapped, "tenant_a")
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant_a", partition)
+	_, err = appMgr.resourcePartition(mapped, "velcro")
+	assert.Error(t, err)
+
+	invalid := appMgr.namespacePartition("default")
+	invalid.err = assert.AnError
+	_, err = appMgr.resourcePartition(invalid, "")
+	assert.Equal(t, assert.AnError, err)
+}
@@ -18,7 +18,6 @@ package appmanager
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -27,10 +26,11 @@ import (
 
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
+
 	routeapi "github.com/openshift/origin/pkg/route/api"
 	"github.com/xeipuuv/gojsonschema"
 	"k8s.io/client-go/pkg/api/v1"
-	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -48,12 +48,9 @@ const DEFAULT_HTTPS_PORT int32 = 443
 // FIXME: remove this global variable.
 var DEFAULT_PARTITION string
 
-// Indicator to use an F5 schema
+// Indicator to use an F5 schema, embedded in the controller
 const schemaIndicator string = "f5schemadb://"
 
-// Where the schemas reside locally
-const schemaLocal string = "file:///app/vendor/src/f5/schemas/"
-
 // Wrappers around the ssl profile name to simplify its use due to the
 // pointer and nested depth.
 func (v *Virtual) AddFrontendSslProfileName(name string) {
@@ -181,8 +178,14 @@ func formatConfigMapVSName(cm *v1.ConfigMap) string {
 	return fmt.Sprintf("%v_%v", cm.ObjectMeta.Namespace, cm.ObjectMeta.Name)
 }
 
+// format the virtual server and backend name for use in the backend
+// definition of a ConfigMap with several backends
+func formatConfigMapPoolName(vsName, backend string) string {
+	return fmt.Sprintf("%s_%s", vsName, backend)
+}
+
 // format the namespace and name for use in the frontend definition
-func formatIngressVSName(ing *v1beta1.Ingress, protocol string) string {
+func formatIngressVSName(ing *netv1.Ingress, protocol string) string {
 	return fmt.Sprintf("%v_%v-ingress_%s",
 		ing.ObjectMeta.Namespace, ing.ObjectMeta.Name, protocol)
 }
@@ -368,17 +371,11 @@ func parseConfigMap(cm *v1.ConfigMap) (*ResourceConfig, error) {
 			return nil, err
 		}
 		if schemaName, ok := cm.Data["schema"]; ok {
-			// FIXME For now, "f5schemadb" means the schema is local
 			// Trim whitespace and embedded quotes
 			schemaName = strings.TrimSpace(schemaName)
 			schemaName = strings.Trim(schemaName, "\"")
-			if strings.HasPrefix(schemaName, schemaIndicator) {
-				schemaName = strings.Replace(
-					schemaName, schemaIndicator, schemaLocal, 1)
-			}
 			// Load the schema
-			schemaLoader := gojsonschema.NewReferenceLoader(schemaName)
-			schema, err := gojsonschema.NewSchema(schemaLoader)
+			schema, err := getSchema(schemaName)
 			if err != nil {
 				return &cfg, err
 			}
@@ -389,12 +386,19 @@ func parseConfigMap(cm *v1.ConfigMap) (*ResourceConfig, error) {
 				return &cfg, err
 			}
 
-			//Check if we care about the partition specified in the configmap
-			if cfgMap.VirtualServer.Frontend.Partition != DEFAULT_PARTITION {
-				var errStr string = fmt.Sprintf("The partition '%s' in the ConfigMap does not match '%s' that the controller watches for", cfgMap.VirtualServer.Frontend.Partition, DEFAULT_PARTITION)
-				return &cfg, errors.New(errStr)
-			}
 			if result.Valid() {
+				err = validateConfigMapBackends(&cfgMap)
+				if nil != err {
+					return &cfg, err
+				}
+				err = validateConfigMapUdp(&cfgMap)
+				if nil != err {
+					return &cfg, err
+				}
+				err = validateConfigMapPersistence(&cfgMap)
+				if nil != err {
+					return &cfg, err
+				}
 				cfg.Virtual.VirtualServerName = formatConfigMapVSName(cm)
 				copyConfigMap(&cfg, &cfgMap)
 
@@ -420,6 +424,8 @@ func parseConfigMap(cm *v1.ConfigMap) (*ResourceConfig, error) {
 				for _, desc := range result.Errors() {
 					errors = append(errors, desc.String())
 				}
+				// Keep the message the same for the same errors
+				sort.Strings(errors)
 				return &cfg, fmt.Errorf("configMap is not valid, errors: %q", errors)
 			}
 		} else {
@@ -457,53 +463,348 @@ func copyConfigMap(cfg *ResourceConfig, cfgMap *ConfigMap) {
 	cfg.Virtual.IAppOptions = cfgMap.VirtualServer.Frontend.IAppOptions
 	cfg.Virtual.IAppTables = cfgMap.VirtualServer.Frontend.IAppTables
 	cfg.Virtual.IAppVariables = cfgMap.VirtualServer.Frontend.IAppVariables
+	// Existing iRules and policies, referenced by full path
+	for _, irule := range cfgMap.VirtualServer.Frontend.IRules {
+		cfg.Virtual.AddIRule(irule)
+	}
+	for _, policy := range cfgMap.VirtualServer.Frontend.Policies {
+		partition, name := splitBigipPath(policy, false)
+		cfg.Virtual.Policies = append(cfg.Virtual.Policies,
+			nameRef{Name: name, Partition: partition})
+	}
+	if "" != cfgMap.VirtualServer.Frontend.Snat {
+		// The schema checked the value
+		cfg.Virtual.SourceAddrTranslation, _ = ParseSourceAddrTranslation(
+			cfgMap.VirtualServer.Frontend.Snat)
+	}
+	if persist := cfgMap.VirtualServer.Frontend.Persistence; nil != persist {
+		// The schema checked the values
+		cfg.Virtual.PersistenceProfile, _ = parsePersistenceProfile(
+			persist.Default)
+		if "" != persist.Fallback {
+			cfg.Virtual.FallbackPersistenceProfile, _ =
+				parsePersistenceProfile(persist.Fallback)
+		}
+	}
 
+	if 0 != len(cfgMap.VirtualServer.Backends) {
+		copyConfigMapBackends(cfg, cfgMap, balance)
+		return
+	}
+	pool := Pool{
+		Name:            cfg.Virtual.VirtualServerName,
+		Partition:       cfg.Virtual.Partition,
+		Balance:         balance,
+		ServiceName:     cfgMap.VirtualServer.Backend.ServiceName,
+		ServicePort:     cfgMap.VirtualServer.Backend.ServicePort.IntVal,
+		ServicePortName: cfgMap.VirtualServer.Backend.ServicePort.StrVal,
+		PoolMemberAddrs: cfgMap.VirtualServer.Backend.PoolMemberAddrs,
+		MonitorNames: copyConfigMapMonitors(cfg, cfg.Virtual.VirtualServerName,
+			cfgMap.VirtualServer.Backend.HealthMonitors),
+	}
+	cfg.Pools = append(cfg.Pools, pool)
+	cfg.Virtual.PoolName = fmt.Sprintf("/%s/%s", cfg.Virtual.Partition, pool.Name)
+}
+
+// Create a pool for each of the backends of a ConfigMap, and a policy
+// forwarding requests to them by its rules
+func copyConfigMapBackends(cfg *ResourceConfig, cfgMap *ConfigMap, balance string) {
+	vsName := cfg.Virtual.VirtualServerName
+	partition := cfg.Virtual.Partition
+	poolNames := make(map[string]string)
+	for _, backend := range cfgMap.VirtualServer.Backends {
+		poolName := formatConfigMapPoolName(vsName, backend.Name)
+		poolBalance := balance
+		if "" != backend.Balance {
+			poolBalance = backend.Balance
+		}
+		pool := Pool{
+			Name:            poolName,
+			Partition:       partition,
+			Balance:         poolBalance,
+			ServiceName:     backend.ServiceName,
+			ServicePort:     backend.ServicePort.IntVal,
+			ServicePortName: backend.ServicePort.StrVal,
+			PoolMemberAddrs: backend.PoolMemberAddrs,
+			MonitorNames: copyConfigMapMonitors(
+				cfg, poolName, backend.HealthMonitors),
+		}
+		cfg.Pools = append(cfg.Pools, pool)
+		poolNames[backend.Name] = poolName
+	}
+
+	// Without rules, all requests go to the first backend
+	defaultBackend := cfgMap.VirtualServer.DefaultBackend
+	if "" == defaultBackend && 0 == len(cfgMap.VirtualServer.Rules) {
+		defaultBackend = cfgMap.VirtualServer.Backends[0].Name
+	}
+	if "" != defaultBackend {
+		cfg.Virtual.PoolName = fmt.Sprintf("/%s/%s",
+			partition, poolNames[defaultBackend])
+	}
+	if 0 == len(cfgMap.VirtualServer.Rules) {
+		return
+	}
+
+	rlMap := make(ruleMap)
+	wildcards := make(ruleMap)
+	for _, rule := range cfgMap.VirtualServer.Rules {
+		uri := rule.Host + rule.Path
+		// The rules were checked by validateConfigMapBackends
+		rl, _ := createRule(uri, pathMatchPrefix, poolNames[rule.Backend],
+			partition, "")
+		addRequestConditions(rl, rule.Conditions)
+		key := ruleKey(uri, pathMatchPrefix, rule.Conditions)
+		if strings.HasPrefix(uri, "*.") {
+			wildcards[key] = rl
+		} else {
+			rlMap[key] = rl
+		}
+	}
+	cfg.SetPolicy(*createPolicy(*orderRules(rlMap, wildcards), vsName, partition))
+}
+
+// Create the health monitors of a ConfigMap backend for pool poolName, and
+// return their full names
+func copyConfigMapMonitors(
+	cfg *ResourceConfig,
+	poolName string,
+	monitors []Monitor,
+) []string {
 	var monitorNames []string
 	var name string
-	for index, mon := range cfgMap.VirtualServer.Backend.HealthMonitors {
+	for index, mon := range monitors {
 		if index > 0 {
-			name = fmt.Sprintf("%s_%d", cfg.Virtual.VirtualServerName, index)
+			name = fmt.Sprintf("%s_%d", poolName, index)
 		} else {
-			name = fmt.Sprintf("%s", cfg.Virtual.VirtualServerName)
+			name = fmt.Sprintf("%s", poolName)
 		}
 		monitor := Monitor{
-			Name:      name,
-			Partition: cfg.Virtual.Partition,
-			Interval:  mon.Interval,
-			Protocol:  mon.Protocol,
-			Send:      mon.Send,
-			Timeout:   mon.Timeout,
+			Name:         name,
+			Partition:    cfg.Virtual.Partition,
+			Interval:     mon.Interval,
+			Protocol:     mon.Protocol,
+			Send:         mon.Send,
+			Recv:         mon.Recv,
+			RecvDisable:  mon.RecvDisable,
+			Timeout:      mon.Timeout,
+			UpInterval:   mon.UpInterval,
+			TimeUntilUp:  mon.TimeUntilUp,
+			AliasAddress: mon.AliasAddress,
+			AliasPort:    mon.AliasPort,
 		}
 		cfg.Monitors = append(cfg.Monitors, monitor)
 		fullName := fmt.Sprintf("/%s/%s", cfg.Virtual.Partition, name)
 		monitorNames = append(monitorNames, fullName)
 	}
-	pool := Pool{
-		Name:            cfg.Virtual.VirtualServerName,
-		Partition:       cfg.Virtual.Partition,
-		Balance:         balance,
-		ServiceName:     cfgMap.VirtualServer.Backend.ServiceName,
-		ServicePort:     cfgMap.VirtualServer.Backend.ServicePort,
-		PoolMemberAddrs: cfgMap.VirtualServer.Backend.PoolMemberAddrs,
-		MonitorNames:    monitorNames,
+	return monitorNames
+}
+
+// Monitor types that can check the members of a UDP virtual
+var udpMonitorTypes = map[string]bool{
+	"udp":          true,
+	"icmp":         true,
+	"gateway-icmp": true,
+}
+
+// Check a UDP virtual has no SSL profile and only UDP or ICMP monitors
+func validateConfigMapUdp(cfgMap *ConfigMap) error {
+	vs := &cfgMap.VirtualServer
+	if "udp" != vs.Frontend.Mode {
+		return nil
 	}
-	cfg.Pools = append(cfg.Pools, pool)
-	cfg.Virtual.PoolName = fmt.Sprintf("/%s/%s", cfg.Virtual.Partition, pool.Name)
+	if 0 != len(vs.Frontend.GetFrontendSslProfileNames()) {
+		return fmt.Errorf("The sslProfile property is not supported " +
+			"for the udp mode")
+	}
+	backends := append([]configMapBackend{vs.Backend}, vs.Backends...)
+	for _, backend := range backends {
+		for _, mon := range backend.HealthMonitors {
+			if !udpMonitorTypes[mon.Protocol] {
+				return fmt.Errorf("The %s health monitor protocol is not "+
+					"supported for the udp mode", mon.Protocol)
+			}
+		}
+	}
+	return nil
+}
+
+// Check the persistence of a ConfigMap is valid for its mode
+func validateConfigMapPersistence(cfgMap *ConfigMap) error {
+	persist := cfgMap.VirtualServer.Frontend.Persistence
+	if nil == persist {
+		return nil
+	}
+	mode := cfgMap.VirtualServer.Frontend.Mode
+	if "" == mode {
+		mode = DEFAULT_MODE
+	}
+	// The schema checked the values
+	dflt, _ := parsePersistenceProfile(persist.Default)
+	var fallback string
+	if "" != persist.Fallback {
+		fallback, _ = parsePersistenceProfile(persist.Fallback)
+	}
+	return validatePersistence(mode, dflt, fallback)
+}
+
+// Check what the schema can not express about the backends and rules of a
+// ConfigMap
+func validateConfigMapBackends(cfgMap *ConfigMap) error {
+	vs := &cfgMap.VirtualServer
+	if 0 == len(vs.Backends) {
+		if 0 != len(vs.Rules) || "" != vs.DefaultBackend {
+			return fmt.Errorf("The rules and defaultBackend properties " +
+				"require the backends property")
+		}
+		return nil
+	}
+	if "" != vs.Frontend.IApp {
+		return fmt.Errorf("The backends property is not supported for iApps")
+	}
+	if 0 != len(vs.Rules) && "http" != vs.Frontend.Mode {
+		return fmt.Errorf("The rules property requires the http mode")
+	}
+	backends := make(map[string]bool)
+	services := make(map[string]string)
+	for _, backend := range vs.Backends {
+		if backends[backend.Name] {
+			return fmt.Errorf("Duplicate backend '%s'", backend.Name)
+		}
+		backends[backend.Name] = true
+		// Pools are matched to their service by name
+		if other, ok := services[backend.ServiceName]; ok {
+			return fmt.Errorf("Backends '%s' and '%s' both use service '%s'",
+				other, backend.Name, backend.ServiceName)
+		}
+		services[backend.ServiceName] = backend.Name
+	}
+	if "" != vs.DefaultBackend && !backends[vs.DefaultBackend] {
+		return fmt.Errorf("The defaultBackend '%s' is not one of the backends",
+			vs.DefaultBackend)
+	}
+	uris := make(map[string]bool)
+	for _, rule := range vs.Rules {
+		uri := rule.Host + rule.Path
+		if !backends[rule.Backend] {
+			return fmt.Errorf("The rule for '%s' uses unknown backend '%s'",
+				uri, rule.Backend)
+		}
+		key := ruleKey(uri, pathMatchPrefix, rule.Conditions)
+		if uris[key] {
+			return fmt.Errorf("Duplicate rule for '%s'", uri)
+		}
+		uris[key] = true
+		rl, err := createRule(uri, pathMatchPrefix, rule.Backend, "", "")
+		if nil == err {
+			err = addRequestConditions(rl, rule.Conditions)
+		}
+		if nil != err {
+			return fmt.Errorf("Invalid rule for '%s': %v", uri, err)
+		}
+	}
+	return nil
+}
+
+// Return the number of the named port of a Service. A Service that does not
+// exist yet is not an error; its port is resolved when it is added.
+func resolveServicePort(
+	svcIndexer cache.Indexer,
+	namespace string,
+	svcName string,
+	portName string,
+) (int32, error) {
+	if nil == svcIndexer {
+		return 0, nil
+	}
+	obj, found, err := svcIndexer.GetByKey(namespace + "/" + svcName)
+	if nil != err || !found {
+		return 0, nil
+	}
+	svc := obj.(*v1.Service)
+	for _, port := range svc.Spec.Ports {
+		if port.Name == portName {
+			return port.Port, nil
+		}
+	}
+	return 0, fmt.Errorf("Service '%s' has no port named '%s'",
+		svcName, portName)
+}
+
+// Resolve the named Service ports of the pools of a ConfigMap. Pools whose
+// port cannot be resolved keep port 0, which matches no Service port.
+func (rc *ResourceConfig) resolveServicePorts(
+	namespace string,
+	svcIndexer cache.Indexer,
+) error {
+	var firstErr error
+	for i, pool := range rc.Pools {
+		if "" == pool.ServicePortName {
+			continue
+		}
+		port, err := resolveServicePort(
+			svcIndexer, namespace, pool.ServiceName, pool.ServicePortName)
+		if nil != err && nil == firstErr {
+			firstErr = err
+		}
+		rc.Pools[i].ServicePort = port
+	}
+	return firstErr
+}
+
+// Resolve the named Service ports of the backends of an Ingress, so the
+// config is created from port numbers. The backends are replaced rather
+// than changed, since the Ingress shares them with the informer cache.
+func resolveIngressServicePorts(
+	ing *netv1.Ingress,
+	svcIndexer cache.Indexer,
+) []error {
+	var errs []error
+	resolve := func(backend *netv1.IngressBackend) *netv1.IngressBackend {
+		if nil == backend || nil == backend.Service ||
+			"" == backend.Service.Port.Name {
+			return backend
+		}
+		port, err := resolveServicePort(svcIndexer, ing.ObjectMeta.Namespace,
+			backend.Service.Name, backend.Service.Port.Name)
+		if nil != err {
+			errs = append(errs, err)
+		}
+		svc := *backend.Service
+		svc.Port.Number = port
+		return &netv1.IngressBackend{Service: &svc}
+	}
+
+	ing.Spec.DefaultBackend = resolve(ing.Spec.DefaultBackend)
+	if nil == ing.Spec.Rules {
+		return errs
+	}
+	rules := make([]netv1.IngressRule, len(ing.Spec.Rules))
+	for i, rule := range ing.Spec.Rules {
+		rules[i] = rule
+		if nil == rule.HTTP {
+			continue
+		}
+		paths := make([]netv1.HTTPIngressPath, len(rule.HTTP.Paths))
+		for j, path := range rule.HTTP.Paths {
+			paths[j] = path
+			paths[j].Backend = *resolve(&path.Backend)
+		}
+		rules[i].HTTP = &netv1.HTTPIngressRuleValue{Paths: paths}
+	}
+	ing.Spec.Rules = rules
+	return errs
 }
 
 // Create a ResourceConfig based on an Ingress resource config
-func createRSConfigFromIngress(ing *v1beta1.Ingress,
+func createRSConfigFromIngress(ing *netv1.Ingress,
+	partition string,
 	ns string,
 	svcIndexer cache.Indexer,
 	pStruct portStruct,
 ) *ResourceConfig {
 	var cfg ResourceConfig
 
-	if class, ok := ing.ObjectMeta.Annotations["kubernetes.io/ingress.class"]; ok == true {
-		if class != "f5" {
-			return nil
-		}
-	}
 	cfg.Virtual.VirtualServerName = formatIngressVSName(ing, pStruct.protocol)
 	cfg.Virtual.Mode = "http"
 	var balance string
@@ -515,11 +816,7 @@ func createRSConfigFromIngress(ing *v1beta1.Ingress,
 	cfg.Virtual.VirtualAddress = &virtualAddress{}
 	cfg.Virtual.VirtualAddress.Port = pStruct.port
 
-	if partition, ok := ing.ObjectMeta.Annotations["virtual-server.f5.com/partition"]; ok == true {
-		cfg.Virtual.Partition = partition
-	} else {
-		cfg.Virtual.Partition = DEFAULT_PARTITION
-	}
+	cfg.Virtual.Partition = partition
 
 	if addr, ok := ing.ObjectMeta.Annotations["virtual-server.f5.com/ip"]; ok == true {
 		cfg.Virtual.VirtualAddress.BindAddr = addr
@@ -528,16 +825,21 @@ func createRSConfigFromIngress(ing *v1beta1.Ingress,
 			ing.ObjectMeta.Name)
 	}
 
-	if nil != ing.Spec.Rules { //multi-service
+	if 0 != len(ing.Spec.Rules) { //multi-service
 		index := 0
 		poolName := cfg.Virtual.VirtualServerName
 		for _, rule := range ing.Spec.Rules {
 			if nil != rule.IngressRuleValue.HTTP {
 				for _, path := range rule.IngressRuleValue.HTTP.Paths {
+					backend := path.Backend.Service
+					if nil == backend {
+						// Resource backends are not supported
+						continue
+					}
 					exists := false
 					for _, pl := range cfg.Pools {
-						if pl.ServiceName == path.Backend.ServiceName &&
-							pl.ServicePort == path.Backend.ServicePort.IntVal {
+						if pl.ServiceName == backend.Name &&
+							pl.ServicePort == backend.Port.Number {
 							exists = true
 						}
 					}
@@ -545,7 +847,7 @@ func createRSConfigFromIngress(ing *v1beta1.Ingress,
 						continue
 					}
 					// If service doesn't exist, don't create a pool for it
-					sKey := ns + "/" + path.Backend.ServiceName
+					sKey := ns + "/" + backend.Name
 					_, svcFound, _ := svcIndexer.GetByKey(sKey)
 					if !svcFound {
 						index++
@@ -558,27 +860,81 @@ func createRSConfigFromIngress(ing *v1beta1.Ingress,
 						Name:        poolName,
 						Partition:   cfg.Virtual.Partition,
 						Balance:     balance,
-						ServiceName: path.Backend.ServiceName,
-						ServicePort: path.Backend.ServicePort.IntVal,
+						ServiceName: backend.Name,
+						ServicePort: backend.Port.Number,
 					}
 					cfg.Pools = append(cfg.Pools, pool)
 					index++
 				}
 			}
 		}
-		rules := processIngressRules(&ing.Spec, cfg.Pools, cfg.Virtual.Partition)
+		// Requests that match no rule go to the default backend, which is
+		// added after the rule backends so the rules use their own pools
+		if nil != ing.Spec.DefaultBackend &&
+			nil != ing.Spec.DefaultBackend.Service {
+			backend := ing.Spec.DefaultBackend.Service
+			plIdx := -1
+			for i, pl := range cfg.Pools {
+				if pl.ServiceName == backend.Name &&
+					pl.ServicePort == backend.Port.Number {
+					plIdx = i
+					break
+				}
+			}
+			_, svcFound, _ := svcIndexer.GetByKey(ns + "/" + backend.Name)
+			if -1 == plIdx && svcFound {
+				cfg.Pools = append(cfg.Pools, Pool{
+					Name: fmt.Sprintf("%s_default",
+						cfg.Virtual.VirtualServerName),
+					Partition:   cfg.Virtual.Partition,
+					Balance:     balance,
+					ServiceName: backend.Name,
+					ServicePort: backend.Port.Number,
+				})
+				plIdx = len(cfg.Pools) - 1
+			}
+			if -1 != plIdx {
+				cfg.Virtual.PoolName = fmt.Sprintf("/%s/%s",
+					cfg.Virtual.Partition, cfg.Pools[plIdx].Name)
+			}
+		}
+		// An invalid path match, conditions or actions are reported when the
+		// Ingress is synced
+		match, _ := parsePathMatch(
+			ing.ObjectMeta.Annotations[ingPathMatchAnnotation])
+		var conditions []ingressRuleConditions
+		if value, ok := ing.ObjectMeta.Annotations[ingConditionsAnnotation]; ok {
+			conditions, _ = parseIngressConditions(value)
+		}
+		actions, _ := parseIngressRuleActions(ing.ObjectMeta.Annotations)
+		rules := processIngressRules(&ing.Spec, cfg.Pools,
+			cfg.Virtual.Partition, match, conditions, actions)
 		plcy := createPolicy(*rules, cfg.Virtual.VirtualServerName, cfg.Virtual.Partition)
 		cfg.SetPolicy(*plcy)
-	} else { // single-service
+		addRedirectIRule(&cfg, actions)
+	} else if nil != ing.Spec.DefaultBackend &&
+		nil != ing.Spec.DefaultBackend.Service { // single-service
 		pool := Pool{
 			Name:        cfg.Virtual.VirtualServerName,
 			Partition:   cfg.Virtual.Partition,
 			Balance:     balance,
-			ServiceName: ing.Spec.Backend.ServiceName,
-			ServicePort: ing.Spec.Backend.ServicePort.IntVal,
+			ServiceName: ing.Spec.DefaultBackend.Service.Name,
+			ServicePort: ing.Spec.DefaultBackend.Service.Port.Number,
 		}
 		cfg.Pools = append(cfg.Pools, pool)
 		cfg.Virtual.PoolName = fmt.Sprintf("/%s/%s", cfg.Virtual.Partition, pool.Name)
+		// Rewrites and redirects need a policy to apply them
+		actions, _ := parseIngressRuleActions(ing.ObjectMeta.Annotations)
+		if !actions.empty() {
+			rules := processIngressDefaultRules(pool.Name,
+				cfg.Virtual.Partition, actions)
+			plcy := createPolicy(*rules, cfg.Virtual.VirtualServerName,
+				cfg.Virtual.Partition)
+			cfg.SetPolicy(*plcy)
+			addRedirectIRule(&cfg, actions)
+		}
+	} else {
+		return nil
 	}
 
 	return &cfg
@@ -586,6 +942,7 @@ func createRSConfigFromIngress(ing *v1beta1.Ingress,
 
 func createRSConfigFromRoute(
 	route *routeapi.Route,
+	partition string,
 	resources Resources,
 	routeConfig RouteConfig,
 	pStruct portStruct,
@@ -602,19 +959,20 @@ func createRSConfigFromRoute(
 		rsName = formatRouteVSName(route, "https")
 	}
 	passThroughRuleName := fmt.Sprintf("/%s/%s",
-		DEFAULT_PARTITION, sslPassthroughIRuleName)
+		partition, sslPassthroughIRuleName)
 
 	// Create the pool
 	pool := Pool{
 		Name:        formatRoutePoolName(route),
-		Partition:   DEFAULT_PARTITION,
+		Partition:   partition,
 		Balance:     DEFAULT_BALANCE,
 		ServiceName: route.Spec.To.Name,
 		ServicePort: backendPort,
 	}
 	// Create the rule
 	uri := route.Spec.Host + route.Spec.Path
-	rule, err := createRule(uri, pool.Name, pool.Partition, formatRouteRuleName(route))
+	rule, err := createRule(uri, pathMatchPrefix, pool.Name, pool.Partition,
+		formatRouteRuleName(route))
 	if nil != err {
 		err = fmt.Errorf("Error configuring rule for Route %s: %v", route.ObjectMeta.Name, err)
 		return rsCfg, err
@@ -623,11 +981,20 @@ func createRSConfigFromRoute(
 
 	resources.Lock()
 	defer resources.Unlock()
-	// Check to see if we have any Routes already saved for this VS type
+	// Check to see if we have any Routes already saved for this VS type.
+	// Configs from before the namespace moved to another partition are
+	// not reused.
+	var existing *ResourceConfig
 	cfgs, _ := resources.GetAllWithName(rsName)
-	if len(cfgs) > 0 {
+	for _, cfg := range cfgs {
+		if cfg.Virtual.Partition == partition {
+			existing = cfg
+			break
+		}
+	}
+	if nil != existing {
 		// If we do, use an existing config
-		rsCfg = *cfgs[0]
+		rsCfg = *existing
 		// If this pool doesn't already exist, add it
 		var found bool
 		for _, pl := range rsCfg.Pools {
@@ -688,7 +1055,7 @@ func createRSConfigFromRoute(
 		rsCfg.MetaData.ResourceType = "route"
 		rsCfg.Virtual.VirtualServerName = rsName
 		rsCfg.Virtual.Mode = "http"
-		rsCfg.Virtual.Partition = DEFAULT_PARTITION
+		rsCfg.Virtual.Partition = partition
 		rsCfg.Virtual.VirtualAddress = &virtualAddress{}
 		rsCfg.Virtual.VirtualAddress.Port = pStruct.port
 		if routeConfig.RouteVSAddr != "" {
@@ -866,6 +1233,73 @@ func splitBigipPath(path string, keepSlash bool) (partition, objName string) {
 	return
 }
 
+// Parse the SNAT setting of a virtual server: automap, none or the full
+// path of a SNAT pool
+func ParseSourceAddrTranslation(value string) (*SourceAddrTranslation, error) {
+	switch value {
+	case "automap", "none":
+		return &SourceAddrTranslation{Type: value}, nil
+	}
+	partition, name := splitBigipPath(value, false)
+	if !strings.HasPrefix(value, "/") || "" == partition || "" == name {
+		return nil, fmt.Errorf("SNAT '%s' is not automap, none or the full "+
+			"path of a SNAT pool", value)
+	}
+	return &SourceAddrTranslation{Type: "snat", Pool: value}, nil
+}
+
+// Built-in persistence profiles, which exist on every BIG-IP
+var persistenceProfiles = map[string]string{
+	"cookie":              "/Common/cookie",
+	"source-address":      "/Common/source_addr",
+	"destination-address": "/Common/dest_addr",
+}
+
+// Parse a persistence profile: the name of a built-in profile or the full
+// path of a profile
+func parsePersistenceProfile(value string) (string, error) {
+	if profile, ok := persistenceProfiles[value]; ok {
+		return profile, nil
+	}
+	partition, name := splitBigipPath(value, false)
+	if !strings.HasPrefix(value, "/") || "" == partition || "" == name {
+		return "", fmt.Errorf("Persistence '%s' is not cookie, "+
+			"source-address, destination-address or the full path of a "+
+			"profile", value)
+	}
+	return value, nil
+}
+
+// Parse the persistence annotation of an Ingress: the default persistence
+// profile, optionally followed by a comma and the fallback
+func parsePersistenceAnnotation(value string) (string, string, error) {
+	var profiles []string
+	for _, item := range strings.SplitN(value, ",", 2) {
+		profile, err := parsePersistenceProfile(strings.TrimSpace(item))
+		if nil != err {
+			return "", "", err
+		}
+		profiles = append(profiles, profile)
+	}
+	if 1 == len(profiles) {
+		return profiles[0], "", nil
+	}
+	return profiles[0], profiles[1], nil
+}
+
+// Cookie persistence needs the HTTP profile, and can not be a fallback. The
+// type of other profiles is not known, so they are left to the BIG-IP.
+func validatePersistence(mode, dflt, fallback string) error {
+	cookie := persistenceProfiles["cookie"]
+	if cookie == dflt && "http" != mode {
+		return fmt.Errorf("Cookie persistence requires the http mode")
+	}
+	if cookie == fallback {
+		return fmt.Errorf("Cookie persistence can not be a fallback")
+	}
+	return nil
+}
+
 func joinBigipPath(partition, objName string) string {
 	if objName == "" {
 		return ""
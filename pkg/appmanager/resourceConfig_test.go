@@ -19,15 +19,20 @@ package appmanager
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/test"
 
 	routeapi "github.com/openshift/origin/pkg/route/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/tools/cache"
 )
 
 type simpleTestConfig struct {
@@ -452,7 +457,8 @@ func TestIngressConfiguration(t *testing.T) {
 		protocol: "http",
 		port:     80,
 	}
-	cfg := createRSConfigFromIngress(ingress, namespace, nil, ps)
+	cfg := createRSConfigFromIngress(netv1.ConvertV1beta1Ingress(ingress),
+		"velcro", namespace, nil, ps)
 	require.Equal("round-robin", cfg.Pools[0].Balance)
 	require.Equal("http", cfg.Virtual.Mode)
 	require.Equal("velcro", cfg.Virtual.Partition)
@@ -471,18 +477,408 @@ func TestIngressConfiguration(t *testing.T) {
 		protocol: "http",
 		port:     100,
 	}
-	cfg = createRSConfigFromIngress(ingress, namespace, nil, ps)
+	cfg = createRSConfigFromIngress(netv1.ConvertV1beta1Ingress(ingress),
+		"velcro", namespace, nil, ps)
 	require.Equal("foobar", cfg.Pools[0].Balance)
 	require.Equal(int32(100), cfg.Virtual.VirtualAddress.Port)
 
-	ingress = test.NewIngress("ingress", "1", namespace, ingressConfig,
+	// Without a default backend or rules there is nothing to route to
+	ingress = test.NewIngress("ingress", "1", namespace, v1beta1.IngressSpec{},
 		map[string]string{
-			"kubernetes.io/ingress.class": "notf5",
+			"virtual-server.f5.com/ip": "1.2.3.4",
 		})
-	cfg = createRSConfigFromIngress(ingress, namespace, nil, ps)
+	cfg = createRSConfigFromIngress(netv1.ConvertV1beta1Ingress(ingress),
+		"velcro", namespace, nil, ps)
 	require.Nil(cfg)
 }
 
+func TestIngressDefaultBackendSameService(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+	svcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	svcIndexer.Add(test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{
+			{Port: 80, NodePort: 30001},
+			{Port: 8080, NodePort: 30002},
+		}))
+	ps := portStruct{
+		protocol: "http",
+		port:     80,
+	}
+	backend := func(port int32) *netv1.IngressBackend {
+		return &netv1.IngressBackend{
+			Service: &netv1.IngressServiceBackend{
+				Name: "foo",
+				Port: netv1.ServiceBackendPort{Number: port},
+			},
+		}
+	}
+	ing := test.NewNetworkingIngress("ingress", "1", namespace,
+		netv1.IngressSpec{
+			DefaultBackend: backend(8080),
+			Rules: []netv1.IngressRule{
+				{
+					Host: "a.com",
+					IngressRuleValue: netv1.IngressRuleValue{
+						HTTP: &netv1.HTTPIngressRuleValue{
+							Paths: []netv1.HTTPIngressPath{
+								{Path: "/api", Backend: *backend(80)},
+							},
+						},
+					},
+				},
+			},
+		}, map[string]string{"virtual-server.f5.com/ip": "1.2.3.4"})
+
+	// The rule uses the pool for its port, not the default backend's pool
+	// for the same Service
+	cfg := createRSConfigFromIngress(ing, "velcro", namespace, svcIndexer, ps)
+	require.NotNil(cfg)
+	require.Equal(2, len(cfg.Pools))
+	assert.Equal(int32(80), cfg.Pools[0].ServicePort)
+	assert.Equal(int32(8080), cfg.Pools[1].ServicePort)
+	assert.Equal("/velcro/default_ingress-ingress_http_default",
+		cfg.Virtual.PoolName)
+	require.Equal(1, len(cfg.Policies))
+	require.Equal(1, len(cfg.Policies[0].Rules))
+	assert.Equal("/velcro/default_ingress-ingress_http",
+		cfg.Policies[0].Rules[0].Actions[0].Pool)
+}
+
+func TestIngressPathMatch(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+	svcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	svcIndexer.Add(test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 30001}}))
+	ps := portStruct{
+		protocol: "http",
+		port:     80,
+	}
+	exact := netv1.PathTypeExact
+	prefix := netv1.PathTypePrefix
+	backend := netv1.IngressBackend{
+		Service: &netv1.IngressServiceBackend{
+			Name: "foo",
+			Port: netv1.ServiceBackendPort{Number: 80},
+		},
+	}
+	newIngress := func(
+		paths []netv1.HTTPIngressPath,
+		annotations map[string]string,
+	) *netv1.Ingress {
+		annotations["virtual-server.f5.com/ip"] = "1.2.3.4"
+		return test.NewNetworkingIngress("ingress", "1", namespace,
+			netv1.IngressSpec{
+				Rules: []netv1.IngressRule{
+					{
+						Host: "foo.com",
+						IngressRuleValue: netv1.IngressRuleValue{
+							HTTP: &netv1.HTTPIngressRuleValue{Paths: paths},
+						},
+					},
+				},
+			}, annotations)
+	}
+
+	// The pathType decides, unless it is ImplementationSpecific
+	ing := newIngress([]netv1.HTTPIngressPath{
+		{Path: "/api", PathType: &prefix, Backend: backend},
+		{Path: "/api", PathType: &exact, Backend: backend},
+		{Path: "/api/v1", Backend: backend},
+	}, map[string]string{ingPathMatchAnnotation: "starts-with"})
+	cfg := createRSConfigFromIngress(ing, "velcro", namespace, svcIndexer, ps)
+	require.NotNil(cfg)
+	require.Equal(1, len(cfg.Policies))
+	rules := cfg.Policies[0].Rules
+	require.Equal(3, len(rules))
+	expected := []struct {
+		match pathMatch
+		value string
+	}{
+		{pathMatchStartsWith, "/api/v1"},
+		{pathMatchExact, "/api"},
+		{pathMatchPrefix, "api"},
+	}
+	for i, exp := range expected {
+		assert.Equal(strconv.Itoa(i), rules[i].Name)
+		assert.Equal(exp.match, rules[i].PathMatch)
+		require.Equal(2, len(rules[i].Conditions))
+		cond := rules[i].Conditions[1]
+		assert.True(cond.HTTPURI)
+		assert.Equal([]string{exp.value}, cond.Values)
+		assert.Equal(pathMatchStartsWith == exp.match, cond.StartsWith)
+		assert.Equal(pathMatchStartsWith != exp.match, cond.Equals)
+		assert.Equal(pathMatchPrefix == exp.match, cond.PathSegment)
+		assert.Equal(pathMatchPrefix != exp.match, cond.Path)
+	}
+
+	// Regular expressions match after all other paths, and invalid ones
+	// have no rule
+	ing = newIngress([]netv1.HTTPIngressPath{
+		{Path: "/v[0-9]+/.*", Backend: backend},
+		{Path: "/(", Backend: backend},
+		{Path: "/", PathType: &prefix, Backend: backend},
+	}, map[string]string{ingPathMatchAnnotation: "regex"})
+	cfg = createRSConfigFromIngress(ing, "velcro", namespace, svcIndexer, ps)
+	require.NotNil(cfg)
+	rules = cfg.Policies[0].Rules
+	require.Equal(2, len(rules))
+	assert.Equal(pathMatchPrefix, rules[0].PathMatch)
+	assert.Equal(1, len(rules[0].Conditions))
+	assert.Equal(pathMatchRegex, rules[1].PathMatch)
+	require.Equal(2, len(rules[1].Conditions))
+	assert.True(rules[1].Conditions[1].Matches)
+	assert.Equal([]string{"/v[0-9]+/.*"}, rules[1].Conditions[1].Values)
+
+	match, err := parsePathMatch("")
+	assert.Nil(err)
+	assert.Equal(pathMatchPrefix, match)
+	match, err = parsePathMatch("exact")
+	assert.Nil(err)
+	assert.Equal(pathMatchExact, match)
+	match, err = parsePathMatch("suffix")
+	assert.NotNil(err)
+	assert.Equal(pathMatchPrefix, match)
+}
+
+func TestIngressRuleConditions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+	svcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range []string{"web", "mobile"} {
+		svcIndexer.Add(test.NewService(name, "1", namespace, "NodePort",
+			[]v1.ServicePort{{Port: 80, NodePort: 30001}}))
+	}
+	ps := portStruct{
+		protocol: "http",
+		port:     80,
+	}
+	backend := func(name string) netv1.IngressBackend {
+		return netv1.IngressBackend{
+			Service: &netv1.IngressServiceBackend{
+				Name: name,
+				Port: netv1.ServiceBackendPort{Number: 80},
+			},
+		}
+	}
+	ing := test.NewNetworkingIngress("ingress", "1", namespace,
+		netv1.IngressSpec{
+			Rules: []netv1.IngressRule{
+				{
+					Host: "foo.com",
+					IngressRuleValue: netv1.IngressRuleValue{
+						HTTP: &netv1.HTTPIngressRuleValue{
+							Paths: []netv1.HTTPIngressPath{
+								{Path: "/", Backend: backend("web")},
+								{Path: "/", Backend: backend("mobile")},
+								{Path: "/api", Backend: backend("mobile")},
+							},
+						},
+					},
+				},
+			},
+		}, map[string]string{
+			"virtual-server.f5.com/ip": "1.2.3.4",
+			ingConditionsAnnotation: `[
+				{
+					"path": "foo.com/",
+					"serviceName": "mobile",
+					"conditions": [ {
+						"type": "header",
+						"name": "User-Agent",
+						"operator": "contains",
+						"values": [ "Mobile" ]
+					} ]
+				}, {
+					"path": "*/api",
+					"conditions": [ { "type": "body", "values": [ "v1" ] } ]
+				}
+			]`,
+		})
+	_, err := parseIngressConditions(
+		ing.ObjectMeta.Annotations[ingConditionsAnnotation])
+	assert.Error(err)
+
+	// The rule with the invalid condition is skipped, and the rule with
+	// the condition matches before the rule for the same path without it
+	cfg := createRSConfigFromIngress(ing, "velcro", namespace, svcIndexer, ps)
+	require.NotNil(cfg)
+	require.Equal(1, len(cfg.Policies))
+	rules := cfg.Policies[0].Rules
+	require.Equal(2, len(rules))
+	serviceOf := func(rl *Rule) string {
+		for _, pool := range cfg.Pools {
+			if joinBigipPath(pool.Partition, pool.Name) == rl.Actions[0].Pool {
+				return pool.ServiceName
+			}
+		}
+		return ""
+	}
+	assert.Equal("mobile", serviceOf(rules[0]))
+	require.Equal(2, len(rules[0].Conditions))
+	header := rules[0].Conditions[1]
+	assert.Equal("1", header.Name)
+	assert.True(header.HTTPHeader)
+	assert.True(header.Contains)
+	assert.Equal("User-Agent", header.TmName)
+	assert.Equal("web", serviceOf(rules[1]))
+	assert.Equal(1, len(rules[1].Conditions))
+
+	_, err = parseIngressConditions("not json")
+	assert.Error(err)
+}
+
+func TestIngressRuleActions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+	svcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	svcIndexer.Add(test.NewService("web", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Port: 80, NodePort: 30001}}))
+	ps := portStruct{
+		protocol: "http",
+		port:     80,
+	}
+	backend := netv1.IngressBackend{
+		Service: &netv1.IngressServiceBackend{
+			Name: "web",
+			Port: netv1.ServiceBackendPort{Number: 80},
+		},
+	}
+	exact := netv1.PathTypeExact
+	spec := netv1.IngressSpec{
+		Rules: []netv1.IngressRule{
+			{
+				Host: "foo.com",
+				IngressRuleValue: netv1.IngressRuleValue{
+					HTTP: &netv1.HTTPIngressRuleValue{
+						Paths: []netv1.HTTPIngressPath{
+							{Path: "/app", Backend: backend},
+							{Path: "/login", PathType: &exact, Backend: backend},
+						},
+					},
+				},
+			},
+		},
+	}
+	ruleFor := func(cfg *ResourceConfig, uri string) *Rule {
+		for _, rl := range cfg.Policies[0].Rules {
+			if rl.FullURI == uri {
+				return rl
+			}
+		}
+		return nil
+	}
+
+	// Rewrites are added to the rules for the paths, and the root of the
+	// host redirects to the app root
+	ing := test.NewNetworkingIngress("ingress", "1", namespace, spec,
+		map[string]string{
+			"virtual-server.f5.com/ip": "1.2.3.4",
+			ingRewriteTargetAnnotation: "/",
+			ingRewriteHostAnnotation:   "web.internal",
+			ingAppRootAnnotation:       "/app",
+		})
+	cfg := createRSConfigFromIngress(ing, "velcro", namespace, svcIndexer, ps)
+	require.NotNil(cfg)
+	require.Equal(1, len(cfg.Policies))
+	require.Equal(3, len(cfg.Policies[0].Rules))
+	rl := ruleFor(cfg, "foo.com/app")
+	require.NotNil(rl)
+	require.Equal(3, len(rl.Actions))
+	assert.True(rl.Actions[0].Forward)
+	assert.Equal("1", rl.Actions[1].Name)
+	assert.True(rl.Actions[1].HTTPURI)
+	assert.True(rl.Actions[1].Replace)
+	assert.Equal("tcl:[regsub -- {^/app/?} [HTTP::path] {/}]",
+		rl.Actions[1].Path)
+	assert.Equal("2", rl.Actions[2].Name)
+	assert.True(rl.Actions[2].HTTPHost)
+	assert.Equal("web.internal", rl.Actions[2].Value)
+	rl = ruleFor(cfg, "foo.com/login")
+	require.NotNil(rl)
+	require.Equal(3, len(rl.Actions))
+	assert.Equal("/", rl.Actions[1].Path)
+	rl = ruleFor(cfg, "foo.com/")
+	require.NotNil(rl)
+	assert.Equal(2, rl.Ordinal)
+	require.Equal(1, len(rl.Actions))
+	assert.True(rl.Actions[0].Redirect)
+	assert.Equal("/app", rl.Actions[0].Location)
+	require.Equal(2, len(rl.Conditions))
+	assert.True(rl.Conditions[1].Equals)
+	assert.Equal([]string{"/"}, rl.Conditions[1].Values)
+	assert.Equal(0, len(cfg.IRules))
+
+	// A redirect replaces forwarding, and redirects that are not 302 use
+	// the redirect iRule
+	ing.ObjectMeta.Annotations = map[string]string{
+		"virtual-server.f5.com/ip": "1.2.3.4",
+		ingAppRootAnnotation:       "/app",
+		ingRedirectURLAnnotation:   "https://example.com/",
+		ingRedirectCodeAnnotation:  "301",
+	}
+	cfg = createRSConfigFromIngress(ing, "velcro", namespace, svcIndexer, ps)
+	require.NotNil(cfg)
+	require.Equal(2, len(cfg.Policies[0].Rules))
+	for _, rl := range cfg.Policies[0].Rules {
+		require.Equal(1, len(rl.Actions))
+		assert.True(rl.Actions[0].Tcl)
+		assert.True(rl.Actions[0].SetVariable)
+		assert.Equal(redirectVariable, rl.Actions[0].TmName)
+		assert.Equal("{301 https://example.com/}", rl.Actions[0].Expression)
+	}
+	require.Equal(1, len(cfg.IRules))
+	assert.Equal(redirectIRuleCode, cfg.IRules[0].Code)
+	assert.Equal([]string{"/velcro/default_ingress-ingress_http_redirect"},
+		cfg.Virtual.IRules)
+
+	// Ingresses with only a default backend get a rule for every request
+	ing = test.NewNetworkingIngress("ingress", "1", namespace,
+		netv1.IngressSpec{DefaultBackend: &backend}, map[string]string{
+			"virtual-server.f5.com/ip": "1.2.3.4",
+			ingRewriteHostAnnotation:   "web.internal",
+		})
+	cfg = createRSConfigFromIngress(ing, "velcro", namespace, svcIndexer, ps)
+	require.NotNil(cfg)
+	require.Equal(1, len(cfg.Policies))
+	require.Equal(1, len(cfg.Policies[0].Rules))
+	rl = cfg.Policies[0].Rules[0]
+	assert.Equal(0, len(rl.Conditions))
+	require.Equal(2, len(rl.Actions))
+	assert.Equal("/velcro/default_ingress-ingress_http", rl.Actions[0].Pool)
+	assert.Equal("web.internal", rl.Actions[1].Value)
+
+	// Invalid annotations are left out
+	actions, err := parseIngressRuleActions(map[string]string{
+		ingRewriteTargetAnnotation: "app",
+		ingRewriteHostAnnotation:   "web internal",
+		ingAppRootAnnotation:       "/",
+		ingRedirectURLAnnotation:   "example.com",
+		ingRedirectCodeAnnotation:  "200",
+	})
+	assert.Error(err)
+	assert.True(actions.empty())
+	assert.Equal(302, actions.RedirectCode)
+
+	// Regular expressions rewrite with their groups
+	path, err := rewritePath(&Rule{
+		FullURI:   `foo.com/v[0-9]{1,2}/(.*)`,
+		PathMatch: pathMatchRegex,
+	}, `/\1`)
+	assert.Nil(err)
+	assert.Equal(`tcl:[regsub -- {/v[0-9]{1,2}/(.*)} [HTTP::path] {/\1}]`, path)
+	_, err = rewritePath(&Rule{
+		FullURI:   "foo.com/v{1",
+		PathMatch: pathMatchRegex,
+	}, "/")
+	assert.Error(err)
+}
+
 func TestRouteConfiguration(t *testing.T) {
 	require := require.New(t)
 	namespace := "default"
@@ -504,7 +900,7 @@ func TestRouteConfiguration(t *testing.T) {
 		protocol: "https",
 		port:     443,
 	}
-	cfg, _ := createRSConfigFromRoute(route, Resources{}, RouteConfig{}, ps, 443)
+	cfg, _ := createRSConfigFromRoute(route, "velcro", Resources{}, RouteConfig{}, ps, 443)
 
 	require.Equal("openshift_default_https", cfg.Virtual.VirtualServerName)
 	require.Equal("openshift_default_foo", cfg.Pools[0].Name)
@@ -526,9 +922,11 @@ func TestRouteConfiguration(t *testing.T) {
 		protocol: "http",
 		port:     80,
 	}
-	cfg, _ = createRSConfigFromRoute(route2, Resources{}, RouteConfig{}, ps, 80)
+	cfg, _ = createRSConfigFromRoute(route2, "tenant", Resources{}, RouteConfig{}, ps, 80)
 
 	require.Equal("openshift_default_http", cfg.Virtual.VirtualServerName)
+	require.Equal("tenant", cfg.Virtual.Partition)
+	require.Equal("tenant", cfg.Pools[0].Partition)
 	require.Equal("openshift_default_bar", cfg.Pools[0].Name)
 	require.Equal("bar", cfg.Pools[0].ServiceName)
 	require.Equal(int32(80), cfg.Pools[0].ServicePort)
@@ -536,6 +934,345 @@ func TestRouteConfiguration(t *testing.T) {
 	require.Equal("openshift_route_default_route2", cfg.Policies[0].Rules[0].Name)
 }
 
+func TestConfigMapBackendsConfiguration(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+
+	cm := test.NewConfigMap("backendsmap", "1", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   configmapBackends,
+	})
+	cfg, err := parseConfigMap(cm)
+	require.NoError(err)
+	require.Len(cfg.Pools, 2)
+	assert.Equal(Pool{
+		Name:         "default_backendsmap_foo",
+		Partition:    "velcro",
+		Balance:      "round-robin",
+		ServiceName:  "foo",
+		ServicePort:  80,
+		MonitorNames: []string{"/velcro/default_backendsmap_foo"},
+	}, cfg.Pools[0])
+	assert.Equal("least-connections-member", cfg.Pools[1].Balance)
+	require.Len(cfg.Monitors, 1)
+	assert.Equal("default_backendsmap_foo", cfg.Monitors[0].Name)
+	assert.Equal("/velcro/default_backendsmap_foo", cfg.Virtual.PoolName)
+	require.Len(cfg.Policies, 1)
+	assert.Equal("default_backendsmap", cfg.Policies[0].Name)
+	assert.Len(cfg.Policies[0].Rules, 3)
+
+	// Without rules or a default backend, the first backend is the default
+	data := strings.Replace(configmapBackends, `"defaultBackend": "foo",`, "", 1)
+	data = data[:strings.Index(data, `"rules"`)] +
+		data[strings.Index(data, `"frontend"`):]
+	cm.Data["data"] = data
+	cfg, err = parseConfigMap(cm)
+	require.NoError(err)
+	assert.Equal("/velcro/default_backendsmap_foo", cfg.Virtual.PoolName)
+	assert.Len(cfg.Policies, 0)
+
+	invalid := map[string][]string{
+		"unknown backend": {`"backend": "bar"`, `"backend": "baz"`},
+		"unknown default": {`"defaultBackend": "foo"`, `"defaultBackend": "baz"`},
+		"duplicate name":  {`"name": "bar"`, `"name": "foo"`},
+		"same service":    {`"serviceName": "bar"`, `"serviceName": "foo"`},
+		"duplicate rule":  {`"path": "/foo"`, `"host": "bar.example.com"`},
+		"tcp rules":       {`"mode": "http"`, `"mode": "tcp"`},
+		"underscore name": {`"name": "bar"`, `"name": "bar_1"`},
+	}
+	for name, replace := range invalid {
+		cm.Data["data"] = strings.Replace(
+			configmapBackends, replace[0], replace[1], 1)
+		_, err = parseConfigMap(cm)
+		assert.Error(err, name)
+	}
+}
+
+func TestConfigMapRuleConditions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+
+	rules := `"rules": [
+      { "path": "/foo", "backend": "foo" },
+      { "backend": "bar", "conditions": [
+        { "type": "cookie", "name": "group", "values": [ "beta" ] },
+        { "type": "method", "values": [ "GET", "HEAD" ] }
+      ] },
+      { "path": "/foo", "backend": "bar", "conditions": [
+        { "type": "header", "name": "User-Agent", "operator": "contains",
+          "values": [ "Mobile" ] }
+      ] }
+    ],`
+	data := configmapBackends[:strings.Index(configmapBackends, `"rules"`)] +
+		rules + configmapBackends[strings.Index(configmapBackends, `"frontend"`):]
+	cm := test.NewConfigMap("backendsmap", "1", namespace, map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	cfg, err := parseConfigMap(cm)
+	require.NoError(err)
+	require.Len(cfg.Policies, 1)
+	rls := cfg.Policies[0].Rules
+	require.Len(rls, 3)
+
+	// Rules with conditions match before the rules for the same path
+	// without them
+	fooPool := "/velcro/default_backendsmap_foo"
+	barPool := "/velcro/default_backendsmap_bar"
+	assert.Equal(barPool, rls[0].Actions[0].Pool)
+	require.Len(rls[0].Conditions, 2)
+	header := rls[0].Conditions[1]
+	assert.Equal("2", header.Name)
+	assert.True(header.HTTPHeader)
+	assert.True(header.Contains)
+	assert.Equal("User-Agent", header.TmName)
+	assert.Equal([]string{"Mobile"}, header.Values)
+	assert.Equal(fooPool, rls[1].Actions[0].Pool)
+	assert.Len(rls[1].Conditions, 1)
+	assert.Equal(barPool, rls[2].Actions[0].Pool)
+	require.Len(rls[2].Conditions, 2)
+	cookie := rls[2].Conditions[0]
+	assert.Equal("0", cookie.Name)
+	assert.True(cookie.HTTPCookie)
+	assert.True(cookie.Equals)
+	assert.Equal("group", cookie.TmName)
+	method := rls[2].Conditions[1]
+	assert.Equal("1", method.Name)
+	assert.True(method.HTTPMethod)
+	assert.Equal("", method.TmName)
+	assert.Equal([]string{"GET", "HEAD"}, method.Values)
+
+	duplicate := `"backend": "foo", "conditions": [
+        { "type": "header", "name": "User-Agent", "operator": "contains",
+          "values": [ "Mobile" ] }
+      ] }`
+	invalid := map[string][]string{
+		"duplicate rule": {`"backend": "foo" }`, duplicate},
+		"unknown type":   {`"type": "cookie"`, `"type": "body"`},
+		"method name":    {`"type": "cookie"`, `"type": "method"`},
+	}
+	for name, replace := range invalid {
+		cm.Data["data"] = strings.Replace(data, replace[0], replace[1], 1)
+		_, err = parseConfigMap(cm)
+		assert.Error(err, name)
+	}
+}
+
+func TestConfigMapIRulesAndPolicies(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	data := strings.Replace(configmapFoo, `"partition": "velcro",`,
+		`"partition": "velcro",
+      "iRules": [ "/Common/shared_irule", "/velcro/app_irule" ],
+      "policies": [ "/Common/shared_policy" ],`, 1)
+	cm := test.NewConfigMap("rulesmap", "1", "default", map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	cfg, err := parseConfigMap(cm)
+	require.NoError(err)
+	assert.Equal([]string{"/Common/shared_irule", "/velcro/app_irule"},
+		cfg.Virtual.IRules)
+	assert.Equal([]nameRef{{Name: "shared_policy", Partition: "Common"}},
+		cfg.Virtual.Policies)
+	// Only references, nothing to create on the BIG-IP
+	assert.Len(cfg.Policies, 0)
+
+	// The generated policy of the rules goes with the referenced ones
+	data = strings.Replace(configmapBackends, `"partition": "velcro",`,
+		`"partition": "velcro",
+      "policies": [ "/Common/shared_policy" ],`, 1)
+	cm.Data["data"] = data
+	cfg, err = parseConfigMap(cm)
+	require.NoError(err)
+	assert.Equal([]nameRef{
+		{Name: "shared_policy", Partition: "Common"},
+		{Name: "default_rulesmap", Partition: "velcro"},
+	}, cfg.Virtual.Policies)
+
+	// References need a partition
+	cm.Data["data"] = strings.Replace(configmapFoo, `"partition": "velcro",`,
+		`"partition": "velcro", "iRules": [ "shared_irule" ],`, 1)
+	_, err = parseConfigMap(cm)
+	assert.Error(err)
+}
+
+func TestConfigMapHealthMonitorOptions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	data := strings.Replace(configmapFoo, `"healthMonitors": [ {
+        "interval": 30,
+        "timeout": 20,
+        "send": "GET /",
+        "protocol": "tcp"
+        }
+      ]`, `"healthMonitors": [ {
+        "protocol": "https",
+        "send": "GET /health",
+        "recv": "200 OK",
+        "recvDisable": "503",
+        "upInterval": 30,
+        "timeUntilUp": 60,
+        "aliasAddress": "10.0.0.1",
+        "aliasPort": 8443
+        }, {
+        "protocol": "tcp-half-open"
+        }
+      ]`, 1)
+	cm := test.NewConfigMap("monitormap", "1", "default", map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	cfg, err := parseConfigMap(cm)
+	require.NoError(err)
+	require.Len(cfg.Monitors, 2)
+	assert.Equal(Monitor{
+		Name:         "default_monitormap",
+		Partition:    "velcro",
+		Protocol:     "https",
+		Send:         "GET /health",
+		Recv:         "200 OK",
+		RecvDisable:  "503",
+		UpInterval:   30,
+		TimeUntilUp:  60,
+		AliasAddress: "10.0.0.1",
+		AliasPort:    8443,
+	}, cfg.Monitors[0])
+	assert.Equal(Monitor{
+		Name:      "default_monitormap_1",
+		Partition: "velcro",
+		Protocol:  "tcp-half-open",
+	}, cfg.Monitors[1])
+
+	// Reachability monitors do not send anything
+	cm.Data["data"] = strings.Replace(data,
+		`"protocol": "tcp-half-open"`,
+		`"protocol": "tcp-half-open", "send": "GET /"`, 1)
+	_, err = parseConfigMap(cm)
+	assert.Error(err)
+}
+
+func TestConfigMapUdp(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	udpMonitors := `"healthMonitors": [ {
+        "protocol": "udp",
+        "send": "default send string",
+        "interval": 10,
+        "timeout": 31
+        }, {
+        "protocol": "icmp"
+        }
+      ]`
+	data := strings.Replace(configmapFooTcp, `"mode": "tcp"`, `"mode": "udp"`, 1)
+	data = strings.Replace(data, `"servicePort": 80`,
+		`"servicePort": 80, `+udpMonitors, 1)
+	cm := test.NewConfigMap("dnsmap", "1", "default", map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	cfg, err := parseConfigMap(cm)
+	require.NoError(err)
+	assert.Equal("udp", cfg.Virtual.Mode)
+	require.Len(cfg.Monitors, 2)
+	assert.Equal("udp", cfg.Monitors[0].Protocol)
+	assert.Equal("icmp", cfg.Monitors[1].Protocol)
+
+	// TCP monitors and SSL profiles do not apply to UDP virtuals
+	cm.Data["data"] = strings.Replace(data,
+		`"protocol": "udp"`, `"protocol": "tcp"`, 1)
+	_, err = parseConfigMap(cm)
+	assert.Error(err)
+	cm.Data["data"] = strings.Replace(configmapFoo,
+		`"mode": "http"`, `"mode": "udp"`, 1)
+	_, err = parseConfigMap(cm)
+	assert.Error(err)
+}
+
+func TestConfigMapPersistence(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	data := strings.Replace(configmapFoo, `"partition": "velcro",`,
+		`"partition": "velcro", "persistence": { "default": "cookie" },`, 1)
+	cm := test.NewConfigMap("persistmap", "1", "default", map[string]string{
+		"schema": schemaUrl,
+		"data":   data,
+	})
+	cfg, err := parseConfigMap(cm)
+	require.NoError(err)
+	assert.Equal("/Common/cookie", cfg.Virtual.PersistenceProfile)
+	assert.Equal("", cfg.Virtual.FallbackPersistenceProfile)
+
+	// Cookie persistence needs the http mode, and can not be a fallback
+	cm.Data["data"] = strings.Replace(data,
+		`"mode": "http"`, `"mode": "tcp"`, 1)
+	_, err = parseConfigMap(cm)
+	assert.Error(err)
+	cm.Data["data"] = strings.Replace(data, `"default": "cookie"`,
+		`"default": "source-address", "fallback": "cookie"`, 1)
+	_, err = parseConfigMap(cm)
+	assert.Error(err)
+
+	// Other profiles are referenced by path
+	cm.Data["data"] = strings.Replace(data, `"default": "cookie"`,
+		`"default": "/Common/universal", "fallback": "destination-address"`, 1)
+	cfg, err = parseConfigMap(cm)
+	require.NoError(err)
+	assert.Equal("/Common/universal", cfg.Virtual.PersistenceProfile)
+	assert.Equal("/Common/dest_addr", cfg.Virtual.FallbackPersistenceProfile)
+	cm.Data["data"] = strings.Replace(data, `"default": "cookie"`,
+		`"default": "universal"`, 1)
+	_, err = parseConfigMap(cm)
+	assert.Error(err)
+}
+
+func TestParsePersistenceAnnotation(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := map[string][]string{
+		"cookie":                 {"/Common/cookie", ""},
+		"cookie, source-address": {"/Common/cookie", "/Common/source_addr"},
+		"/velcro/persist,destination-address": {
+			"/velcro/persist", "/Common/dest_addr"},
+	}
+	for value, expected := range valid {
+		dflt, fallback, err := parsePersistenceAnnotation(value)
+		assert.NoError(err, value)
+		assert.Equal(expected, []string{dflt, fallback}, value)
+	}
+	for _, value := range []string{"", "sticky", "cookie,",
+		"cookie,source-address,destination-address"} {
+		_, _, err := parsePersistenceAnnotation(value)
+		assert.Error(err, value)
+	}
+}
+
+func TestParseSourceAddrTranslation(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := map[string]SourceAddrTranslation{
+		"automap":           {Type: "automap"},
+		"none":              {Type: "none"},
+		"/Common/snatpool":  {Type: "snat", Pool: "/Common/snatpool"},
+		"/velcro/app/snat1": {Type: "snat", Pool: "/velcro/app/snat1"},
+	}
+	for value, expected := range valid {
+		sat, err := ParseSourceAddrTranslation(value)
+		assert.NoError(err, value)
+		assert.Equal(&expected, sat, value)
+	}
+	for _, value := range []string{"", "snat", "Common/snatpool", "/Common"} {
+		_, err := ParseSourceAddrTranslation(value)
+		assert.Error(err, value)
+	}
+}
+
 func TestSetAndRemoveInternalDataGroupRecords(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -581,3 +1318,71 @@ func TestSetAndRemoveInternalDataGroupRecords(t *testing.T) {
 		assert.Equal(expectedRecCt, idg.Records.Len())
 	}
 }
+
+func TestResolveServicePorts(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	namespace := "default"
+	svcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	svcIndexer.Add(test.NewService("foo", "1", namespace, "NodePort",
+		[]v1.ServicePort{{Name: "http", Port: 8080, NodePort: 30001}}))
+
+	cfg := ResourceConfig{Pools: []Pool{
+		{ServiceName: "foo", ServicePort: 80},
+		{ServiceName: "foo", ServicePortName: "http"},
+		{ServiceName: "bar", ServicePortName: "http"},
+	}}
+	err := cfg.resolveServicePorts(namespace, svcIndexer)
+	assert.Nil(err)
+	assert.Equal(int32(80), cfg.Pools[0].ServicePort)
+	assert.Equal(int32(8080), cfg.Pools[1].ServicePort)
+	assert.Equal(int32(0), cfg.Pools[2].ServicePort,
+		"Port of a missing Service should be resolved later")
+
+	cfg.Pools[1].ServicePortName = "https"
+	err = cfg.resolveServicePorts(namespace, svcIndexer)
+	assert.NotNil(err)
+	assert.Equal(int32(0), cfg.Pools[1].ServicePort)
+
+	backend := &netv1.IngressBackend{
+		Service: &netv1.IngressServiceBackend{
+			Name: "foo",
+			Port: netv1.ServiceBackendPort{Name: "http"},
+		},
+	}
+	missing := netv1.IngressBackend{
+		Service: &netv1.IngressServiceBackend{
+			Name: "foo",
+			Port: netv1.ServiceBackendPort{Name: "https"},
+		},
+	}
+	ing := test.NewNetworkingIngress("ingress", "1", namespace,
+		netv1.IngressSpec{
+			DefaultBackend: backend,
+			Rules: []netv1.IngressRule{
+				{
+					IngressRuleValue: netv1.IngressRuleValue{
+						HTTP: &netv1.HTTPIngressRuleValue{
+							Paths: []netv1.HTTPIngressPath{
+								{Path: "/", Backend: *backend},
+								{Path: "/secure", Backend: missing},
+							},
+						},
+					},
+				},
+			},
+		}, nil)
+	cached := *ing
+	errs := resolveIngressServicePorts(ing, svcIndexer)
+	require.Equal(1, len(errs))
+	assert.Contains(errs[0].Error(), "no port named 'https'")
+	assert.Equal(int32(8080), ing.Spec.DefaultBackend.Service.Port.Number)
+	paths := ing.Spec.Rules[0].HTTP.Paths
+	assert.Equal(int32(8080), paths[0].Backend.Service.Port.Number)
+	assert.Equal(int32(0), paths[1].Backend.Service.Port.Number)
+	assert.Equal(int32(0), backend.Service.Port.Number,
+		"Backends shared with the cache should not be changed")
+	assert.Equal(int32(0),
+		cached.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Port.Number,
+		"Rules shared with the cache should not be changed")
+}
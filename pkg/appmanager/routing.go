@@ -18,9 +18,11 @@ package appmanager
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -28,8 +30,9 @@ import (
 
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
+
 	routeapi "github.com/openshift/origin/pkg/route/api"
-	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
 )
 
 const httpRedirectRuleName = "http-redirect"
@@ -42,17 +45,105 @@ const passthroughHostsDgName = "ssl_passthrough_servername_dg"
 // FIXME: Only used by the iRule below until reencrypt is supported.
 const reencryptHostsDgName = "ssl_reencrypt_servername_dg"
 
-func (r Rules) Len() int           { return len(r) }
-func (r Rules) Less(i, j int) bool { return r[i].FullURI < r[j].FullURI }
-func (r Rules) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+// How the path of a rule matches the path of a request
+type pathMatch string
+
+const (
+	// Every segment of the path equals the segment of the request
+	pathMatchPrefix pathMatch = "prefix"
+	// The path equals the path of the request
+	pathMatchExact pathMatch = "exact"
+	// The path of the request starts with the path
+	pathMatchStartsWith pathMatch = "starts-with"
+	// The path is a regular expression the path of the request matches
+	pathMatchRegex pathMatch = "regex"
+)
+
+// Parse the path match annotation of an Ingress
+func parsePathMatch(value string) (pathMatch, error) {
+	switch match := pathMatch(value); match {
+	case "":
+		return pathMatchPrefix, nil
+	case pathMatchPrefix, pathMatchExact, pathMatchStartsWith, pathMatchRegex:
+		return match, nil
+	}
+	return pathMatchPrefix, fmt.Errorf("Invalid path match '%s', must be "+
+		"'prefix', 'exact', 'starts-with' or 'regex'", value)
+}
+
+// Return how a path of an Ingress matches; its pathType decides, unless it is
+// ImplementationSpecific, when the path match annotation does
+func ingressPathMatch(path *netv1.HTTPIngressPath, annotation pathMatch) pathMatch {
+	if nil != path.PathType {
+		switch *path.PathType {
+		case netv1.PathTypeExact:
+			return pathMatchExact
+		case netv1.PathTypePrefix:
+			return pathMatchPrefix
+		}
+	}
+	return annotation
+}
 
-func createRule(uri, poolName, partition, routeName string) (*Rule, error) {
-	_u := "scheme://" + uri
-	_u = strings.TrimSuffix(_u, "/")
+// Rank of the matches for the same path, from the least specific
+func (m pathMatch) rank() int {
+	switch m {
+	case pathMatchStartsWith:
+		return 1
+	case pathMatchPrefix:
+		return 2
+	case pathMatchExact:
+		return 3
+	}
+	return 0
+}
+
+func (r Rules) Len() int { return len(r) }
+func (r Rules) Less(i, j int) bool {
+	// Reversed, regular expressions sort after all other matches
+	iRegex := pathMatchRegex == r[i].PathMatch
+	jRegex := pathMatchRegex == r[j].PathMatch
+	if iRegex != jRegex {
+		return iRegex
+	}
+	if r[i].FullURI != r[j].FullURI {
+		return r[i].FullURI < r[j].FullURI
+	}
+	if r[i].PathMatch != r[j].PathMatch {
+		return r[i].PathMatch.rank() < r[j].PathMatch.rank()
+	}
+	// Rules with more conditions on the request are more specific
+	return len(r[i].Conditions) < len(r[j].Conditions)
+}
+func (r Rules) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+
+func createRule(
+	uri string,
+	match pathMatch,
+	poolName string,
+	partition string,
+	routeName string,
+) (*Rule, error) {
+	// Only the prefix match splits the path into segments; the others use
+	// the path as it is, which may not parse as part of a URL
+	host, path := uri, ""
+	if i := strings.Index(uri, "/"); -1 != i {
+		host, path = uri[:i], uri[i:]
+	}
+	_u := "scheme://" + host
+	if pathMatchPrefix == match && "" != path {
+		_u = strings.TrimSuffix("scheme://"+uri, "/")
+	}
 	u, err := url.Parse(_u)
 	if nil != err {
 		return nil, err
 	}
+	if pathMatchRegex == match {
+		if _, err := regexp.Compile(path); nil != err {
+			return nil, fmt.Errorf("Invalid regular expression '%s': %v",
+				path, err)
+		}
+	}
 	var b bytes.Buffer
 	b.WriteRune('/')
 	b.WriteString(partition)
@@ -88,7 +179,21 @@ func createRule(uri, poolName, partition, routeName string) (*Rule, error) {
 			Values:   []string{u.Host},
 		})
 	}
-	if 0 != len(u.EscapedPath()) {
+	if pathMatchPrefix != match {
+		if "" != path {
+			c = append(c, &condition{
+				Equals:     pathMatchExact == match,
+				StartsWith: pathMatchStartsWith == match,
+				Matches:    pathMatchRegex == match,
+				HTTPURI:    true,
+				Path:       true,
+				Name:       "1",
+				Index:      1,
+				Request:    true,
+				Values:     []string{path},
+			})
+		}
+	} else if 0 != len(u.EscapedPath()) {
 		path := strings.TrimPrefix(u.EscapedPath(), "/")
 		segments := strings.Split(path, "/")
 		for i, v := range segments {
@@ -107,6 +212,7 @@ func createRule(uri, poolName, partition, routeName string) (*Rule, error) {
 	rl := Rule{
 		Name:       routeName,
 		FullURI:    uri,
+		PathMatch:  match,
 		Actions:    []*action{&a},
 		Conditions: c,
 	}
@@ -115,6 +221,120 @@ func createRule(uri, poolName, partition, routeName string) (*Rule, error) {
 	return &rl, nil
 }
 
+// Add the conditions on the headers, cookies, query parameters and method of
+// a request to a rule
+func addRequestConditions(rl *Rule, conds []requestCondition) error {
+	// The conditions are named after those of the host and path
+	next := 0
+	if n := len(rl.Conditions); 0 != n {
+		next, _ = strconv.Atoi(rl.Conditions[n-1].Name)
+		next++
+	}
+	for i, rc := range conds {
+		c := condition{
+			Name:    strconv.Itoa(next + i),
+			Request: true,
+			TmName:  rc.Name,
+			Values:  rc.Values,
+		}
+		switch rc.Type {
+		case "header":
+			c.HTTPHeader = true
+		case "cookie":
+			c.HTTPCookie = true
+		case "queryParameter":
+			c.HTTPURI = true
+			c.QueryParameter = true
+		case "method":
+			c.HTTPMethod = true
+		default:
+			return fmt.Errorf("Unknown condition type '%s', must be 'header', "+
+				"'cookie', 'queryParameter' or 'method'", rc.Type)
+		}
+		if "method" == rc.Type {
+			if "" != rc.Name {
+				return fmt.Errorf("The method condition has no name")
+			}
+		} else if "" == rc.Name {
+			return fmt.Errorf("The %s condition needs a name", rc.Type)
+		}
+		switch rc.Operator {
+		case "", "equals":
+			c.Equals = true
+		case "starts-with":
+			c.StartsWith = true
+		case "ends-with":
+			c.EndsWith = true
+		case "contains":
+			c.Contains = true
+		default:
+			return fmt.Errorf("Unknown condition operator '%s', must be "+
+				"'equals', 'starts-with', 'ends-with' or 'contains'", rc.Operator)
+		}
+		if 0 == len(rc.Values) {
+			return fmt.Errorf("The %s condition needs values", rc.Type)
+		}
+		rl.Conditions = append(rl.Conditions, &c)
+	}
+	return nil
+}
+
+// Parse the conditions annotation of an Ingress. The entries are returned
+// even if some are not valid, so that the rules for their paths are skipped
+// rather than configured without their conditions.
+func parseIngressConditions(value string) ([]ingressRuleConditions, error) {
+	var entries []ingressRuleConditions
+	err := json.Unmarshal([]byte(value), &entries)
+	if nil != err {
+		return nil, fmt.Errorf(
+			"Unable to parse the conditions JSON array '%s': %v", value, err)
+	}
+	for _, entry := range entries {
+		err = addRequestConditions(&Rule{}, entry.Conditions)
+		if nil != err {
+			return entries, fmt.Errorf("Invalid conditions for '%s': %v",
+				entry.Path, err)
+		}
+	}
+	return entries, nil
+}
+
+// Return the conditions annotation entry for a path of an Ingress, which
+// names its host, or '*' for any host, and its path
+func ingressConditionsFor(
+	entries []ingressRuleConditions,
+	host string,
+	path string,
+	svcName string,
+) *ingressRuleConditions {
+	for i, entry := range entries {
+		slashPos := strings.Index(entry.Path, "/")
+		if -1 == slashPos || path != entry.Path[slashPos:] {
+			continue
+		}
+		entryHost := entry.Path[:slashPos]
+		if ("*" == entryHost || host == entryHost) &&
+			("" == entry.ServiceName || svcName == entry.ServiceName) {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// Key of a rule in a ruleMap, so that rules for the same URI with another
+// path match or other conditions are kept
+func ruleKey(uri string, match pathMatch, conds []requestCondition) string {
+	key := uri
+	if pathMatchPrefix != match {
+		key = string(match) + " " + key
+	}
+	if 0 != len(conds) {
+		data, _ := json.Marshal(conds)
+		key += " " + string(data)
+	}
+	return key
+}
+
 func createPolicy(rls Rules, policyName, partition string) *Policy {
 	plcy := Policy{
 		Controls:  []string{"forwarding"},
@@ -133,42 +353,122 @@ func createPolicy(rls Rules, policyName, partition string) *Policy {
 }
 
 func processIngressRules(
-	ing *v1beta1.IngressSpec,
+	ing *netv1.IngressSpec,
 	pools []Pool,
 	partition string,
+	annotation pathMatch,
+	conditions []ingressRuleConditions,
+	actions ingressRuleActions,
 ) *Rules {
 	var err error
 	var uri, poolName string
 	var rl *Rule
 	rlMap := make(ruleMap)
 	wildcards := make(ruleMap)
+	hosts := make(map[string]bool)
 	for _, rule := range ing.Rules {
+		hosts[rule.Host] = true
 		if nil != rule.IngressRuleValue.HTTP {
-			for _, path := range rule.IngressRuleValue.HTTP.Paths {
+			for i, path := range rule.IngressRuleValue.HTTP.Paths {
+				if nil == path.Backend.Service {
+					continue
+				}
 				uri = rule.Host + path.Path
+				match := ingressPathMatch(
+					&rule.IngressRuleValue.HTTP.Paths[i], annotation)
+				// The default backend may add a pool for the same Service
+				// on another port
 				for _, pool := range pools {
-					if path.Backend.ServiceName == pool.ServiceName {
+					if path.Backend.Service.Name == pool.ServiceName &&
+						path.Backend.Service.Port.Number == pool.ServicePort {
 						poolName = pool.Name
+						break
 					}
 				}
 				if poolName == "" {
 					continue
 				}
 				// This blank name gets overridden by an ordinal later on
-				rl, err = createRule(uri, poolName, partition, "")
+				rl, err = createRule(uri, match, poolName, partition, "")
+				var conds []requestCondition
+				if nil == err {
+					entry := ingressConditionsFor(conditions, rule.Host,
+						path.Path, path.Backend.Service.Name)
+					if nil != entry {
+						conds = entry.Conditions
+						err = addRequestConditions(rl, conds)
+					}
+				}
+				if nil == err {
+					err = actions.applyTo(rl)
+				}
 				if nil != err {
 					log.Warningf("Error configuring rule: %v", err)
-					return nil
+					poolName = ""
+					continue
 				}
+				// The same path may be matched more than one way
+				key := ruleKey(uri, match, conds)
 				if true == strings.HasPrefix(uri, "*.") {
-					wildcards[uri] = rl
+					wildcards[key] = rl
 				} else {
-					rlMap[uri] = rl
+					rlMap[key] = rl
 				}
 				poolName = ""
 			}
 		}
 	}
+	// Requests for the root of each host are redirected to the app root,
+	// unless every request is redirected
+	if "" != actions.AppRoot && "" == actions.RedirectURL {
+		for host := range hosts {
+			rl, err = appRootRule(host, actions.AppRoot, partition)
+			if nil != err {
+				log.Warningf("Error configuring rule: %v", err)
+				continue
+			}
+			key := ruleKey(host+"/", pathMatchExact, nil)
+			if true == strings.HasPrefix(host, "*.") {
+				wildcards[key] = rl
+			} else {
+				rlMap[key] = rl
+			}
+		}
+	}
+	return orderRules(rlMap, wildcards)
+}
+
+// Rules for an Ingress with only a default backend and rewrites or redirects,
+// which apply to every request
+func processIngressDefaultRules(
+	poolName string,
+	partition string,
+	actions ingressRuleActions,
+) *Rules {
+	rlMap := make(ruleMap)
+	rl, err := createRule("", pathMatchPrefix, poolName, partition, "")
+	if nil == err {
+		err = actions.applyTo(rl)
+	}
+	if nil != err {
+		log.Warningf("Error configuring rule: %v", err)
+	} else {
+		rlMap[ruleKey("", pathMatchPrefix, nil)] = rl
+	}
+	if "" != actions.AppRoot && "" == actions.RedirectURL {
+		rl, err = appRootRule("", actions.AppRoot, partition)
+		if nil != err {
+			log.Warningf("Error configuring rule: %v", err)
+		} else {
+			rlMap[ruleKey("/", pathMatchExact, nil)] = rl
+		}
+	}
+	return orderRules(rlMap, make(ruleMap))
+}
+
+// Order and name rules so that longer URIs and more specific matches match
+// first, and rules for wildcard hosts only match after all others
+func orderRules(rlMap, wildcards ruleMap) *Rules {
 	var wg sync.WaitGroup
 	wg.Add(2)
 	sortrules := func(r ruleMap, rls *Rules, ordinal int) {
@@ -0,0 +1,108 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+//go:generate go run gen_schemas.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonreference"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Loads the schemas embedded in the controller for f5schemadb:// references,
+// and any other reference as gojsonschema does
+type embeddedSchemaLoader struct {
+	source string
+}
+
+func newEmbeddedSchemaLoader(source string) gojsonschema.JSONLoader {
+	if !strings.HasPrefix(source, schemaIndicator) {
+		return gojsonschema.NewReferenceLoader(source)
+	}
+	return &embeddedSchemaLoader{source: source}
+}
+
+func (l *embeddedSchemaLoader) JsonSource() interface{} {
+	return l.source
+}
+
+func (l *embeddedSchemaLoader) JsonReference() (
+	gojsonreference.JsonReference,
+	error,
+) {
+	return gojsonreference.NewJsonReference(l.source)
+}
+
+func (l *embeddedSchemaLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return embeddedSchemaLoaderFactory{}
+}
+
+func (l *embeddedSchemaLoader) LoadJSON() (interface{}, error) {
+	// References within the schema add a fragment to its name
+	name := strings.TrimPrefix(l.source, schemaIndicator)
+	name = strings.SplitN(name, "#", 2)[0]
+	schema, ok := embeddedSchemas[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown schema '%s'", l.source)
+	}
+	// gojsonschema expects numbers as json.Number
+	var doc interface{}
+	decoder := json.NewDecoder(strings.NewReader(schema))
+	decoder.UseNumber()
+	err := decoder.Decode(&doc)
+	return doc, err
+}
+
+// Creates the loaders for the references within a schema
+type embeddedSchemaLoaderFactory struct{}
+
+func (f embeddedSchemaLoaderFactory) New(
+	source string,
+) gojsonschema.JSONLoader {
+	return newEmbeddedSchemaLoader(source)
+}
+
+// Compiled schemas, by name
+type schemaCache struct {
+	sync.Mutex
+	schemas map[string]*gojsonschema.Schema
+}
+
+var compiledSchemas = schemaCache{
+	schemas: make(map[string]*gojsonschema.Schema),
+}
+
+// Return the compiled schema for name, compiling it on first use. Schemas
+// are versioned by name, so they never change once compiled.
+func getSchema(name string) (*gojsonschema.Schema, error) {
+	compiledSchemas.Lock()
+	defer compiledSchemas.Unlock()
+	if schema, ok := compiledSchemas.schemas[name]; ok {
+		return schema, nil
+	}
+	schema, err := gojsonschema.NewSchema(newEmbeddedSchemaLoader(name))
+	if nil != err {
+		return nil, err
+	}
+	compiledSchemas.schemas[name] = schema
+	return schema, nil
+}
@@ -0,0 +1,20 @@
+// Code generated by gen_schemas.go; DO NOT EDIT.
+
+package appmanager
+
+// Schemas for the f5schemadb:// scheme, by name
+var embeddedSchemas = map[string]string{
+	"bigip-virtual-server_v0.1.0.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.0.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ip-address\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"bindAddr\", \"port\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"f5ProfileName\": { \"type\": \"string\", \"minLength\": \"1\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"f5ProfileName\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"tcp\", \"http\" ] },\n        \"balance\": { \"type\": \"string\", \"enum\": [ \"round-robin\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"virtualAddress\" ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappTableName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappTableName\", \"iappOptions\",\n                    \"iappVariables\" ]\n    },\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendVSType\" },\n            { \"$ref\": \"#/definitions/frontendIAppType\" }\n          ]\n        },\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\", \"backend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.1.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.1.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappTableName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappTableName\",\n                    \"iappVariables\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"type\": \"string\", \"enum\":\n          [ \"dynamic-ratio-member\",\n            \"dynamic-ratio-node\",\n            \"fastest-app-response\",\n            \"fastest-node\",\n            \"least-connections-member\",\n            \"least-connections-node\",\n            \"least-sessions\",\n            \"observed-member\",\n            \"observed-node\",\n            \"predictive-member\",\n            \"predictive-node\",\n            \"ratio-least-connections-member\",\n            \"ratio-least-connections-node\",\n            \"ratio-member\",\n            \"ratio-node\",\n            \"round-robin\",\n            \"ratio-session\",\n            \"weighted-least-connections-member\",\n            \"weighted-least-connections-node\" ] },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"virtualAddress\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"f5ProfileName\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"f5ProfileName\" ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"bindAddr\", \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\", \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.10.json": "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.10.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"bigipPathType\": {\n      \"type\": \"string\",\n      \"pattern\": \"^/[^/]+/[^/]+\"\n    },\n    \"persistenceProfileType\": {\n      \"anyOf\": [\n        { \"type\": \"string\",\n          \"enum\": [ \"cookie\", \"source-address\", \"destination-address\" ] },\n        { \"$ref\": \"#/definitions/bigipPathType\" }\n      ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\", \"udp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" },\n        \"iRules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"policies\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"snat\": {\n          \"anyOf\": [\n            { \"type\": \"string\", \"enum\": [ \"automap\", \"none\" ] },\n            { \"$ref\": \"#/definitions/bigipPathType\" }\n          ]\n        },\n        \"persistence\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"default\": { \"$ref\": \"#/definitions/persistenceProfileType\" },\n            \"fallback\": { \"$ref\": \"#/definitions/persistenceProfileType\" }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"default\" ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": {\n          \"type\": \"string\",\n          \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\",\n                    \"icmp\", \"gateway-icmp\", \"tcp-half-open\" ]\n        },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recv\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recvDisable\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"upInterval\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"timeUntilUp\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"aliasAddress\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"aliasPort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"anyOf\": [\n        {\n          \"properties\": {\n            \"protocol\": { \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\" ] }\n          }\n        },\n        {\n          \"not\": {\n            \"anyOf\": [\n              { \"required\": [ \"send\" ] },\n              { \"required\": [ \"recv\" ] },\n              { \"required\": [ \"recvDisable\" ] }\n            ]\n          }\n        }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.11.json": "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.11.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/servicePortType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"bigipPathType\": {\n      \"type\": \"string\",\n      \"pattern\": \"^/[^/]+/[^/]+\"\n    },\n    \"persistenceProfileType\": {\n      \"anyOf\": [\n        { \"type\": \"string\",\n          \"enum\": [ \"cookie\", \"source-address\", \"destination-address\" ] },\n        { \"$ref\": \"#/definitions/bigipPathType\" }\n      ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\", \"udp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" },\n        \"iRules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"policies\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"snat\": {\n          \"anyOf\": [\n            { \"type\": \"string\", \"enum\": [ \"automap\", \"none\" ] },\n            { \"$ref\": \"#/definitions/bigipPathType\" }\n          ]\n        },\n        \"persistence\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"default\": { \"$ref\": \"#/definitions/persistenceProfileType\" },\n            \"fallback\": { \"$ref\": \"#/definitions/persistenceProfileType\" }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"default\" ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": {\n          \"type\": \"string\",\n          \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\",\n                    \"icmp\", \"gateway-icmp\", \"tcp-half-open\" ]\n        },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recv\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recvDisable\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"upInterval\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"timeUntilUp\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"aliasAddress\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"aliasPort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"anyOf\": [\n        {\n          \"properties\": {\n            \"protocol\": { \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\" ] }\n          }\n        },\n        {\n          \"not\": {\n            \"anyOf\": [\n              { \"required\": [ \"send\" ] },\n              { \"required\": [ \"recv\" ] },\n              { \"required\": [ \"recvDisable\" ] }\n            ]\n          }\n        }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/servicePortType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"servicePortType\": {\n      \"type\": [ \"integer\", \"string\" ],\n      \"minimum\": 1,\n      \"maximum\": 65535,\n      \"maxLength\": 15,\n      \"pattern\": \"^([a-z0-9]+-)*[a-z0-9]*[a-z][a-z0-9]*(-[a-z0-9]+)*$\"\n    },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.12.json": "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.12.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/servicePortType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"bigipPathType\": {\n      \"type\": \"string\",\n      \"pattern\": \"^/[^/]+/[^/]+\"\n    },\n    \"persistenceProfileType\": {\n      \"anyOf\": [\n        { \"type\": \"string\",\n          \"enum\": [ \"cookie\", \"source-address\", \"destination-address\" ] },\n        { \"$ref\": \"#/definitions/bigipPathType\" }\n      ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\", \"udp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" },\n        \"iRules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"policies\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"snat\": {\n          \"anyOf\": [\n            { \"type\": \"string\", \"enum\": [ \"automap\", \"none\" ] },\n            { \"$ref\": \"#/definitions/bigipPathType\" }\n          ]\n        },\n        \"persistence\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"default\": { \"$ref\": \"#/definitions/persistenceProfileType\" },\n            \"fallback\": { \"$ref\": \"#/definitions/persistenceProfileType\" }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"default\" ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": {\n          \"type\": \"string\",\n          \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\",\n                    \"icmp\", \"gateway-icmp\", \"tcp-half-open\" ]\n        },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recv\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recvDisable\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"upInterval\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"timeUntilUp\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"aliasAddress\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"aliasPort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"anyOf\": [\n        {\n          \"properties\": {\n            \"protocol\": { \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\" ] }\n          }\n        },\n        {\n          \"not\": {\n            \"anyOf\": [\n              { \"required\": [ \"send\" ] },\n              { \"required\": [ \"recv\" ] },\n              { \"required\": [ \"recvDisable\" ] }\n            ]\n          }\n        }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/servicePortType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"servicePortType\": {\n      \"type\": [ \"integer\", \"string\" ],\n      \"minimum\": 1,\n      \"maximum\": 65535,\n      \"maxLength\": 15,\n      \"pattern\": \"^([a-z0-9]+-)*[a-z0-9]*[a-z][a-z0-9]*(-[a-z0-9]+)*$\"\n    },\n    \"ruleConditionType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"type\": { \"enum\": [ \"header\", \"cookie\", \"queryParameter\", \"method\" ] },\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"operator\": {\n          \"enum\": [ \"equals\", \"starts-with\", \"ends-with\", \"contains\" ]\n        },\n        \"values\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\" }\n        }\n      },\n      \"oneOf\": [\n        {\n          \"properties\": { \"type\": { \"enum\": [ \"method\" ] } },\n          \"not\": { \"required\": [ \"name\" ] }\n        },\n        {\n          \"properties\": {\n            \"type\": { \"enum\": [ \"header\", \"cookie\", \"queryParameter\" ] }\n          },\n          \"required\": [ \"name\" ]\n        }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"type\", \"values\" ]\n    },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" },\n        \"conditions\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"$ref\": \"#/definitions/ruleConditionType\" }\n        }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] },\n        { \"required\": [ \"conditions\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.2.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.2.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"type\": \"string\", \"enum\":\n          [ \"dynamic-ratio-member\",\n            \"dynamic-ratio-node\",\n            \"fastest-app-response\",\n            \"fastest-node\",\n            \"least-connections-member\",\n            \"least-connections-node\",\n            \"least-sessions\",\n            \"observed-member\",\n            \"observed-node\",\n            \"predictive-member\",\n            \"predictive-node\",\n            \"ratio-least-connections-member\",\n            \"ratio-least-connections-node\",\n            \"ratio-member\",\n            \"ratio-node\",\n            \"round-robin\",\n            \"ratio-session\",\n            \"weighted-least-connections-member\",\n            \"weighted-least-connections-node\" ] },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"virtualAddress\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"f5ProfileName\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"f5ProfileName\" ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"bindAddr\", \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\", \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.3.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.3.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"type\": \"string\", \"enum\":\n          [ \"dynamic-ratio-member\",\n            \"dynamic-ratio-node\",\n            \"fastest-app-response\",\n            \"fastest-node\",\n            \"least-connections-member\",\n            \"least-connections-node\",\n            \"least-sessions\",\n            \"observed-member\",\n            \"observed-node\",\n            \"predictive-member\",\n            \"predictive-node\",\n            \"ratio-least-connections-member\",\n            \"ratio-least-connections-node\",\n            \"ratio-member\",\n            \"ratio-node\",\n            \"round-robin\",\n            \"ratio-session\",\n            \"weighted-least-connections-member\",\n            \"weighted-least-connections-node\" ] },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"f5ProfileName\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"f5ProfileName\" ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\", \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.4.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.4.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"type\": \"string\", \"enum\":\n          [ \"dynamic-ratio-member\",\n            \"dynamic-ratio-node\",\n            \"fastest-app-response\",\n            \"fastest-node\",\n            \"least-connections-member\",\n            \"least-connections-node\",\n            \"least-sessions\",\n            \"observed-member\",\n            \"observed-node\",\n            \"predictive-member\",\n            \"predictive-node\",\n            \"ratio-least-connections-member\",\n            \"ratio-least-connections-node\",\n            \"ratio-member\",\n            \"ratio-node\",\n            \"round-robin\",\n            \"ratio-session\",\n            \"weighted-least-connections-member\",\n            \"weighted-least-connections-node\" ] },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\", \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.5.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.5.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.6.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.6.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"bigipPathType\": {\n      \"type\": \"string\",\n      \"pattern\": \"^/[^/]+/[^/]+\"\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" },\n        \"iRules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"policies\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.7.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.7.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"bigipPathType\": {\n      \"type\": \"string\",\n      \"pattern\": \"^/[^/]+/[^/]+\"\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" },\n        \"iRules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"policies\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": {\n          \"type\": \"string\",\n          \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\",\n                    \"icmp\", \"gateway-icmp\", \"tcp-half-open\" ]\n        },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recv\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recvDisable\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"upInterval\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"timeUntilUp\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"aliasAddress\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"aliasPort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"anyOf\": [\n        {\n          \"properties\": {\n            \"protocol\": { \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\" ] }\n          }\n        },\n        {\n          \"not\": {\n            \"anyOf\": [\n              { \"required\": [ \"send\" ] },\n              { \"required\": [ \"recv\" ] },\n              { \"required\": [ \"recvDisable\" ] }\n            ]\n          }\n        }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.8.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.8.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"bigipPathType\": {\n      \"type\": \"string\",\n      \"pattern\": \"^/[^/]+/[^/]+\"\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\", \"udp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" },\n        \"iRules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"policies\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": {\n          \"type\": \"string\",\n          \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\",\n                    \"icmp\", \"gateway-icmp\", \"tcp-half-open\" ]\n        },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recv\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recvDisable\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"upInterval\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"timeUntilUp\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"aliasAddress\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"aliasPort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"anyOf\": [\n        {\n          \"properties\": {\n            \"protocol\": { \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\" ] }\n          }\n        },\n        {\n          \"not\": {\n            \"anyOf\": [\n              { \"required\": [ \"send\" ] },\n              { \"required\": [ \"recv\" ] },\n              { \"required\": [ \"recvDisable\" ] }\n            ]\n          }\n        }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+	"bigip-virtual-server_v0.1.9.json":  "{\n  \"$schema\": \"http://json-schema/org/schema#\",\n  \"id\": \"f5schemadb://bigip-virtual-server_v0.1.9.json\",\n\n  \"type\": \"object\",\n\n  \"definitions\": {\n    \"backendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"serviceName\", \"servicePort\" ]\n    },\n    \"balanceType\": {\n      \"type\": \"string\",\n      \"enum\":\n        [ \"dynamic-ratio-member\",\n          \"dynamic-ratio-node\",\n          \"fastest-app-response\",\n          \"fastest-node\",\n          \"least-connections-member\",\n          \"least-connections-node\",\n          \"least-sessions\",\n          \"observed-member\",\n          \"observed-node\",\n          \"predictive-member\",\n          \"predictive-node\",\n          \"ratio-least-connections-member\",\n          \"ratio-least-connections-node\",\n          \"ratio-member\",\n          \"ratio-node\",\n          \"round-robin\",\n          \"ratio-session\",\n          \"weighted-least-connections-member\",\n          \"weighted-least-connections-node\" ]\n    },\n    \"bigipPathType\": {\n      \"type\": \"string\",\n      \"pattern\": \"^/[^/]+/[^/]+\"\n    },\n    \"frontendIAppType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"iapp\": { \"type\": \"string\", \"minLength\": 1 },\n        \"iappOptions\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappPoolMemberTable\": {\n          \"type\": \"object\",\n          \"properties\": {\n            \"name\": { \"type\": \"string\", \"minLength\": 1 },\n            \"columns\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"oneOf\": [\n                  { \"$ref\": \"#/definitions/iappAddressType\" },\n                  { \"$ref\": \"#/definitions/iappPortType\" },\n                  { \"$ref\": \"#/definitions/iappValueType\" }\n                ]\n              }\n            }\n          },\n          \"additionalProperties\": false,\n          \"required\": [ \"name\", \"columns\" ]\n        },\n        \"iappTables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"$ref\": \"#/definitions/iappTableType\" }\n          },\n          \"additionalProperties\": false\n        },\n        \"iappVariables\": {\n          \"type\": \"object\",\n          \"patternProperties\": {\n            \"^[a-zA-Z0-9_-]+$\": { \"type\": \"string\", \"minLength\": 1 }\n          },\n          \"additionalProperties\": false\n        },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\", \"iapp\", \"iappOptions\", \"iappVariables\",\n                    \"iappPoolMemberTable\" ]\n    },\n    \"frontendVSType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"partition\": { \"type\": \"string\", \"minLength\": 1 },\n        \"mode\": { \"type\": \"string\", \"enum\": [ \"http\", \"tcp\", \"udp\" ] },\n        \"sslProfile\": { \"$ref\": \"#/definitions/sslProfileType\" },\n        \"virtualAddress\": { \"$ref\": \"#/definitions/virtualAddressType\" },\n        \"iRules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"policies\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/bigipPathType\" },\n          \"uniqueItems\": true\n        },\n        \"snat\": {\n          \"anyOf\": [\n            { \"type\": \"string\", \"enum\": [ \"automap\", \"none\" ] },\n            { \"$ref\": \"#/definitions/bigipPathType\" }\n          ]\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"partition\" ]\n    },\n    \"healthMonitorType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"interval\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"protocol\": {\n          \"type\": \"string\",\n          \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\",\n                    \"icmp\", \"gateway-icmp\", \"tcp-half-open\" ]\n        },\n        \"send\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recv\": { \"type\": \"string\", \"minLength\": 1 },\n        \"recvDisable\": { \"type\": \"string\", \"minLength\": 1 },\n        \"timeout\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 86400 },\n        \"upInterval\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"timeUntilUp\": { \"type\": \"integer\", \"minimum\": 0, \"maximum\": 86400 },\n        \"aliasAddress\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"aliasPort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"anyOf\": [\n        {\n          \"properties\": {\n            \"protocol\": { \"enum\": [ \"http\", \"https\", \"tcp\", \"udp\" ] }\n          }\n        },\n        {\n          \"not\": {\n            \"anyOf\": [\n              { \"required\": [ \"send\" ] },\n              { \"required\": [ \"recv\" ] },\n              { \"required\": [ \"recvDisable\" ] }\n            ]\n          }\n        }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"protocol\" ]\n    },\n    \"iappAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"IPAddress\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappPortType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"kind\": { \"type\": \"string\", \"enum\": [ \"Port\" ] }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"kind\" ]\n    },\n    \"iappValueType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"name\": { \"type\": \"string\", \"minLength\": 1 },\n        \"value\": { \"type\": \"string\", \"minLength\": 1 }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"value\" ]\n    },\n    \"iappTableType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"columns\": {\n          \"type\": \"array\",\n          \"minItems\": 1,\n          \"items\": { \"type\": \"string\", \"minLength\": 1 }\n        },\n        \"rows\": {\n          \"type\": \"array\",\n          \"items\": { \"type\": \"array\", \"items\": { \"type\": \"string\" }}\n        }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"columns\", \"rows\" ]\n    },\n    \"namedBackendType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"balance\": { \"$ref\": \"#/definitions/balanceType\" },\n        \"healthMonitors\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/healthMonitorType\" }\n        },\n        \"name\": { \"type\": \"string\", \"pattern\": \"^[a-zA-Z0-9-]+$\" },\n        \"serviceName\": { \"type\": \"string\", \"minLength\": 1 },\n        \"servicePort\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"name\", \"serviceName\", \"servicePort\" ]\n    },\n    \"portType\": { \"type\": \"integer\", \"minimum\": 1, \"maximum\": 65535 },\n    \"ruleType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"host\": { \"type\": \"string\", \"minLength\": 1 },\n        \"path\": { \"type\": \"string\", \"pattern\": \"^/\" }\n      },\n      \"anyOf\": [\n        { \"required\": [ \"host\" ] },\n        { \"required\": [ \"path\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"backend\" ]\n    },\n    \"sslProfileType\": {\n      \"type\": \"object\",\n      \"oneOf\": [\n        {\n          \"properties\": {\n            \"f5ProfileNames\": {\n              \"type\": \"array\",\n              \"items\": {\n                \"type\": \"string\",\n                \"minLength\": 1\n              }\n            }\n          },\n          \"required\": [ \"f5ProfileNames\" ]\n        }, {\n          \"properties\": {\n            \"f5ProfileName\": {\n              \"type\": \"string\",\n              \"minLength\": 1\n            }\n          },\n          \"additionalProperties\": false\n        }\n      ]\n    },\n    \"virtualAddressType\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"bindAddr\": {\n          \"anyOf\": [ { \"format\": \"ipv4\" }, { \"format\": \"ipv6\" } ]\n        },\n        \"port\": { \"$ref\": \"#/definitions/portType\" }\n      },\n      \"additionalProperties\": false,\n      \"required\": [ \"port\" ]\n    }\n  },\n\n  \"properties\": {\n    \"virtualServer\": {\n      \"type\": \"object\",\n      \"properties\": {\n        \"backend\": { \"$ref\": \"#/definitions/backendType\" },\n        \"backends\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/namedBackendType\" },\n          \"minItems\": 1\n        },\n        \"defaultBackend\": { \"type\": \"string\", \"minLength\": 1 },\n        \"frontend\": {\n          \"oneOf\": [\n            { \"$ref\": \"#/definitions/frontendIAppType\" },\n            { \"$ref\": \"#/definitions/frontendVSType\" }\n          ]\n        },\n        \"rules\": {\n          \"type\": \"array\",\n          \"items\": { \"$ref\": \"#/definitions/ruleType\" }\n        }\n      },\n      \"oneOf\": [\n        { \"required\": [ \"backend\" ] },\n        { \"required\": [ \"backends\" ] }\n      ],\n      \"additionalProperties\": false,\n      \"required\": [ \"frontend\" ]\n    }\n  },\n  \"additionalProperties\": false,\n  \"required\": [ \"virtualServer\" ]\n}\n",
+}
@@ -0,0 +1,88 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestEmbeddedSchemasUpToDate(t *testing.T) {
+	paths, err := filepath.Glob("../../schemas/bigip-virtual-server_v*.json")
+	require.Nil(t, err)
+	require.NotEmpty(t, paths)
+	assert.Equal(t, len(paths), len(embeddedSchemas),
+		"Run go generate after adding a schema")
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		name := filepath.Base(path)
+		assert.Equal(t, string(data), embeddedSchemas[name],
+			"Run go generate after changing schema %s", name)
+	}
+}
+
+func TestEmbeddedSchemasCompile(t *testing.T) {
+	for name := range embeddedSchemas {
+		// v0.1.0 shipped with a string minLength, which gojsonschema rejects
+		if "bigip-virtual-server_v0.1.0.json" == name {
+			continue
+		}
+		schema, err := getSchema(schemaIndicator + name)
+		assert.Nil(t, err, "Schema %s should compile", name)
+		assert.NotNil(t, schema)
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	schema, err := getSchema(schemaUrl)
+	require.Nil(t, err)
+	cached, err := getSchema(schemaUrl)
+	require.Nil(t, err)
+	assert.True(t, schema == cached, "Compiled schema should be cached")
+
+	// Data is validated against the embedded schema
+	result, err := schema.Validate(gojsonschema.NewStringLoader(configmapFoo))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+	result, err = schema.Validate(
+		gojsonschema.NewStringLoader(configmapFooInvalid))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+
+	// Unknown schemas are an error, and not cached
+	_, err = getSchema("f5schemadb://bigip-virtual-server_v9.9.9.json")
+	assert.NotNil(t, err)
+	_, ok := compiledSchemas.schemas["f5schemadb://bigip-virtual-server_v9.9.9.json"]
+	assert.False(t, ok)
+
+	// Schemas outside of f5schemadb are loaded from their URL
+	workingDir, _ := os.Getwd()
+	fileUrl := "file://" + workingDir +
+		"/../../schemas/bigip-virtual-server_v0.1.5.json"
+	schema, err = getSchema(fileUrl)
+	require.Nil(t, err)
+	result, err = schema.Validate(gojsonschema.NewStringLoader(configmapFoo))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
@@ -16,6 +16,10 @@
 
 package appmanager
 
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
 type (
 	// Config of all resources to configure on the BIG-IP
 	BigIPConfig struct {
@@ -35,6 +39,7 @@ type (
 		Pools    []Pool   `json:"pools,omitempty"`
 		Monitors Monitors `json:"monitors,omitempty"`
 		Policies []Policy `json:"policies,omitempty"`
+		IRules   []IRule  `json:"iRules,omitempty"`
 	}
 	ResourceConfigs []*ResourceConfig
 
@@ -58,6 +63,11 @@ type (
 		SslProfile     *sslProfile     `json:"sslProfile,omitempty"`
 		Policies       []nameRef       `json:"policies,omitempty"`
 		IRules         []string        `json:"rules,omitempty"`
+		// Unset uses the controller default
+		SourceAddrTranslation *SourceAddrTranslation `json:"sourceAddressTranslation,omitempty"`
+		// Full paths of the persistence profiles
+		PersistenceProfile         string `json:"persistenceProfile,omitempty"`
+		FallbackPersistenceProfile string `json:"fallbackPersistenceProfile,omitempty"`
 
 		// iApp parameters
 		IApp                string                    `json:"iapp,omitempty"`
@@ -67,6 +77,12 @@ type (
 		IAppVariables       map[string]string         `json:"iappVariables,omitempty"`
 	}
 
+	// SNAT of a virtual server: automap, none or snat with a SNAT pool
+	SourceAddrTranslation struct {
+		Type string `json:"type"`
+		Pool string `json:"pool,omitempty"`
+	}
+
 	// Pool config
 	Pool struct {
 		Name            string   `json:"name"`
@@ -76,16 +92,27 @@ type (
 		ServicePort     int32    `json:"servicePort"`
 		PoolMemberAddrs []string `json:"poolMemberAddrs"`
 		MonitorNames    []string `json:"monitor"`
+		// Named port of the Service, resolved into ServicePort when synced
+		ServicePortName string `json:"-"`
 	}
 
 	// Pool health monitor
 	Monitor struct {
-		Name      string `json:"name"`
-		Partition string `json:"partition"`
-		Interval  int    `json:"interval,omitempty"`
-		Protocol  string `json:"protocol"`
-		Send      string `json:"send,omitempty"`
-		Timeout   int    `json:"timeout,omitempty"`
+		Name        string `json:"name"`
+		Partition   string `json:"partition"`
+		Interval    int    `json:"interval,omitempty"`
+		Protocol    string `json:"protocol"`
+		Send        string `json:"send,omitempty"`
+		Recv        string `json:"recv,omitempty"`
+		RecvDisable string `json:"recvDisable,omitempty"`
+		Timeout     int    `json:"timeout,omitempty"`
+		// Monitor interval while a member is up, and how long a member must
+		// pass before it is marked up
+		UpInterval  int `json:"upInterval,omitempty"`
+		TimeUntilUp int `json:"timeUntilUp,omitempty"`
+		// Alias to check instead of the pool member
+		AliasAddress string `json:"aliasAddress,omitempty"`
+		AliasPort    int32  `json:"aliasPort,omitempty"`
 	}
 	Monitors []Monitor
 
@@ -106,6 +133,7 @@ type (
 	Rule struct {
 		Name       string       `json:"name"`
 		FullURI    string       `json:"-"`
+		PathMatch  pathMatch    `json:"-"`
 		Ordinal    int          `json:"ordinal,omitempty"`
 		Actions    []*action    `json:"actions,omitempty"`
 		Conditions []*condition `json:"conditions,omitempty"`
@@ -113,32 +141,50 @@ type (
 
 	// action config for a Rule
 	action struct {
-		Name      string `json:"name"`
-		Pool      string `json:"pool",omitempty"`
-		HttpReply bool   `json:"httpReply,omitempty"`
-		Forward   bool   `json:"forward,omitempty"`
-		Location  string `json:"location,omitempty"`
-		Redirect  bool   `json:"redirect,omitempty"`
-		Request   bool   `json:"request,omitempty"`
-		Reset     bool   `json:"reset,omitempty"`
+		Name        string `json:"name"`
+		Pool        string `json:"pool",omitempty"`
+		HttpReply   bool   `json:"httpReply,omitempty"`
+		Expression  string `json:"expression,omitempty"`
+		Forward     bool   `json:"forward,omitempty"`
+		HTTPHost    bool   `json:"httpHost,omitempty"`
+		HTTPURI     bool   `json:"httpUri,omitempty"`
+		Location    string `json:"location,omitempty"`
+		Path        string `json:"path,omitempty"`
+		Redirect    bool   `json:"redirect,omitempty"`
+		Replace     bool   `json:"replace,omitempty"`
+		Request     bool   `json:"request,omitempty"`
+		Reset       bool   `json:"reset,omitempty"`
+		SetVariable bool   `json:"setVariable,omitempty"`
+		Tcl         bool   `json:"tcl,omitempty"`
+		TmName      string `json:"tmName,omitempty"`
+		Value       string `json:"value,omitempty"`
 	}
 
 	// condition config for a Rule
 	condition struct {
 		Name            string   `json:"name"`
 		CaseInsensitive bool     `json:"caseInsensitive,omitempty"`
+		Contains        bool     `json:"contains,omitempty"`
 		Equals          bool     `json:"equals,omitempty"`
 		EndsWith        bool     `json:"endsWith,omitempty"`
 		External        bool     `json:"external,omitempty"`
+		HTTPCookie      bool     `json:"httpCookie,omitempty"`
+		HTTPHeader      bool     `json:"httpHeader,omitempty"`
 		HTTPHost        bool     `json:"httpHost,omitempty"`
+		HTTPMethod      bool     `json:"httpMethod,omitempty"`
 		Host            bool     `json:"host,omitempty"`
 		HTTPURI         bool     `json:"httpUri,omitempty"`
 		Index           int      `json:"index,omitempty"`
+		Matches         bool     `json:"matches,omitempty"`
+		Path            bool     `json:"path,omitempty"`
 		PathSegment     bool     `json:"pathSegment,omitempty"`
 		Present         bool     `json:"present,omitempty"`
+		QueryParameter  bool     `json:"queryParameter,omitempty"`
 		Remote          bool     `json:"remote,omitempty"`
 		Request         bool     `json:"request,omitempty"`
 		Scheme          bool     `json:"scheme,omitempty"`
+		StartsWith      bool     `json:"startsWith,omitempty"`
+		TmName          string   `json:"tmName,omitempty"`
 		Values          []string `json:"values"`
 	}
 
@@ -194,16 +240,74 @@ type (
 	// Used to unmarshal ConfigMap data
 	ConfigMap struct {
 		VirtualServer struct {
-			Backend  configMapBackend `json:"backend"`
-			Frontend Virtual          `json:"frontend"`
+			Backend  configMapBackend  `json:"backend"`
+			Frontend configMapFrontend `json:"frontend"`
+			// Replaces backend with a pool for each backend
+			Backends       []configMapBackend `json:"backends,omitempty"`
+			DefaultBackend string             `json:"defaultBackend,omitempty"`
+			Rules          []configMapRule    `json:"rules,omitempty"`
 		} `json:"virtualServer"`
 	}
 
+	// References to existing iRules and policies replace the generated
+	// output fields of the virtual
+	configMapFrontend struct {
+		Virtual
+		IRules      []string              `json:"iRules,omitempty"`
+		Policies    []string              `json:"policies,omitempty"`
+		Snat        string                `json:"snat,omitempty"`
+		Persistence *configMapPersistence `json:"persistence,omitempty"`
+	}
+
+	// Persistence profiles of a ConfigMap frontend, by built-in name or path
+	configMapPersistence struct {
+		Default  string `json:"default"`
+		Fallback string `json:"fallback,omitempty"`
+	}
+
 	configMapBackend struct {
-		ServiceName     string    `json:"serviceName"`
-		ServicePort     int32     `json:"servicePort"`
-		PoolMemberAddrs []string  `json:"poolMemberAddrs"`
-		HealthMonitors  []Monitor `json:"healthMonitors,omitempty"`
+		// Name and Balance are only used in the backends list
+		Name            string             `json:"name,omitempty"`
+		ServiceName     string             `json:"serviceName"`
+		ServicePort     intstr.IntOrString `json:"servicePort"`
+		PoolMemberAddrs []string           `json:"poolMemberAddrs"`
+		HealthMonitors  []Monitor          `json:"healthMonitors,omitempty"`
+		Balance         string             `json:"balance,omitempty"`
+	}
+
+	// L7 rule forwarding requests for a host and path to a backend
+	configMapRule struct {
+		Host       string             `json:"host,omitempty"`
+		Path       string             `json:"path,omitempty"`
+		Conditions []requestCondition `json:"conditions,omitempty"`
+		Backend    string             `json:"backend"`
+	}
+
+	// Condition of a rule on a header, cookie, query parameter or the method
+	// of a request, which matches one of the values
+	requestCondition struct {
+		Type     string   `json:"type"`
+		Name     string   `json:"name,omitempty"`
+		Operator string   `json:"operator,omitempty"`
+		Values   []string `json:"values"`
+	}
+
+	// Conditions of the rules for a path of an Ingress, from the conditions
+	// annotation
+	ingressRuleConditions struct {
+		Path        string             `json:"path"`
+		ServiceName string             `json:"serviceName,omitempty"`
+		Conditions  []requestCondition `json:"conditions"`
+	}
+
+	// Rewrites and redirects of the rules for the paths of an Ingress, from
+	// its annotations
+	ingressRuleActions struct {
+		RewriteTarget string
+		RewriteHost   string
+		AppRoot       string
+		RedirectURL   string
+		RedirectCode  int
 	}
 
 	// This is the format for each item in the health monitor annotation used
@@ -211,8 +315,16 @@ type (
 	IngressHealthMonitor struct {
 		Path     string `json:"path"`
 		Interval int    `json:"interval"`
+		Protocol string `json:"protocol,omitempty"`
 		Send     string `json:"send"`
-		Timeout  int    `json:"timeout"`
+		Recv     string `json:"recv,omitempty"`
+		// Response that marks the member as disabled
+		RecvDisable  string `json:"recvDisable,omitempty"`
+		Timeout      int    `json:"timeout"`
+		UpInterval   int    `json:"upInterval,omitempty"`
+		TimeUntilUp  int    `json:"timeUntilUp,omitempty"`
+		AliasAddress string `json:"aliasAddress,omitempty"`
+		AliasPort    int32  `json:"aliasPort,omitempty"`
 	}
 	IngressHealthMonitors []IngressHealthMonitor
 
@@ -19,7 +19,6 @@ package appmanager
 import (
 	routeapi "github.com/openshift/origin/pkg/route/api"
 	"k8s.io/client-go/pkg/api/v1"
-	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
 )
 
 func (appMgr *Manager) checkValidConfigMap(
@@ -29,7 +28,7 @@ func (appMgr *Manager) checkValidConfigMap(
 	// one we care about.
 	cm := obj.(*v1.ConfigMap)
 	namespace := cm.ObjectMeta.Namespace
-	_, ok := appMgr.getNamespaceInformer(namespace)
+	appInf, ok := appMgr.getNamespaceInformer(namespace)
 	if !ok {
 		// Not watching this namespace
 		return false, nil
@@ -42,12 +41,34 @@ func (appMgr *Manager) checkValidConfigMap(
 		}
 		return false, nil
 	}
-	key := &serviceQueueKey{
-		ServiceName: cfg.Pools[0].ServiceName,
-		Namespace:   namespace,
-	}
 	var keyList []*serviceQueueKey
-	keyList = append(keyList, key)
+	for _, pool := range cfg.Pools {
+		keyList = append(keyList, &serviceQueueKey{
+			ServiceName: pool.ServiceName,
+			Namespace:   namespace,
+		})
+	}
+	// Delete the config stored for any service that the ConfigMap no longer
+	// uses. Named ports are compared by their number, and unknown names are
+	// reported when the ConfigMap is synced.
+	cfg.resolveServicePorts(namespace, appInf.svcInformer.GetIndexer())
+	rsName := cfg.Virtual.VirtualServerName
+	appMgr.resources.Lock()
+	defer appMgr.resources.Unlock()
+	_, keys := appMgr.resources.GetAllWithName(rsName)
+	for _, key := range keys {
+		used := false
+		for _, pool := range cfg.Pools {
+			if pool.ServiceName == key.ServiceName &&
+				pool.ServicePort == key.ServicePort {
+				used = true
+				break
+			}
+		}
+		if !used {
+			appMgr.resources.Delete(key, rsName)
+		}
+	}
 	return true, keyList
 }
 
@@ -94,7 +115,10 @@ func (appMgr *Manager) checkValidEndpoints(
 func (appMgr *Manager) checkValidIngress(
 	obj interface{},
 ) (bool, []*serviceQueueKey) {
-	ing := obj.(*v1beta1.Ingress)
+	ing, ours := appMgr.prepareIngress(obj)
+	if nil == ing {
+		return false, nil
+	}
 	namespace := ing.ObjectMeta.Namespace
 	appInf, ok := appMgr.getNamespaceInformer(namespace)
 	if !ok {
@@ -104,28 +128,52 @@ func (appMgr *Manager) checkValidIngress(
 	var allKeys []*serviceQueueKey
 	appMgr.resources.Lock()
 	defer appMgr.resources.Unlock()
+	if !ours {
+		// The Ingress may have been moved to another class; remove any
+		// virtual servers it had
+		deleted := false
+		for _, portStruct := range appMgr.virtualPorts(ing) {
+			rsName := formatIngressVSName(ing, portStruct.protocol)
+			_, keys := appMgr.resources.GetAllWithName(rsName)
+			for _, key := range keys {
+				appMgr.resources.Delete(key, rsName)
+				deleted = true
+			}
+		}
+		if deleted {
+			appMgr.outputConfigLocked()
+		}
+		return false, nil
+	}
+	// The partition is checked when the Ingress is synced; the pools here
+	// only identify the services
+	partition, err := appMgr.resourcePartition(
+		appMgr.namespacePartition(namespace),
+		ing.ObjectMeta.Annotations[partitionAnnotation])
+	if nil != err {
+		partition = DEFAULT_PARTITION
+	}
+	ingresses, canaries := appMgr.namespaceIngresses(namespace, appInf)
+	if _, canary, _ := ingressCanaryWeight(ing); canary {
+		return true, appMgr.canaryServiceKeysLocked(
+			ing, ingresses, canaries, appInf)
+	}
 	for _, portStruct := range appMgr.virtualPorts(ing) {
 		var keyList []*serviceQueueKey
-		rsCfg := createRSConfigFromIngress(ing, namespace,
+		rsCfg := createRSConfigFromIngress(ing, partition, namespace,
 			appInf.svcInformer.GetIndexer(), portStruct)
-		rsName := rsCfg.Virtual.VirtualServerName
-		if rsCfg == nil {
-			if nil == ing.Spec.Rules { //single-service
-				serviceName := ing.Spec.Backend.ServiceName
-				servicePort := ing.Spec.Backend.ServicePort.IntVal
-				sKey := serviceKey{serviceName, servicePort, ing.ObjectMeta.Namespace}
-				if _, ok := appMgr.resources.Get(sKey, rsName); ok {
-					appMgr.resources.Delete(sKey, rsName)
-				}
-			} else { //multi-service
-				_, keys := appMgr.resources.GetAllWithName(rsName)
-				for _, key := range keys {
-					appMgr.resources.Delete(key, rsName)
-				}
+		if nil == rsCfg {
+			// No backends we can use, so remove what the Ingress had
+			rsName := formatIngressVSName(ing, portStruct.protocol)
+			_, keys := appMgr.resources.GetAllWithName(rsName)
+			for _, key := range keys {
+				appMgr.resources.Delete(key, rsName)
 			}
-			appMgr.outputConfig()
+			appMgr.outputConfigLocked()
 			return false, nil
 		}
+		addIngressCanaries(rsCfg, ing, canaries, appInf.svcInformer.GetIndexer())
+		rsName := rsCfg.Virtual.VirtualServerName
 
 		for _, pool := range rsCfg.Pools {
 			key := &serviceQueueKey{
@@ -0,0 +1,308 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package leaderelection elects a single leader among controller replicas
+// using a ConfigMap as the lock. The lock record is stored in the same
+// annotation, and in the same format, as the Kubernetes leader election
+// library uses so the usual tooling can show the current holder.
+package leaderelection
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const LeaderAnnotation = "control-plane.alpha.kubernetes.io/leader"
+
+// Contents of the lock annotation
+type LeaderElectionRecord struct {
+	HolderIdentity       string      `json:"holderIdentity"`
+	LeaseDurationSeconds int         `json:"leaseDurationSeconds"`
+	AcquireTime          metav1.Time `json:"acquireTime"`
+	RenewTime            metav1.Time `json:"renewTime"`
+	LeaderTransitions    int         `json:"leaderTransitions"`
+}
+
+type Config struct {
+	KubeClient kubernetes.Interface
+	// ConfigMap used as the lock
+	Namespace string
+	Name      string
+	// Unique name of this replica
+	Identity string
+	// How long followers wait after the last renewal before taking over
+	LeaseDuration time.Duration
+	// How long the leader keeps retrying a failed renewal before giving up
+	RenewDeadline time.Duration
+	// Time between attempts to acquire or renew the lock
+	RetryPeriod time.Duration
+	// Called in a new goroutine when this replica becomes the leader
+	OnStartedLeading func()
+	// Called when this replica fails to renew the lock. It is not called
+	// when Run stops because its stop channel was closed.
+	OnStoppedLeading func()
+}
+
+type LeaderElector struct {
+	config Config
+
+	// Last record seen and when it was seen, by the local clock, so clock
+	// skew between replicas does not matter.
+	observedRecord LeaderElectionRecord
+	observedTime   time.Time
+
+	leaderMutex sync.Mutex
+	isLeader    bool
+}
+
+func NewLeaderElector(config Config) (*LeaderElector, error) {
+	if nil == config.KubeClient {
+		return nil, fmt.Errorf("Leader election requires a kubernetes client")
+	}
+	if 0 == len(config.Name) || 0 == len(config.Namespace) {
+		return nil, fmt.Errorf("Leader election requires a lock name and namespace")
+	}
+	if 0 == len(config.Identity) {
+		return nil, fmt.Errorf("Leader election requires an identity")
+	}
+	if config.LeaseDuration <= config.RenewDeadline {
+		return nil, fmt.Errorf(
+			"Leader election lease duration must be greater than the renew deadline")
+	}
+	if config.RenewDeadline <= config.RetryPeriod {
+		return nil, fmt.Errorf(
+			"Leader election renew deadline must be greater than the retry period")
+	}
+	return &LeaderElector{config: config}, nil
+}
+
+// Whether this replica currently holds the lock
+func (le *LeaderElector) IsLeader() bool {
+	le.leaderMutex.Lock()
+	defer le.leaderMutex.Unlock()
+	return le.isLeader
+}
+
+func (le *LeaderElector) setLeader(isLeader bool) {
+	le.leaderMutex.Lock()
+	defer le.leaderMutex.Unlock()
+	le.isLeader = isLeader
+}
+
+// Wait to acquire the lock, then keep renewing it until renewal fails or
+// stopCh is closed. A leader that is stopped releases the lock so another
+// replica can take over without waiting for the lease to expire.
+func (le *LeaderElector) Run(stopCh <-chan struct{}) {
+	if !le.acquire(stopCh) {
+		return
+	}
+	le.setLeader(true)
+	if nil != le.config.OnStartedLeading {
+		go le.config.OnStartedLeading()
+	}
+
+	lost := le.renew(stopCh)
+	le.setLeader(false)
+	if lost {
+		log.Errorf("Lost leader election lock %s/%s",
+			le.config.Namespace, le.config.Name)
+		if nil != le.config.OnStoppedLeading {
+			le.config.OnStoppedLeading()
+		}
+		return
+	}
+	le.release()
+}
+
+func (le *LeaderElector) acquire(stopCh <-chan struct{}) bool {
+	log.Infof("Attempting to acquire leader election lock %s/%s as %s",
+		le.config.Namespace, le.config.Name, le.config.Identity)
+	for {
+		if le.tryAcquireOrRenew() {
+			log.Infof("Acquired leader election lock %s/%s",
+				le.config.Namespace, le.config.Name)
+			return true
+		}
+		select {
+		case <-stopCh:
+			return false
+		case <-time.After(le.config.RetryPeriod):
+		}
+	}
+}
+
+// Renew the lock until stopCh is closed, returning true if the lock was lost
+func (le *LeaderElector) renew(stopCh <-chan struct{}) bool {
+	for {
+		deadline := time.Now().Add(le.config.RenewDeadline)
+		renewed := false
+		for !renewed && time.Now().Before(deadline) {
+			renewed = le.tryAcquireOrRenew()
+			if !renewed {
+				select {
+				case <-stopCh:
+					return false
+				case <-time.After(le.config.RetryPeriod):
+				}
+			}
+		}
+		if !renewed {
+			return true
+		}
+		select {
+		case <-stopCh:
+			return false
+		case <-time.After(le.config.RetryPeriod):
+		}
+	}
+}
+
+// Clear the holder so followers acquire the lock on their next attempt
+func (le *LeaderElector) release() {
+	cm, err := le.configMaps().Get(le.config.Name, metav1.GetOptions{})
+	if nil != err {
+		log.Warningf("Failed to release leader election lock: %v", err)
+		return
+	}
+	record, err := getRecord(cm)
+	if nil != err || record.HolderIdentity != le.config.Identity {
+		return
+	}
+	record.HolderIdentity = ""
+	record.LeaseDurationSeconds = 1
+	record.RenewTime = metav1.Now()
+	err = setRecord(cm, record)
+	if nil == err {
+		_, err = le.configMaps().Update(cm)
+	}
+	if nil != err {
+		log.Warningf("Failed to release leader election lock: %v", err)
+		return
+	}
+	log.Infof("Released leader election lock %s/%s",
+		le.config.Namespace, le.config.Name)
+}
+
+func (le *LeaderElector) tryAcquireOrRenew() bool {
+	now := metav1.Now()
+	record := LeaderElectionRecord{
+		HolderIdentity:       le.config.Identity,
+		LeaseDurationSeconds: int(le.config.LeaseDuration / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	cm, err := le.configMaps().Get(le.config.Name, metav1.GetOptions{})
+	if nil != err {
+		if !errors.IsNotFound(err) {
+			log.Warningf("Error getting leader election lock: %v", err)
+			return false
+		}
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      le.config.Name,
+				Namespace: le.config.Namespace,
+			},
+		}
+		err = setRecord(cm, record)
+		if nil == err {
+			_, err = le.configMaps().Create(cm)
+		}
+		if nil != err {
+			log.Warningf("Error creating leader election lock: %v", err)
+			return false
+		}
+		le.observe(record)
+		return true
+	}
+
+	oldRecord, err := getRecord(cm)
+	if nil != err {
+		log.Warningf("Ignoring invalid leader election record: %v", err)
+	}
+	if !reflect.DeepEqual(oldRecord, le.observedRecord) {
+		le.observe(oldRecord)
+	}
+	if len(oldRecord.HolderIdentity) > 0 &&
+		oldRecord.HolderIdentity != le.config.Identity {
+		lease := time.Duration(oldRecord.LeaseDurationSeconds) * time.Second
+		if le.observedTime.Add(lease).After(time.Now()) {
+			// Another replica holds an unexpired lease
+			return false
+		}
+	}
+
+	if oldRecord.HolderIdentity == le.config.Identity {
+		record.AcquireTime = oldRecord.AcquireTime
+		record.LeaderTransitions = oldRecord.LeaderTransitions
+	} else {
+		record.LeaderTransitions = oldRecord.LeaderTransitions + 1
+	}
+	err = setRecord(cm, record)
+	if nil == err {
+		// The update fails with a conflict if another replica changed the
+		// lock since we read it.
+		_, err = le.configMaps().Update(cm)
+	}
+	if nil != err {
+		log.Warningf("Error updating leader election lock: %v", err)
+		return false
+	}
+	le.observe(record)
+	return true
+}
+
+func (le *LeaderElector) observe(record LeaderElectionRecord) {
+	le.observedRecord = record
+	le.observedTime = time.Now()
+}
+
+func (le *LeaderElector) configMaps() corev1.ConfigMapInterface {
+	return le.config.KubeClient.Core().ConfigMaps(le.config.Namespace)
+}
+
+func getRecord(cm *v1.ConfigMap) (LeaderElectionRecord, error) {
+	var record LeaderElectionRecord
+	raw, found := cm.Annotations[LeaderAnnotation]
+	if !found {
+		return record, nil
+	}
+	err := json.Unmarshal([]byte(raw), &record)
+	return record, err
+}
+
+func setRecord(cm *v1.ConfigMap, record LeaderElectionRecord) error {
+	raw, err := json.Marshal(record)
+	if nil != err {
+		return err
+	}
+	if nil == cm.Annotations {
+		cm.Annotations = make(map[string]string)
+	}
+	cm.Annotations[LeaderAnnotation] = string(raw)
+	return nil
+}
@@ -0,0 +1,182 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package leaderelection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestElector(
+	t *testing.T,
+	client kubernetes.Interface,
+	identity string,
+	started chan<- string,
+	stopped chan<- string,
+) *LeaderElector {
+	le, err := NewLeaderElector(Config{
+		KubeClient:    client,
+		Namespace:     "kube-system",
+		Name:          "k8s-bigip-ctlr",
+		Identity:      identity,
+		LeaseDuration: 2 * time.Second,
+		RenewDeadline: time.Second,
+		RetryPeriod:   50 * time.Millisecond,
+		OnStartedLeading: func() {
+			started <- identity
+		},
+		OnStoppedLeading: func() {
+			stopped <- identity
+		},
+	})
+	require.NoError(t, err)
+	return le
+}
+
+func getTestRecord(t *testing.T, client kubernetes.Interface) LeaderElectionRecord {
+	cm, err := client.Core().ConfigMaps("kube-system").Get(
+		"k8s-bigip-ctlr", metav1.GetOptions{})
+	require.NoError(t, err)
+	record, err := getRecord(cm)
+	require.NoError(t, err)
+	return record
+}
+
+func waitForLeader(t *testing.T, started <-chan string) string {
+	select {
+	case id := <-started:
+		return id
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for a leader")
+	}
+	return ""
+}
+
+func TestNewLeaderElectorValidation(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	valid := Config{
+		KubeClient:    client,
+		Namespace:     "kube-system",
+		Name:          "lock",
+		Identity:      "pod1",
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+	_, err := NewLeaderElector(valid)
+	assert.NoError(t, err)
+
+	invalid := []func(c *Config){
+		func(c *Config) { c.KubeClient = nil },
+		func(c *Config) { c.Name = "" },
+		func(c *Config) { c.Identity = "" },
+		func(c *Config) { c.LeaseDuration = c.RenewDeadline },
+		func(c *Config) { c.RetryPeriod = c.RenewDeadline },
+	}
+	for _, change := range invalid {
+		cfg := valid
+		change(&cfg)
+		_, err = NewLeaderElector(cfg)
+		assert.Error(t, err)
+	}
+}
+
+func TestLeaderElectionFailover(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	started := make(chan string, 2)
+	stopped := make(chan string, 2)
+
+	le1 := newTestElector(t, client, "pod1", started, stopped)
+	stop1 := make(chan struct{})
+	done1 := make(chan struct{})
+	go func() {
+		le1.Run(stop1)
+		close(done1)
+	}()
+	assert.Equal(t, "pod1", waitForLeader(t, started))
+	assert.True(t, le1.IsLeader())
+
+	record := getTestRecord(t, client)
+	assert.Equal(t, "pod1", record.HolderIdentity)
+	assert.Equal(t, 2, record.LeaseDurationSeconds)
+
+	// The follower waits while the leader keeps renewing
+	le2 := newTestElector(t, client, "pod2", started, stopped)
+	stop2 := make(chan struct{})
+	defer close(stop2)
+	go le2.Run(stop2)
+	time.Sleep(300 * time.Millisecond)
+	assert.False(t, le2.IsLeader())
+	assert.Equal(t, "pod1", getTestRecord(t, client).HolderIdentity)
+
+	// Stopping the leader releases the lock and the follower takes over
+	// well before the lease would have expired
+	close(stop1)
+	<-done1
+	assert.False(t, le1.IsLeader())
+	stoppedAt := time.Now()
+	assert.Equal(t, "pod2", waitForLeader(t, started))
+	assert.True(t, time.Since(stoppedAt) < time.Second)
+	assert.True(t, le2.IsLeader())
+
+	record = getTestRecord(t, client)
+	assert.Equal(t, "pod2", record.HolderIdentity)
+	assert.Equal(t, 1, record.LeaderTransitions)
+
+	// A clean stop is not reported as lost leadership
+	assert.Len(t, stopped, 0)
+}
+
+func TestLeaderElectionLost(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	started := make(chan string, 1)
+	stopped := make(chan string, 1)
+
+	le := newTestElector(t, client, "pod1", started, stopped)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go le.Run(stopCh)
+	waitForLeader(t, started)
+
+	// Another replica takes the lock, e.g. after this one was partitioned
+	cm, err := client.Core().ConfigMaps("kube-system").Get(
+		"k8s-bigip-ctlr", metav1.GetOptions{})
+	require.NoError(t, err)
+	err = setRecord(cm, LeaderElectionRecord{
+		HolderIdentity:       "pod2",
+		LeaseDurationSeconds: 60,
+		RenewTime:            metav1.Now(),
+	})
+	require.NoError(t, err)
+	_, err = client.Core().ConfigMaps("kube-system").Update(cm)
+	require.NoError(t, err)
+
+	select {
+	case id := <-stopped:
+		assert.Equal(t, "pod1", id)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for leadership to be lost")
+	}
+	assert.False(t, le.IsLeader())
+}
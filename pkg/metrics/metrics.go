@@ -0,0 +1,104 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const namespace = "k8s_bigip_ctlr"
+
+// Results of a config section write
+const (
+	WriteSuccess = "success"
+	WriteFailure = "failure"
+	WriteTimeout = "timeout"
+)
+
+var (
+	// Time spent in each syncVirtualServer call
+	SyncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_virtual_server_duration_seconds",
+		Help:      "Time taken to sync the virtual servers for a service.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// Totals of the per-sync stats, labelled by stat name, e.g. vs_updated
+	SyncStats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_stats_total",
+		Help:      "Virtual servers, custom profiles and data groups found, updated or deleted while syncing.",
+	}, []string{"stat"})
+
+	// Config section writes, labelled by section and result
+	ConfigWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_writes_total",
+		Help:      "Config sections written for the driver, by section and result.",
+	}, []string{"section", "result"})
+
+	// Errors listing nodes in the node poller
+	NodePollErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "node_poll_errors_total",
+		Help:      "Errors polling the cluster for nodes.",
+	})
+
+	// Restarts of the config driver sub-process
+	DriverRestarts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "driver_restarts_total",
+		Help:      "Times the config driver was restarted after exiting.",
+	})
+
+	// Objects in the last resources section written, labelled by object type
+	ConfigObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_objects",
+		Help:      "Objects in the last BIG-IP config written, by type.",
+	}, []string{"type"})
+
+	// Result of the driver's last apply to each BIG-IP, labelled by device
+	DeviceIncomplete = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "bigip_device_incomplete_partitions",
+		Help:      "Partitions that failed to apply in the last apply to each BIG-IP device.",
+	}, []string{"device"})
+	DeviceLastApply = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "bigip_device_last_apply_timestamp_seconds",
+		Help:      "Time of the last apply to each BIG-IP device.",
+	}, []string{"device"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		SyncDuration,
+		SyncStats,
+		ConfigWrites,
+		NodePollErrors,
+		DriverRestarts,
+		ConfigObjects,
+		DeviceIncomplete,
+		DeviceLastApply,
+	)
+	// Queues read the provider when they are created, so this has to be in
+	// place before any app manager exists.
+	workqueue.SetProvider(newWorkqueueProvider(prometheus.DefaultRegisterer))
+}
@@ -0,0 +1,122 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Find the metric in family name with the given queue label
+func findQueueMetric(
+	t *testing.T,
+	gatherer prometheus.Gatherer,
+	name string,
+	queue string,
+) *dto.Metric {
+	families, err := gatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "queue" && label.GetValue() == queue {
+					return m
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestWorkqueueProvider(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	wp := newWorkqueueProvider(reg)
+
+	depth := wp.NewDepthMetric("test-queue")
+	depth.Inc()
+	depth.Inc()
+	depth.Dec()
+	wp.NewAddsMetric("test-queue").Inc()
+	wp.NewRetriesMetric("test-queue").Inc()
+	wp.NewLatencyMetric("test-queue").Observe(10)
+	wp.NewWorkDurationMetric("test-queue").Observe(20)
+
+	// A second queue with the same name shares the metrics
+	wp.NewAddsMetric("test-queue").Inc()
+
+	m := findQueueMetric(t, reg, "k8s_bigip_ctlr_workqueue_depth", "test-queue")
+	require.NotNil(t, m)
+	assert.Equal(t, float64(1), m.GetGauge().GetValue())
+
+	m = findQueueMetric(t, reg, "k8s_bigip_ctlr_workqueue_adds_total",
+		"test-queue")
+	require.NotNil(t, m)
+	assert.Equal(t, float64(2), m.GetCounter().GetValue())
+
+	m = findQueueMetric(t, reg, "k8s_bigip_ctlr_workqueue_retries_total",
+		"test-queue")
+	require.NotNil(t, m)
+	assert.Equal(t, float64(1), m.GetCounter().GetValue())
+
+	m = findQueueMetric(t, reg,
+		"k8s_bigip_ctlr_workqueue_queue_latency_microseconds", "test-queue")
+	require.NotNil(t, m)
+	assert.Equal(t, uint64(1), m.GetSummary().GetSampleCount())
+
+	m = findQueueMetric(t, reg,
+		"k8s_bigip_ctlr_workqueue_work_duration_microseconds", "test-queue")
+	require.NotNil(t, m)
+	assert.Equal(t, float64(20), m.GetSummary().GetSampleSum())
+}
+
+func TestDefaultWorkqueueProvider(t *testing.T) {
+	queue := workqueue.NewNamedRateLimitingQueue(
+		workqueue.DefaultControllerRateLimiter(), "default-provider-test")
+	defer queue.ShutDown()
+	queue.Add("item")
+
+	m := findQueueMetric(t, prometheus.DefaultGatherer,
+		"k8s_bigip_ctlr_workqueue_depth", "default-provider-test")
+	require.NotNil(t, m, "Queues should report to the default registry")
+	assert.Equal(t, float64(1), m.GetGauge().GetValue())
+}
+
+func TestDriverRestarts(t *testing.T) {
+	counterValue := func() float64 {
+		families, err := prometheus.DefaultGatherer.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "k8s_bigip_ctlr_driver_restarts_total" {
+				return family.GetMetric()[0].GetCounter().GetValue()
+			}
+		}
+		t.Fatal("Driver restarts should be registered")
+		return 0
+	}
+	before := counterValue()
+	DriverRestarts.Inc()
+	DriverRestarts.Inc()
+	assert.Equal(t, before+2, counterValue())
+}
@@ -0,0 +1,96 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Workqueue metrics provider backed by Prometheus. Every queue shares the
+// same metrics, labelled by queue name, so creating several queues with the
+// same name (e.g. one app manager per unit test) does not fail to register.
+type workqueueProvider struct {
+	depth        *prometheus.GaugeVec
+	adds         *prometheus.CounterVec
+	latency      *prometheus.SummaryVec
+	workDuration *prometheus.SummaryVec
+	retries      *prometheus.CounterVec
+}
+
+func newWorkqueueProvider(reg prometheus.Registerer) *workqueueProvider {
+	wp := &workqueueProvider{
+		depth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "depth",
+			Help:      "Current depth of the workqueue.",
+		}, []string{"queue"}),
+		adds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "adds_total",
+			Help:      "Items added to the workqueue.",
+		}, []string{"queue"}),
+		latency: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "queue_latency_microseconds",
+			Help:      "Time an item stays in the workqueue before being processed.",
+		}, []string{"queue"}),
+		workDuration: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "work_duration_microseconds",
+			Help:      "Time taken to process an item from the workqueue.",
+		}, []string{"queue"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "retries_total",
+			Help:      "Items requeued with rate limiting.",
+		}, []string{"queue"}),
+	}
+	reg.MustRegister(
+		wp.depth,
+		wp.adds,
+		wp.latency,
+		wp.workDuration,
+		wp.retries,
+	)
+	return wp
+}
+
+func (wp *workqueueProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return wp.depth.WithLabelValues(name)
+}
+
+func (wp *workqueueProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return wp.adds.WithLabelValues(name)
+}
+
+func (wp *workqueueProvider) NewLatencyMetric(name string) workqueue.SummaryMetric {
+	return wp.latency.WithLabelValues(name)
+}
+
+func (wp *workqueueProvider) NewWorkDurationMetric(name string) workqueue.SummaryMetric {
+	return wp.workDuration.WithLabelValues(name)
+}
+
+func (wp *workqueueProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return wp.retries.WithLabelValues(name)
+}
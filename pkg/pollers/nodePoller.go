@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/F5Networks/k8s-bigip-ctlr/pkg/metrics"
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -174,6 +175,9 @@ func (np *nodePoller) poller() {
 			nodes, err := np.kubeClient.Core().Nodes().List(metav1.ListOptions{})
 			np.nodeCache = nodes.Items
 			np.lastError = err
+			if nil != err {
+				metrics.NodePollErrors.Inc()
+			}
 
 			for _, listener := range listeners {
 				log.Debugf("NodePoller (%p) notifying listener: %+v", np, listener)
@@ -25,6 +25,7 @@ import (
 	"sync"
 	"time"
 
+	netv1 "github.com/F5Networks/k8s-bigip-ctlr/pkg/apis/networking/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/pollers"
 
 	routeapi "github.com/openshift/origin/pkg/route/api"
@@ -148,6 +149,46 @@ func NewIngress(id, rv, namespace string,
 	}
 }
 
+// NewNetworkingIngress returns a new networking.k8s.io/v1 ingress object
+func NewNetworkingIngress(id, rv, namespace string,
+	spec netv1.IngressSpec,
+	annotations map[string]string) *netv1.Ingress {
+	return &netv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            id,
+			ResourceVersion: rv,
+			Namespace:       namespace,
+			Annotations:     annotations,
+		},
+		Spec: spec,
+	}
+}
+
+// NewIngressClass returns a new ingress class object
+func NewIngressClass(id, rv, controller string,
+	params *netv1.IngressClassParametersReference,
+	annotations map[string]string) *netv1.IngressClass {
+	return &netv1.IngressClass{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "IngressClass",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            id,
+			ResourceVersion: rv,
+			Annotations:     annotations,
+		},
+		Spec: netv1.IngressClassSpec{
+			Controller: controller,
+			Parameters: params,
+		},
+	}
+}
+
 // NewRoute returns a new route object
 func NewRoute(id, rv, namespace string, spec routeapi.RouteSpec) *routeapi.Route {
 	return &routeapi.Route{
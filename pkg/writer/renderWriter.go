@@ -0,0 +1,188 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Writer used in dry-run mode. Instead of writing the config for the python
+// driver it renders each section to out, followed by a diff against the
+// previous version of the same section.
+type renderWriter struct {
+	out      io.Writer
+	name     string
+	now      func() time.Time
+	sections map[string]string
+	stopped  bool
+	sync.Mutex
+}
+
+func NewRenderWriter(out io.Writer, name string) Writer {
+	rw := &renderWriter{
+		out:      out,
+		name:     name,
+		now:      time.Now,
+		sections: make(map[string]string),
+	}
+	log.Infof("RenderWriter started: %p", rw)
+	return rw
+}
+
+func (rw *renderWriter) GetOutputFilename() string {
+	return rw.name
+}
+
+func (rw *renderWriter) Stop() {
+	rw.Lock()
+	defer rw.Unlock()
+	rw.stopped = true
+	log.Infof("RenderWriter stopped: %p", rw)
+}
+
+func (rw *renderWriter) SendSection(
+	name string,
+	obj interface{},
+) (<-chan struct{}, <-chan error, error) {
+	if 0 == len(name) {
+		return nil, nil, fmt.Errorf("cannot marshal section without name")
+	}
+
+	rw.Lock()
+	defer rw.Unlock()
+	if rw.stopped {
+		return nil, nil, fmt.Errorf("RenderWriter (%p) stopped", rw)
+	}
+
+	// Responses are buffered so callers that stop waiting do not leak
+	// a goroutine.
+	done := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+
+	output, err := renderSection(obj)
+	if nil != err {
+		log.Warningf("RenderWriter (%p) received bad json for section (%s): %v",
+			rw, name, err)
+		errCh <- err
+		return done, errCh, nil
+	}
+
+	previous, seen := rw.sections[name]
+	if seen && previous == output {
+		log.Debugf("RenderWriter (%p) section (%s) unchanged", rw, name)
+		done <- struct{}{}
+		return done, errCh, nil
+	}
+	rw.sections[name] = output
+
+	err = rw.write(name, previous, output, seen)
+	if nil != err {
+		log.Warningf("RenderWriter (%p) failed to write section (%s): %v",
+			rw, name, err)
+		errCh <- err
+	} else {
+		done <- struct{}{}
+	}
+	return done, errCh, nil
+}
+
+func (rw *renderWriter) write(
+	name string,
+	previous string,
+	output string,
+	seen bool,
+) error {
+	stamp := rw.now().UTC().Format(time.RFC3339)
+	_, err := fmt.Fprintf(rw.out, "### %s section %s\n%s", stamp, name, output)
+	if nil != err || !seen {
+		return err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(previous),
+		B:        difflib.SplitLines(output),
+		FromFile: name + " (previous)",
+		ToFile:   name + " (current)",
+		Context:  3,
+	})
+	if nil != err {
+		return err
+	}
+	_, err = fmt.Fprintf(rw.out, "### %s diff %s\n%s", stamp, name, diff)
+	return err
+}
+
+// Marshal obj as indented JSON. Top level lists of named objects, such as
+// virtualServers or pools, are sorted by partition and name, since their
+// order comes from map iteration and would otherwise show up in the diff.
+func renderSection(obj interface{}) (string, error) {
+	raw, err := json.Marshal(obj)
+	if nil != err {
+		return "", err
+	}
+	var section interface{}
+	err = json.Unmarshal(raw, &section)
+	if nil != err {
+		return "", err
+	}
+	if fields, ok := section.(map[string]interface{}); ok {
+		for _, value := range fields {
+			if list, ok := value.([]interface{}); ok {
+				sortByName(list)
+			}
+		}
+	}
+	output, err := json.MarshalIndent(section, "", "  ")
+	if nil != err {
+		return "", err
+	}
+	return string(output) + "\n", nil
+}
+
+func sortByName(list []interface{}) {
+	key := func(item interface{}) (string, bool) {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		name, ok := obj["name"].(string)
+		if !ok {
+			return "", false
+		}
+		partition, _ := obj["partition"].(string)
+		return partition + "/" + name, true
+	}
+	for _, item := range list {
+		if _, ok := key(item); !ok {
+			return
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		ki, _ := key(list[i])
+		kj, _ := key(list[j])
+		return ki < kj
+	})
+}
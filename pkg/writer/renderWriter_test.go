@@ -0,0 +1,126 @@
+/*-
+ * Copyright (c) 2017, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testVirtual struct {
+	Name      string `json:"name"`
+	Partition string `json:"partition"`
+	Port      int    `json:"port"`
+}
+
+type testResources struct {
+	Virtuals []testVirtual `json:"virtualServers,omitempty"`
+}
+
+func newTestRenderWriter(out *bytes.Buffer) *renderWriter {
+	rw := NewRenderWriter(out, "-").(*renderWriter)
+	rw.now = func() time.Time {
+		return time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+	return rw
+}
+
+func sendAndWait(t *testing.T, w Writer, name string, obj interface{}) error {
+	doneCh, errCh, err := w.SendSection(name, obj)
+	require.NoError(t, err)
+	select {
+	case <-doneCh:
+		return nil
+	case err = <-errCh:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for render response")
+	}
+	return nil
+}
+
+func TestRenderWriterSections(t *testing.T) {
+	var out bytes.Buffer
+	rw := newTestRenderWriter(&out)
+	assert.Equal(t, "-", rw.GetOutputFilename())
+
+	first := testResources{Virtuals: []testVirtual{
+		{Name: "vs-b", Partition: "velcro", Port: 80},
+		{Name: "vs-a", Partition: "velcro", Port: 80},
+	}}
+	require.NoError(t, sendAndWait(t, rw, "resources", first))
+	rendered := out.String()
+	assert.True(t, strings.HasPrefix(rendered,
+		"### 2017-06-01T12:00:00Z section resources\n"))
+	assert.NotContains(t, rendered, "diff resources",
+		"The first render should not have a diff")
+	// Named objects are sorted so map ordering does not produce diffs
+	assert.True(t,
+		strings.Index(rendered, "vs-a") < strings.Index(rendered, "vs-b"))
+
+	// Reordering alone renders nothing
+	out.Reset()
+	reordered := testResources{Virtuals: []testVirtual{
+		first.Virtuals[1], first.Virtuals[0],
+	}}
+	require.NoError(t, sendAndWait(t, rw, "resources", reordered))
+	assert.Empty(t, out.String())
+
+	// A real change renders the new section and the diff
+	out.Reset()
+	changed := testResources{Virtuals: []testVirtual{
+		{Name: "vs-a", Partition: "velcro", Port: 443},
+		{Name: "vs-b", Partition: "velcro", Port: 80},
+	}}
+	require.NoError(t, sendAndWait(t, rw, "resources", changed))
+	rendered = out.String()
+	assert.Contains(t, rendered, "### 2017-06-01T12:00:00Z section resources\n")
+	assert.Contains(t, rendered, "### 2017-06-01T12:00:00Z diff resources\n")
+	assert.Contains(t, rendered, "--- resources (previous)\n")
+	assert.Contains(t, rendered, "+++ resources (current)\n")
+	assert.Contains(t, rendered, "-      \"port\": 80\n")
+	assert.Contains(t, rendered, "+      \"port\": 443\n")
+
+	// Sections are compared separately
+	out.Reset()
+	require.NoError(t, sendAndWait(t, rw, "global", map[string]int{"a": 1}))
+	assert.Contains(t, out.String(), "section global")
+	assert.NotContains(t, out.String(), "diff global")
+}
+
+func TestRenderWriterErrors(t *testing.T) {
+	var out bytes.Buffer
+	rw := newTestRenderWriter(&out)
+
+	_, _, err := rw.SendSection("", struct{}{})
+	assert.Error(t, err)
+
+	err = sendAndWait(t, rw, "bad", map[string]interface{}{
+		"fn": func() {},
+	})
+	assert.Error(t, err)
+	assert.Empty(t, out.String())
+
+	rw.Stop()
+	_, _, err = rw.SendSection("resources", struct{}{})
+	assert.Error(t, err)
+}
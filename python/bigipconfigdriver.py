@@ -16,7 +16,9 @@
 from __future__ import absolute_import
 
 import argparse
+import copy
 import fcntl
+import functools
 import hashlib
 import ipaddress
 import json
@@ -141,6 +143,20 @@ def get_protocol(protocol):
         return None
 
 
+def get_monitor_destination(address, port):
+    """Return the alias destination of a monitor, '*' for any part unset."""
+    if address != '*' and isinstance(ipaddress.ip_address(address),
+                                     ipaddress.IPv6Address):
+        return '%s.%s' % (address, port)
+    return '%s:%s' % (address, port)
+
+
+def get_persistence_profile(path):
+    """Return the default persistence profile reference of a virtual."""
+    partition, name = path.strip('/').split('/', 1)
+    return {'partition': partition, 'name': name, 'tmDefault': 'yes'}
+
+
 DEFAULT_LOG_LEVEL = logging.INFO
 DEFAULT_VERIFY_INTERVAL = 30.0
 
@@ -280,6 +296,91 @@ class K8sCloudServiceManager():
         vxlan_tunnel.update(**data)
 
 
+class BigIPDevice(object):
+    """A BIG-IP device and the managers for its partitions.
+
+    The device connects on first use, and again after a failed attempt, so
+    that one unreachable device does not stop the others from being
+    configured.
+
+    Args:
+        name: Name used to report the device status
+        connect: Function returning a ManagementRoot for the device
+        partitions: BIG-IP partitions to manage
+        config_sync_group: Device group to sync after applying the config
+        managers: Managers for an already connected device
+    """
+
+    def __init__(self, name, connect=None, partitions=None,
+                 config_sync_group=None, managers=None):
+        self.name = name
+        self.config_sync_group = config_sync_group
+        self._connect = connect
+        self._partitions = partitions or []
+        self._managers = managers
+
+    def managers(self):
+        """Return the partition managers, connecting if needed."""
+        if self._managers is None:
+            bigip = self._connect()
+            self._managers = [
+                K8sCloudServiceManager(bigip, partition,
+                                       schema_path=SCHEMA_PATH)
+                for partition in self._partitions
+            ]
+        return self._managers
+
+    def can_reconnect(self):
+        """Whether the device was configured with a way to connect."""
+        return self._connect is not None
+
+    def disconnect(self):
+        """Drop the connection so the next use connects again."""
+        if self._connect is not None:
+            self._managers = None
+
+    def partition_count(self):
+        if self._managers is not None:
+            return len(self._managers)
+        return len(self._partitions)
+
+
+def _as_devices(managers):
+    """Return managers as a list of devices.
+
+    The managers of a config with a single BIG-IP are treated as one device.
+    """
+    if managers and all(isinstance(m, BigIPDevice) for m in managers):
+        return managers
+    return [BigIPDevice('bigip', managers=list(managers))]
+
+
+def _group_devices(devices):
+    """Group devices by config-sync group, keeping their order."""
+    groups = []
+    by_name = {}
+    for device in devices:
+        if not device.config_sync_group:
+            groups.append([device])
+        elif device.config_sync_group in by_name:
+            by_name[device.config_sync_group].append(device)
+        else:
+            group = [device]
+            by_name[device.config_sync_group] = group
+            groups.append(group)
+    return groups
+
+
+def _config_sync(device):
+    """Sync the config of device to its config-sync group."""
+    mgmt = device.managers()[0].mgmt_root()
+    mgmt.tm.cm.exec_cmd(
+        'run',
+        utilCmdArgs='config-sync to-group {}'.format(device.config_sync_group))
+    log.info('Synced BIG-IP %s to device group %s', device.name,
+             device.config_sync_group)
+
+
 class IntervalTimerError(Exception):
     def __init__(self, msg):
         Exception.__init__(self, msg)
@@ -363,6 +464,9 @@ def create_config_kubernetes(partition, config):
         config: Kubernetes BigIP config
     """
     log.debug("Generating config for BIG-IP from Kubernetes state")
+    # The conversion modifies the config, which is shared by every partition
+    # and device
+    config = copy.deepcopy(config)
     f5 = {'ltm': {}, 'network': {}}
     if 'openshift-sdn' in config:
         f5['network'] = create_network_config_kubernetes(config)
@@ -429,6 +533,10 @@ def create_ltm_config_kubernetes(partition, config):
                 monitor['type'] = monitor['protocol']
                 del monitor['protocol']
             del monitor['partition']
+            if 'aliasAddress' in monitor or 'aliasPort' in monitor:
+                monitor['destination'] = get_monitor_destination(
+                    monitor.pop('aliasAddress', '*'),
+                    monitor.pop('aliasPort', '*'))
 
             configuration['monitors'].append(monitor)
 
@@ -521,12 +629,16 @@ def create_ltm_config_kubernetes(partition, config):
             # Add appropriate profiles
             profile_http = {'partition': 'Common', 'name': 'http'}
             profile_tcp = {'partition': 'Common', 'name': 'tcp'}
+            profile_udp = {'partition': 'Common', 'name': 'udp'}
             if str(svc['mode']).lower() == 'http':
                 if profile_http not in profiles:
                     profiles.append(profile_http)
             elif get_protocol(svc['mode']) == 'tcp':
                 if profile_tcp not in profiles:
                     profiles.append(profile_tcp)
+            elif get_protocol(svc['mode']) == 'udp':
+                if profile_udp not in profiles:
+                    profiles.append(profile_udp)
 
             if ('virtualAddress' in svc and
                     'bindAddr' in svc['virtualAddress']):
@@ -548,10 +660,17 @@ def create_ltm_config_kubernetes(partition, config):
                     'enabled': True,
                     'ipProtocol': get_protocol(svc['mode']),
                     'destination': destination,
-                    'sourceAddressTranslation': {'type': 'automap'},
+                    'sourceAddressTranslation': svc.get(
+                        'sourceAddressTranslation', {'type': 'automap'}),
                     'profiles': profiles,
                     'policies': policies
                 })
+                if 'persistenceProfile' in svc:
+                    f5_service['persist'] = [
+                        get_persistence_profile(svc['persistenceProfile'])]
+                if 'fallbackPersistenceProfile' in svc:
+                    f5_service['fallbackPersistence'] = \
+                        svc['fallbackPersistenceProfile']
                 if 'pool' in svc:
                     f5_service['pool'] = str(svc['pool'])
             f5_services.update({vs_name: f5_service})
@@ -562,6 +681,8 @@ def create_ltm_config_kubernetes(partition, config):
     # FIXME(garyr): CCCL presently expects pools slightly differently than
     # we get from the controller, so convert to the expected format here.
     for pool in config.get('pools', []):
+        if pool.get('partition', partition) != partition:
+            continue
         found_svc = False
         new_pool = {}
         members = []
@@ -708,9 +829,17 @@ def _delete_client_ssl_profiles(mgmt, partition, config):
 
 
 class ConfigHandler():
-    def __init__(self, config_file, managers, verify_interval):
+    def __init__(self, config_file, managers, verify_interval,
+                 bigip_config=None):
         self._config_file = config_file
         self._managers = managers
+        # The bigip section the managers were created from. When set, the
+        # managers are recreated whenever the section changes.
+        self._bigip_config = bigip_config
+
+        # True once we've written out a custom profile. Once we know we've
+        # written out a profile, we can call delete if needed.
+        self._custom_profiles = False
 
         self._condition = threading.Condition()
         self._thread = threading.Thread(target=self._do_reset)
@@ -755,10 +884,6 @@ class ConfigHandler():
         log.debug('config handler thread start')
 
         with self._condition:
-            # customProfiles is true when we've written out a custom profile.
-            # Once we know we've written out a profile, we can call delete
-            # if needed.
-            customProfiles = False
             while True:
                 self._condition.acquire()
                 if not self._pending_reset and not self._stop:
@@ -776,7 +901,7 @@ class ConfigHandler():
 
                 start_time = time.time()
 
-                config = _parse_config(self._config_file)
+                config, digest = _load_config(self._config_file)
                 # No 'resources' indicates that the controller is not
                 # yet ready -- it does not mean to apply an empty config
                 if 'resources' not in config:
@@ -785,41 +910,12 @@ class ConfigHandler():
                 _handle_openshift_sdn_config(config)
                 self.set_interval_timer(verify_interval)
 
-                incomplete = 0
-
-                for mgr in self._managers:
-                    cfg = create_config_kubernetes(mgr.get_partition(),
-                                                   config)
-
-                    try:
-                        # Manually create custom profiles;
-                        # CCCL doesn't yet do this
-                        if 'customProfiles' in config['resources']:
-                            for profile in \
-                                    config['resources']['customProfiles']:
-                                if profile['partition'] == \
-                                        mgr.get_partition():
-                                    _create_client_ssl_profile(
-                                        mgr.mgmt_root(),
-                                        profile)
-                                    customProfiles = True
-
-                        # Apply the BIG-IP config after creating profiles
-                        # and before deleting profiles
-                        incomplete += mgr._apply_config(cfg)
-
-                        # Manually delete custom profiles (if needed)
-                        if customProfiles:
-                            _delete_client_ssl_profiles(
-                                mgr.mgmt_root(),
-                                mgr.get_partition(),
-                                config['resources'])
+                if not self._update_bigip_config(config):
+                    self.handle_backoff()
+                    continue
 
-                    except F5CcclError as e:
-                        # We created an invalid configuration, raise the
-                        # exception and fail
-                        log.error("CCCL Error: %s", e.msg)
-                        raise e
+                incomplete, devices = self._apply_to_devices(config)
+                self._write_status(digest, incomplete, devices)
 
                 if incomplete:
                     # Error occurred, perform retries
@@ -859,6 +955,149 @@ class ConfigHandler():
         if self._interval:
             self._interval.stop()
 
+    def _apply_to_devices(self, config):
+        """Apply the config to every BIG-IP device.
+
+        Devices that share a config-sync group are configured through the
+        first one that can be reached, which then syncs the group. Returns
+        the number of partitions that failed to apply and the status of
+        each device.
+        """
+        incomplete = 0
+        status = {}
+        for group in _group_devices(_as_devices(self._managers)):
+            applied = None
+            for device in group:
+                try:
+                    count = self._apply_to_device(device, config)
+                except F5CcclError:
+                    raise
+                except Exception as e:
+                    if not device.can_reconnect():
+                        # Errors from a single BIG-IP are not caught
+                        raise
+                    log.error('Failed to configure BIG-IP %s: %s',
+                              device.name, e)
+                    device.disconnect()
+                    status[device.name] = {
+                        'incomplete': device.partition_count(),
+                        'error': str(e),
+                        'time': time.time()
+                    }
+                    continue
+                status[device.name] = {
+                    'incomplete': count,
+                    'time': time.time()
+                }
+                applied = device
+                break
+
+            if applied is None:
+                # No device in the group could be reached
+                incomplete += group[-1].partition_count()
+                continue
+
+            applied_status = status[applied.name]
+            incomplete += applied_status['incomplete']
+            synced = False
+            if (applied.config_sync_group and
+                    0 == applied_status['incomplete']):
+                try:
+                    _config_sync(applied)
+                    synced = True
+                except Exception as e:
+                    log.error('Failed to sync BIG-IP %s to group %s: %s',
+                              applied.name, applied.config_sync_group, e)
+                    applied_status['error'] = \
+                        'config-sync failed: {}'.format(e)
+                    applied_status['incomplete'] = 1
+                    incomplete += 1
+
+            for device in group[group.index(applied) + 1:]:
+                status[device.name] = {
+                    'incomplete': 0 if synced else 1,
+                    'configSyncFrom': applied.name,
+                    'time': time.time()
+                }
+        return incomplete, status
+
+    def _apply_to_device(self, device, config):
+        """Apply the config to each partition of a device."""
+        incomplete = 0
+        for mgr in device.managers():
+            cfg = create_config_kubernetes(mgr.get_partition(),
+                                           config)
+
+            try:
+                # Manually create custom profiles;
+                # CCCL doesn't yet do this
+                if 'customProfiles' in config['resources']:
+                    for profile in \
+                            config['resources']['customProfiles']:
+                        if profile['partition'] == \
+                                mgr.get_partition():
+                            _create_client_ssl_profile(
+                                mgr.mgmt_root(),
+                                profile)
+                            self._custom_profiles = True
+
+                # Apply the BIG-IP config after creating profiles
+                # and before deleting profiles
+                incomplete += mgr._apply_config(cfg)
+
+                # Manually delete custom profiles (if needed)
+                if self._custom_profiles:
+                    _delete_client_ssl_profiles(
+                        mgr.mgmt_root(),
+                        mgr.get_partition(),
+                        config['resources'])
+
+            except F5CcclError as e:
+                # We created an invalid configuration, raise the
+                # exception and fail
+                log.error("CCCL Error: %s", e.msg)
+                raise e
+        return incomplete
+
+    def _write_status(self, digest, incomplete, devices=None):
+        """Report the result of applying the config with md5 digest.
+
+        The controller waits for this file to show its final config was
+        applied before stopping the driver, and reports the status of each
+        device.
+        """
+        status_file = os.path.join(os.path.dirname(self._config_file),
+                                   'status.json')
+        tmp_file = status_file + '.tmp'
+        status = {
+            'configMD5': digest,
+            'incomplete': incomplete,
+            'devices': devices or {},
+            'time': time.time()
+        }
+        try:
+            with open(tmp_file, 'w') as f:
+                json.dump(status, f)
+            os.rename(tmp_file, status_file)
+        except (IOError, OSError) as e:
+            log.warning('Failed to write status file %s: %s', status_file, e)
+
+    def _update_bigip_config(self, config):
+        """Reconnect to the BIG-IP if its connection settings changed."""
+        if (self._bigip_config is None or
+                config.get('bigip') == self._bigip_config):
+            return True
+
+        log.info('BIG-IP connection settings changed, reconnecting')
+        try:
+            self._managers = _create_k8s_managers(config)
+        except Exception as e:
+            log.error('Failed to apply new BIG-IP connection settings: %s',
+                      e)
+            return False
+        self._bigip_config = config['bigip']
+        return True
+
     def cleanup_backoff(self):
         """Cleans up canceled backoff timers."""
         self._backoff_timer.cancel()
@@ -1036,15 +1275,22 @@ class ConfigWatcher(pyinotify.ProcessEvent):
 
 
 def _parse_config(config_file):
+    config, _ = _load_config(config_file)
+    return config
+
+
+def _load_config(config_file):
+    """Return the parsed config and the md5 hex digest of its contents."""
     if os.path.exists(config_file):
         with open(config_file, 'r') as config:
             fcntl.lockf(config.fileno(), fcntl.LOCK_SH, 0, 0, 0)
-            config_json = json.load(config)
+            contents = config.read()
             fcntl.lockf(config.fileno(), fcntl.LOCK_UN, 0, 0, 0)
+            config_json = json.loads(contents)
             log.debug('loaded configuration file successfully')
-            return config_json
+            return config_json, hashlib.md5(contents).hexdigest()
     else:
-        return None
+        return None, None
 
 
 def _handle_args():
@@ -1135,6 +1381,86 @@ def _handle_bigip_config(config):
     return host, port
 
 
+def _handle_bigip_devices(config):
+    """Validate the devices in the bigip section.
+
+    Returns a list of dicts with the name, host, port, username, password,
+    partitions and config-sync-group of each device.
+    """
+    bigip = config['bigip']
+    if not isinstance(bigip['devices'], list) or not bigip['devices']:
+        raise ConfigError('Configuration file must list at least one '
+                          'device in the "bigip:devices" section')
+    devices = []
+    names = set()
+    for device in bigip['devices']:
+        for field in ['name', 'url', 'username', 'password']:
+            if field not in device:
+                raise ConfigError('Configuration file missing "{}" for a '
+                                  'device in the "bigip:devices" '
+                                  'section'.format(field))
+        if device['name'] in names:
+            raise ConfigError('Duplicate device "{}" in the "bigip:devices" '
+                              'section'.format(device['name']))
+        names.add(device['name'])
+
+        partitions = device.get('partitions') or bigip.get('partitions')
+        if not partitions:
+            raise ConfigError('Configuration file must specify at least one '
+                              'partition for device "{}"'.format(
+                                  device['name']))
+
+        url = urlparse(device['url'])
+        devices.append({
+            'name': device['name'],
+            'host': url.hostname,
+            'port': url.port or 443,
+            'username': device['username'],
+            'password': device['password'],
+            'partitions': partitions,
+            'config-sync-group': device.get('config-sync-group')
+        })
+    return devices
+
+
+def _connect_bigip(host, port, username, password):
+    return ManagementRoot(host, username, password, port=port, token="tmos")
+
+
+def _create_k8s_managers(config):
+    if config and 'devices' in config.get('bigip', {}):
+        # Each device connects on first use
+        return [
+            BigIPDevice(
+                device['name'],
+                connect=functools.partial(
+                    _connect_bigip, device['host'], device['port'],
+                    device['username'], device['password']),
+                partitions=device['partitions'],
+                config_sync_group=device['config-sync-group'])
+            for device in _handle_bigip_devices(config)
+        ]
+
+    host, port = _handle_bigip_config(config)
+
+    # BIG-IP to manage
+    bigip = _connect_bigip(
+        host,
+        port,
+        config['bigip']['username'],
+        config['bigip']['password'])
+
+    k8s_managers = []
+    for partition in config['bigip']['partitions']:
+        # Management for the BIG-IP partitions
+        manager = K8sCloudServiceManager(
+            bigip,
+            partition,
+            schema_path=SCHEMA_PATH)
+        k8s_managers.append(manager)
+    return k8s_managers
+
+
 def _handle_openshift_sdn_config(config):
     if config and 'openshift-sdn' in config:
         sdn = config['openshift-sdn']
@@ -1152,32 +1478,15 @@ def main():
 
         config = _parse_config(args.config_file)
         verify_interval, _ = _handle_global_config(config)
-        host, port = _handle_bigip_config(config)
-
-        # FIXME (kenr): Big-IP settings are currently static (we ignore any
-        #               changes to these fields in subsequent updates). We
-        #               may want to make the changes dynamic in the future.
-
-        # BIG-IP to manage
-        bigip = ManagementRoot(
-            host,
-            config['bigip']['username'],
-            config['bigip']['password'],
-            port=port,
-            token="tmos")
-
-        k8s_managers = []
-        for partition in config['bigip']['partitions']:
-            # Management for the BIG-IP partitions
-            manager = K8sCloudServiceManager(
-                bigip,
-                partition,
-                schema_path=SCHEMA_PATH)
-            k8s_managers.append(manager)
+
+        # Changes to the bigip section (e.g. rotated credentials) cause the
+        # handler to reconnect with the new settings.
+        k8s_managers = _create_k8s_managers(config)
 
         handler = ConfigHandler(args.config_file,
                                 k8s_managers,
-                                verify_interval)
+                                verify_interval,
+                                bigip_config=config['bigip'])
 
         if os.path.exists(args.config_file):
             handler.notify_reset()
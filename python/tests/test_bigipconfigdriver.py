@@ -16,12 +16,14 @@
 from __future__ import absolute_import
 
 from copy import deepcopy
+import hashlib
 import json
 import logging
 import os
 import shutil
 from string import Template
 import sys
+import tempfile
 import threading
 import time
 
@@ -1330,3 +1332,430 @@ def test_confighandler_backoff_timer(request):
             handler._thread.join(30)
             assert handler._thread.is_alive() is False
             assert handler._interval.is_running() is False
+
+
+def test_confighandler_bigip_reconnect(request, monkeypatch):
+    handler = None
+    try:
+        old_mgr = MockMgr()
+        new_mgr = MockMgr()
+        created = []
+
+        def create_managers(config):
+            if config['bigip']['password'] == 'bad':
+                raise Exception('unauthorized')
+            created.append(config['bigip'])
+            return [new_mgr]
+        monkeypatch.setattr(bigipconfigdriver, '_create_k8s_managers',
+                            create_managers)
+
+        bigip = deepcopy(_cloud_config['bigip'])
+        handler = bigipconfigdriver.ConfigHandler(
+            '/tmp/config', [old_mgr], 0, bigip_config=bigip)
+
+        # Unchanged settings keep the existing managers
+        assert handler._update_bigip_config({'bigip': deepcopy(bigip)})
+        assert handler._managers == [old_mgr]
+        assert created == []
+
+        # Failing to connect with new settings keeps the old managers
+        bad = deepcopy(bigip)
+        bad['password'] = 'bad'
+        assert not handler._update_bigip_config({'bigip': bad})
+        assert handler._managers == [old_mgr]
+
+        # Rotated credentials recreate the managers
+        rotated = deepcopy(bigip)
+        rotated['password'] = 'rotated'
+        assert handler._update_bigip_config({'bigip': rotated})
+        assert handler._managers == [new_mgr]
+        assert created == [rotated]
+        assert handler._update_bigip_config({'bigip': deepcopy(rotated)})
+        assert len(created) == 1
+    finally:
+        assert handler is not None
+
+        handler.stop()
+        handler._thread.join(30)
+        assert handler._thread.is_alive() is False
+
+
+def test_confighandler_write_status(request):
+    handler = None
+    try:
+        config_dir = tempfile.mkdtemp()
+        request.addfinalizer(lambda: shutil.rmtree(config_dir))
+        config_file = os.path.join(config_dir, 'config.json')
+        with open(config_file, 'w') as f:
+            json.dump(_cloud_config, f)
+
+        config, digest = bigipconfigdriver._load_config(config_file)
+        assert config == json.loads(json.dumps(_cloud_config))
+        with open(config_file, 'r') as f:
+            assert digest == hashlib.md5(f.read()).hexdigest()
+
+        handler = bigipconfigdriver.ConfigHandler(config_file, [MockMgr()], 0)
+        handler._write_status(digest, 1)
+
+        status_file = os.path.join(config_dir, 'status.json')
+        with open(status_file, 'r') as f:
+            status = json.load(f)
+        assert status['configMD5'] == digest
+        assert status['incomplete'] == 1
+        assert not os.path.exists(status_file + '.tmp')
+
+        assert bigipconfigdriver._load_config(
+            os.path.join(config_dir, 'missing')) == (None, None)
+    finally:
+        assert handler is not None
+
+        handler.stop()
+        handler._thread.join(30)
+        assert handler._thread.is_alive() is False
+
+
+def test_create_config_kubernetes_partitions():
+    config = {
+        'resources': {
+            'virtualServers': [{
+                'name': 'tenant-a_vs',
+                'partition': 'tenant_a',
+                'mode': 'tcp',
+                'balance': 'round-robin',
+                'virtualAddress': {'bindAddr': '10.0.0.1', 'port': 80},
+                'pool': '/tenant_a/tenant-a_vs'
+            }, {
+                'name': 'tenant-b_vs',
+                'partition': 'tenant_b',
+                'mode': 'tcp',
+                'balance': 'round-robin',
+                'virtualAddress': {'bindAddr': '10.0.0.2', 'port': 80},
+                'pool': '/tenant_b/tenant-b_vs'
+            }],
+            'pools': [{
+                'name': 'tenant-a_vs',
+                'partition': 'tenant_a',
+                'loadBalancingMode': 'round-robin',
+                'serviceName': 'foo',
+                'poolMemberAddrs': ['172.16.0.1:30001']
+            }, {
+                'name': 'openshift_tenant-b_bar',
+                'partition': 'tenant_b',
+                'loadBalancingMode': 'round-robin',
+                'serviceName': 'bar',
+                'poolMemberAddrs': ['172.16.0.2:30002']
+            }],
+            'iRules': [{
+                'name': 'openshift_passthrough_irule',
+                'partition': 'tenant_a',
+                'apiAnonymous': 'when CLIENT_ACCEPTED {}'
+            }, {
+                'name': 'openshift_passthrough_irule',
+                'partition': 'tenant_b',
+                'apiAnonymous': 'when CLIENT_ACCEPTED {}'
+            }]
+        }
+    }
+    original = deepcopy(config)
+
+    for partition, other in [('tenant_a', 'tenant_b'),
+                             ('tenant_b', 'tenant_a')]:
+        ltm = bigipconfigdriver.create_config_kubernetes(
+            partition, config)['ltm']
+        assert [vs['name'] for vs in ltm['virtualServers']] == \
+            [partition.replace('_', '-') + '_vs']
+        assert len(ltm['pools']) == 1
+        assert len(ltm['iRules']) == 1
+        assert other not in json.dumps(ltm)
+
+    # Each partition, and each device, converts the same config
+    assert config == original
+
+
+def test_create_config_kubernetes_udp():
+    config = {
+        'resources': {
+            'virtualServers': [{
+                'name': 'default_dns',
+                'partition': 'k8s',
+                'mode': 'udp',
+                'balance': 'round-robin',
+                'virtualAddress': {'bindAddr': '10.0.0.1', 'port': 53},
+                'pool': '/k8s/default_dns'
+            }],
+            'pools': [{
+                'name': 'default_dns',
+                'partition': 'k8s',
+                'loadBalancingMode': 'round-robin',
+                'serviceName': 'dns',
+                'poolMemberAddrs': ['172.16.0.1:30053']
+            }]
+        }
+    }
+
+    ltm = bigipconfigdriver.create_config_kubernetes('k8s', config)['ltm']
+    vs = ltm['virtualServers'][0]
+    assert vs['ipProtocol'] == 'udp'
+    assert vs['profiles'] == [{'partition': 'Common', 'name': 'udp'}]
+
+
+def test_create_config_kubernetes_snat():
+    config = {
+        'resources': {
+            'virtualServers': [{
+                'name': 'default_automap',
+                'partition': 'k8s',
+                'mode': 'tcp',
+                'balance': 'round-robin',
+                'virtualAddress': {'bindAddr': '10.0.0.1', 'port': 80}
+            }, {
+                'name': 'default_snatpool',
+                'partition': 'k8s',
+                'mode': 'tcp',
+                'balance': 'round-robin',
+                'virtualAddress': {'bindAddr': '10.0.0.2', 'port': 80},
+                'sourceAddressTranslation': {
+                    'type': 'snat',
+                    'pool': '/Common/snatpool'
+                }
+            }]
+        }
+    }
+
+    ltm = bigipconfigdriver.create_config_kubernetes('k8s', config)['ltm']
+    snat = dict((vs['name'], vs['sourceAddressTranslation'])
+                for vs in ltm['virtualServers'])
+    assert snat == {
+        'default_automap': {'type': 'automap'},
+        'default_snatpool': {'type': 'snat', 'pool': '/Common/snatpool'}
+    }
+
+
+def test_create_config_kubernetes_persistence():
+    config = {
+        'resources': {
+            'virtualServers': [{
+                'name': 'default_cookie',
+                'partition': 'k8s',
+                'mode': 'http',
+                'balance': 'round-robin',
+                'virtualAddress': {'bindAddr': '10.0.0.1', 'port': 80},
+                'persistenceProfile': '/Common/cookie',
+                'fallbackPersistenceProfile': '/Common/source_addr'
+            }, {
+                'name': 'default_none',
+                'partition': 'k8s',
+                'mode': 'tcp',
+                'balance': 'round-robin',
+                'virtualAddress': {'bindAddr': '10.0.0.2', 'port': 80}
+            }]
+        }
+    }
+
+    ltm = bigipconfigdriver.create_config_kubernetes('k8s', config)['ltm']
+    virtuals = dict((vs['name'], vs) for vs in ltm['virtualServers'])
+    assert virtuals['default_cookie']['persist'] == [
+        {'partition': 'Common', 'name': 'cookie', 'tmDefault': 'yes'}]
+    assert virtuals['default_cookie']['fallbackPersistence'] == \
+        '/Common/source_addr'
+    assert 'persist' not in virtuals['default_none']
+    assert 'fallbackPersistence' not in virtuals['default_none']
+
+
+def test_create_config_kubernetes_monitors():
+    config = {
+        'resources': {
+            'virtualServers': [{
+                'name': 'default_configmap',
+                'partition': 'k8s',
+                'mode': 'http',
+                'balance': 'round-robin',
+                'pool': '/k8s/default_configmap'
+            }],
+            'pools': [{
+                'name': 'default_configmap',
+                'partition': 'k8s',
+                'loadBalancingMode': 'round-robin',
+                'serviceName': 'foo',
+                'poolMemberAddrs': ['172.16.0.1:30001'],
+                'monitor': ['/k8s/default_configmap',
+                            '/k8s/default_configmap_1']
+            }],
+            'monitors': [{
+                'name': 'default_configmap',
+                'partition': 'k8s',
+                'protocol': 'https',
+                'send': 'GET /',
+                'recv': '200 OK',
+                'recvDisable': '503',
+                'upInterval': 30,
+                'timeUntilUp': 60,
+                'aliasAddress': '10.0.0.1',
+                'aliasPort': 8443
+            }, {
+                'name': 'default_configmap_1',
+                'partition': 'k8s',
+                'protocol': 'gateway-icmp',
+                'aliasAddress': '2001:db8::1'
+            }]
+        }
+    }
+
+    ltm = bigipconfigdriver.create_config_kubernetes('k8s', config)['ltm']
+    assert ltm['monitors'] == [{
+        'name': 'default_configmap_0_https',
+        'type': 'https',
+        'send': 'GET /',
+        'recv': '200 OK',
+        'recvDisable': '503',
+        'upInterval': 30,
+        'timeUntilUp': 60,
+        'destination': '10.0.0.1:8443'
+    }, {
+        'name': 'default_configmap_1_0_gateway-icmp',
+        'type': 'gateway-icmp',
+        'destination': '2001:db8::1.*'
+    }]
+    assert ltm['pools'][0]['monitors'] == [
+        '/k8s/default_configmap_0_https',
+        '/k8s/default_configmap_1_0_gateway-icmp']
+
+
+def test_handle_bigip_devices():
+    config = {
+        'bigip': {
+            'partitions': ['velcro'],
+            'devices': [
+                {'name': 'dc1-a', 'url': 'https://10.1.1.1',
+                 'username': 'admin', 'password': 'a',
+                 'config-sync-group': 'dc1'},
+                {'name': 'dc2', 'url': 'https://10.2.1.1:8443',
+                 'username': 'admin', 'password': 'b',
+                 'partitions': ['dc2']}
+            ]
+        }
+    }
+    devices = bigipconfigdriver._handle_bigip_devices(config)
+    assert devices == [
+        {'name': 'dc1-a', 'host': '10.1.1.1', 'port': 443,
+         'username': 'admin', 'password': 'a', 'partitions': ['velcro'],
+         'config-sync-group': 'dc1'},
+        {'name': 'dc2', 'host': '10.2.1.1', 'port': 8443,
+         'username': 'admin', 'password': 'b', 'partitions': ['dc2'],
+         'config-sync-group': None}
+    ]
+
+    # Devices connect on first use
+    managers = bigipconfigdriver._create_k8s_managers(config)
+    assert [d.name for d in managers] == ['dc1-a', 'dc2']
+    assert managers[0].config_sync_group == 'dc1'
+    assert managers[1].partition_count() == 1
+
+    invalid = deepcopy(config)
+    del invalid['bigip']['devices'][1]['password']
+    with pytest.raises(bigipconfigdriver.ConfigError):
+        bigipconfigdriver._handle_bigip_devices(invalid)
+
+    invalid = deepcopy(config)
+    invalid['bigip']['devices'][1]['name'] = 'dc1-a'
+    with pytest.raises(bigipconfigdriver.ConfigError):
+        bigipconfigdriver._handle_bigip_devices(invalid)
+
+    invalid = deepcopy(config)
+    del invalid['bigip']['partitions']
+    with pytest.raises(bigipconfigdriver.ConfigError):
+        bigipconfigdriver._handle_bigip_devices(invalid)
+
+    invalid = deepcopy(config)
+    invalid['bigip']['devices'] = []
+    with pytest.raises(bigipconfigdriver.ConfigError):
+        bigipconfigdriver._handle_bigip_devices(invalid)
+
+
+class MockCm():
+    def __init__(self, fail=False):
+        self.commands = []
+        self._fail = fail
+
+    def exec_cmd(self, command, utilCmdArgs=None):
+        if self._fail:
+            raise Exception('sync failed')
+        self.commands.append(utilCmdArgs)
+
+
+class MockMgmt():
+    def __init__(self, fail_sync=False):
+        self.tm = type('tm', (), {})()
+        self.tm.cm = MockCm(fail_sync)
+
+
+def _mock_device(name, group=None, fail_connect=False, fail_sync=False):
+    mgr = MockMgr()
+    mgr._mgmt_root = MockMgmt(fail_sync)
+
+    def connect():
+        raise Exception('connection refused')
+
+    if fail_connect:
+        return bigipconfigdriver.BigIPDevice(
+            name, connect=connect, partitions=['velcro'],
+            config_sync_group=group)
+    return bigipconfigdriver.BigIPDevice(
+        name, config_sync_group=group, managers=[mgr])
+
+
+def test_confighandler_apply_to_devices(request):
+    handler = None
+    try:
+        down = _mock_device('dc1-a', group='dc1', fail_connect=True)
+        standby = _mock_device('dc1-b', group='dc1')
+        peer = _mock_device('dc1-c', group='dc1')
+        single = _mock_device('dc2')
+        devices = [down, single, standby, peer]
+        assert bigipconfigdriver._group_devices(devices) == [
+            [down, standby, peer], [single]]
+
+        handler = bigipconfigdriver.ConfigHandler(
+            '/tmp/config', devices, 0)
+        config = deepcopy(_cloud_config)
+        config['resources'] = {}
+        incomplete, status = handler._apply_to_devices(config)
+
+        # The unreachable device is reported and the next one in its
+        # group is configured and synced instead
+        assert incomplete == 0
+        assert status['dc1-a']['error'] == 'connection refused'
+        assert status['dc1-b']['incomplete'] == 0
+        assert status['dc1-c'] == {
+            'incomplete': 0, 'configSyncFrom': 'dc1-b',
+            'time': status['dc1-c']['time']}
+        assert status['dc2']['incomplete'] == 0
+        assert standby.managers()[0].calls == 1
+        assert peer.managers()[0].calls == 0
+        assert single.managers()[0].calls == 1
+        assert standby.managers()[0].mgmt_root().tm.cm.commands == [
+            'config-sync to-group dc1']
+
+        # A failed sync or an unreachable device leaves the config
+        # incomplete
+        handler._managers = [
+            _mock_device('dc1-a', group='dc1', fail_sync=True),
+            _mock_device('dc2', fail_connect=True)]
+        incomplete, status = handler._apply_to_devices(config)
+        assert incomplete == 2
+        assert 'config-sync failed' in status['dc1-a']['error']
+        assert status['dc2']['incomplete'] == 1
+
+        # The managers for a single BIG-IP are one device
+        mgr = MockMgr()
+        handler._managers = [mgr]
+        incomplete, status = handler._apply_to_devices(config)
+        assert incomplete == 0
+        assert status.keys() == ['bigip']
+        assert mgr.calls == 1
+    finally:
+        assert handler is not None
+
+        handler.stop()
+        handler._thread.join(30)
+        assert handler._thread.is_alive() is False
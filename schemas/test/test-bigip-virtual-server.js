@@ -23,7 +23,7 @@ const handleError = require('./util').handleError;
 
 handleError();
 
-const CURRENT_VERSION="v0.1.4";
+const CURRENT_VERSION="v0.1.12";
 const testSchema = `f5schemadb://bigip-virtual-server_${CURRENT_VERSION}.json`;
 
 exports.bigipVirtualServer = {
@@ -236,7 +236,7 @@ exports.bigipVirtualServer.invalidPartition = t => {
 
 exports.bigipVirtualServer.invalidMode = t => {
   let data = Object.assign({}, this.baseValidConfig);
-  data.virtualServer.frontend.mode = 'not tcp or http';
+  data.virtualServer.frontend.mode = 'not tcp, udp or http';
 
   this.sUtil.loadSchemas(testSchema, () => {
     let result = this.sUtil.runValidate(data, testSchema);
@@ -253,6 +253,25 @@ exports.bigipVirtualServer.invalidMode = t => {
   });
 };
 
+exports.bigipVirtualServer.udpMode = t => {
+  let data = Object.assign({}, this.baseValidConfig);
+  data.virtualServer.frontend.mode = 'udp';
+  delete data.virtualServer.frontend.sslProfile;
+  data.virtualServer.backend.healthMonitors = [ {
+    "protocol": "udp",
+    "send": "default send string",
+    "interval": 10,
+    "timeout": 31
+  } ];
+
+  this.sUtil.loadSchemas(testSchema, () => {
+    let result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result');
+
+    t.done();
+  });
+};
+
 exports.bigipVirtualServer.invalidBalance = t => {
 
   let data = Object.assign({}, this.baseValidConfig);
@@ -402,6 +421,192 @@ exports.bigipVirtualServer.missingBackendRequired = t => {
   });
 };
 
+exports.bigipVirtualServer.backends = t => {
+  let data = Object.assign({}, this.baseValidConfig);
+  delete data.virtualServer.backend;
+  data.virtualServer.backends = [
+    { "name": "foo", "serviceName": "foo-service", "servicePort": 80 },
+    { "name": "bar", "serviceName": "bar-service", "servicePort": 8080,
+      "balance": "least-connections-member" }
+  ];
+  data.virtualServer.defaultBackend = "foo";
+  data.virtualServer.rules = [
+    { "host": "bar.example.com", "backend": "bar" },
+    { "host": "foo.example.com", "path": "/bar", "backend": "bar" },
+    { "path": "/foo", "backend": "foo" }
+  ];
+
+  this.sUtil.loadSchemas(testSchema, () => {
+    let result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result');
+
+    // Names are part of the pool names and can not contain underscores
+    data.virtualServer.backends[0].name = 'foo_1';
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for invalid name');
+    data.virtualServer.backends[0].name = 'foo';
+
+    data.virtualServer.rules[2].path = 'foo';
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for relative path');
+
+    data.virtualServer.rules[2] = { "backend": "foo" };
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for rule without host or path');
+    data.virtualServer.rules.pop();
+
+    // backend and backends are mutually exclusive
+    data.virtualServer.backend = {
+      "serviceName": "kubernetes-service",
+      "servicePort": 80
+    };
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for backend and backends');
+
+    delete data.virtualServer.backend;
+    data.virtualServer.backends = [];
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for empty backends');
+
+    t.done();
+  });
+};
+
+exports.bigipVirtualServer.ruleConditions = t => {
+  let data = Object.assign({}, this.baseValidConfig);
+  delete data.virtualServer.backend;
+  data.virtualServer.backends = [
+    { "name": "web", "serviceName": "web-service", "servicePort": 80 },
+    { "name": "mobile", "serviceName": "mobile-service", "servicePort": 80 }
+  ];
+  data.virtualServer.defaultBackend = "web";
+  data.virtualServer.rules = [
+    { "path": "/api", "backend": "mobile", "conditions": [
+      { "type": "header", "name": "User-Agent", "operator": "contains",
+        "values": [ "Android", "iPhone" ] },
+      { "type": "method", "values": [ "GET", "HEAD" ] }
+    ] },
+    { "backend": "mobile", "conditions": [
+      { "type": "cookie", "name": "group", "values": [ "beta" ] }
+    ] }
+  ];
+
+  this.sUtil.loadSchemas(testSchema, () => {
+    let result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result');
+
+    let cond = data.virtualServer.rules[1].conditions[0];
+    cond.type = "queryParameter";
+    cond.operator = "starts-with";
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result for a query parameter');
+
+    delete cond.name;
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for a condition without name');
+
+    cond.type = "method";
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result for a method');
+
+    cond.name = "GET";
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for a method with a name');
+    delete cond.name;
+
+    cond.operator = "matches";
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for an unknown operator');
+    delete cond.operator;
+
+    cond.values = [];
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for a condition without values');
+
+    data.virtualServer.rules[1].conditions = [];
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for empty conditions');
+
+    t.done();
+  });
+};
+
+exports.bigipVirtualServer.iRulesAndPolicies = t => {
+  let data = Object.assign({}, this.baseValidConfig);
+  data.virtualServer.frontend.iRules = [ "/Common/my_irule" ];
+  data.virtualServer.frontend.policies = [ "/Common/my_policy" ];
+
+  this.sUtil.loadSchemas(testSchema, () => {
+    let result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result');
+
+    // References need the partition of the iRule or policy
+    data.virtualServer.frontend.iRules = [ "my_irule" ];
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for iRule without partition');
+    data.virtualServer.frontend.iRules = [ "/Common/my_irule" ];
+
+    data.virtualServer.frontend.policies =
+      [ "/Common/my_policy", "/Common/my_policy" ];
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for duplicate policies');
+
+    delete data.virtualServer.frontend.iRules;
+    delete data.virtualServer.frontend.policies;
+    t.done();
+  });
+};
+
+exports.bigipVirtualServer.snat = t => {
+  let data = Object.assign({}, this.baseValidConfig);
+
+  this.sUtil.loadSchemas(testSchema, () => {
+    for (let snat of [ "automap", "none", "/Common/snatpool" ]) {
+      data.virtualServer.frontend.snat = snat;
+      let result = this.sUtil.runValidate(data, testSchema);
+      t.ok(result.valid, `Should have a valid result for ${snat}`);
+    }
+
+    data.virtualServer.frontend.snat = "snatpool";
+    let result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for SNAT pool without path');
+
+    delete data.virtualServer.frontend.snat;
+    t.done();
+  });
+};
+
+exports.bigipVirtualServer.persistence = t => {
+  let data = Object.assign({}, this.baseValidConfig);
+
+  this.sUtil.loadSchemas(testSchema, () => {
+    for (let profile of [ "cookie", "source-address", "destination-address",
+                          "/Common/universal" ]) {
+      data.virtualServer.frontend.persistence = { default: profile };
+      let result = this.sUtil.runValidate(data, testSchema);
+      t.ok(result.valid, `Should have a valid result for ${profile}`);
+    }
+
+    data.virtualServer.frontend.persistence = {
+      default: "cookie",
+      fallback: "source-address"
+    };
+    let result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result with a fallback');
+
+    data.virtualServer.frontend.persistence = { fallback: "source-address" };
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure without a default');
+
+    data.virtualServer.frontend.persistence = { default: "universal" };
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for a profile without path');
+
+    delete data.virtualServer.frontend.persistence;
+    t.done();
+  });
+};
+
 exports.bigipVirtualServer.invalidServiceName = t => {
   let data = Object.assign({}, this.baseValidConfig);
   data.virtualServer.backend.serviceName = '';
@@ -459,7 +664,7 @@ exports.bigipVirtualServer.invalidServicePort = t => {
     t.strictEqual(result.errors[0].message,
         'must have a maximum value of 65535', 'Should have maximum error');
 
-    data.virtualServer.backend.servicePort = "not an int";
+    data.virtualServer.backend.servicePort = "not a name";
     result = this.sUtil.runValidate(data, testSchema);
     t.ok(!result.valid, 'Should have a failure');
 
@@ -467,9 +672,41 @@ exports.bigipVirtualServer.invalidServicePort = t => {
     t.strictEqual(result.errors[0].property,
         'instance.virtualServer.backend.servicePort',
         'Should have port error');
+    t.ok(result.errors[0].message.startsWith('does not match pattern'),
+        'Should have port name error');
+
+    data.virtualServer.backend.servicePort = true;
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure');
+
+    t.strictEqual(result.errors.length, 1, 'Should have one error');
     t.strictEqual(result.errors[0].message,
-        'is not of a type(s) integer', 'Should have non integer error');
+        'is not of a type(s) integer,string',
+        'Should have non integer or string error');
+
+    data.virtualServer.backend.servicePort = 80;
+    t.done();
+  });
+};
+
+exports.bigipVirtualServer.namedServicePort = t => {
+  let data = Object.assign({}, this.baseValidConfig);
 
+  this.sUtil.loadSchemas(testSchema, () => {
+    for (let name of [ "http", "https-alt", "h2c", "metrics9090" ]) {
+      data.virtualServer.backend.servicePort = name;
+      let result = this.sUtil.runValidate(data, testSchema);
+      t.ok(result.valid, `Should have a valid result for ${name}`);
+    }
+
+    for (let name of [ "", "80", "-http", "http-", "web--ui", "HTTP",
+                       "a-very-long-port-name" ]) {
+      data.virtualServer.backend.servicePort = name;
+      let result = this.sUtil.runValidate(data, testSchema);
+      t.ok(!result.valid, `Should have a failure for '${name}'`);
+    }
+
+    data.virtualServer.backend.servicePort = 80;
     t.done();
   });
 };
@@ -546,16 +783,26 @@ exports.bigipVirtualServer.invalidHealthMonitor = t => {
         'is not of a type(s) integer', 'Should have non integer error');
 
     data.virtualServer.backend.healthMonitors[0].timeout = 10;
-    data.virtualServer.backend.healthMonitors[0].protocol = "icmp";
+    data.virtualServer.backend.healthMonitors[0].protocol = "smtp";
     result = this.sUtil.runValidate(data, testSchema);
     t.ok(!result.valid, 'Should have a failure');
 
-    t.strictEqual(result.errors.length, 1, 'Should have one error');
-    t.strictEqual(result.errors[0].property,
-        'instance.virtualServer.backend.healthMonitors[0].protocol',
-        'Should have protocol error');
-    t.strictEqual(result.errors[0].message,
-        'is not one of enum values: http,tcp', 'Should have non enum error');
+    let protocolErrors = result.errors.filter(err => err.property ===
+        'instance.virtualServer.backend.healthMonitors[0].protocol');
+    t.strictEqual(protocolErrors.length, 1, 'Should have protocol error');
+    t.strictEqual(protocolErrors[0].message,
+        'is not one of enum values: ' +
+        'http,https,tcp,udp,icmp,gateway-icmp,tcp-half-open',
+        'Should have non enum error');
+
+    // icmp monitors do not send anything
+    data.virtualServer.backend.healthMonitors[0].protocol = "icmp";
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for icmp with send');
+    delete data.virtualServer.backend.healthMonitors[0].send;
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should be a success for icmp without send');
+    data.virtualServer.backend.healthMonitors[0].send = "/";
 
     data.virtualServer.backend.healthMonitors[0].protocol = "tcp";
     data.virtualServer.backend.healthMonitors[0].send = "";
@@ -610,6 +857,46 @@ exports.bigipVirtualServer.invalidHealthMonitor = t => {
   });
 };
 
+exports.bigipVirtualServer.healthMonitorOptions = t => {
+  let data = Object.assign({}, this.baseValidConfig);
+  data.virtualServer.backend.healthMonitors = [ {
+    "protocol": "https",
+    "interval": 10,
+    "timeout": 31,
+    "send": "GET /health HTTP/1.0\\r\\n\\r\\n",
+    "recv": "200 OK",
+    "recvDisable": "503",
+    "upInterval": 30,
+    "timeUntilUp": 60,
+    "aliasAddress": "10.0.0.1",
+    "aliasPort": 8443
+  }, {
+    "protocol": "tcp-half-open",
+    "aliasAddress": "2001:db8::1"
+  } ];
+
+  this.sUtil.loadSchemas(testSchema, () => {
+    let result = this.sUtil.runValidate(data, testSchema);
+    t.ok(result.valid, 'Should have a valid result');
+
+    data.virtualServer.backend.healthMonitors[1].aliasAddress = "not an ip";
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for invalid alias address');
+    data.virtualServer.backend.healthMonitors[1].aliasAddress = "10.0.0.1";
+
+    data.virtualServer.backend.healthMonitors[0].aliasPort = 0;
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for invalid alias port');
+    data.virtualServer.backend.healthMonitors[0].aliasPort = 8443;
+
+    data.virtualServer.backend.healthMonitors[1].recv = "OK";
+    result = this.sUtil.runValidate(data, testSchema);
+    t.ok(!result.valid, 'Should have a failure for tcp-half-open with recv');
+
+    t.done();
+  });
+};
+
 exports.bigipVirtualServer.invalidIApp = t => {
   let data = Object.assign({}, this.baseIAppConfig);
   data.virtualServer.frontend.iapp = '';